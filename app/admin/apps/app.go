@@ -7,9 +7,10 @@ type DataApp struct {
 }
 
 type App struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID             string `json:"id"`
+	OrganizationID string `json:"organization_id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
 }
 
 type Apps struct {
@@ -21,6 +22,10 @@ type AppUsers struct {
 	Users []AppUser `json:"data"`
 }
 
+type AppUserData struct {
+	AppUser AppUser `json:"data"`
+}
+
 type AppUser struct {
 	ID   string `json:"id"`
 	User User   `json:"user"`
@@ -40,6 +45,10 @@ type AppToken struct {
 	CreatedAt string  `json:"created_at"`
 }
 
+type AppTokenData struct {
+	AppToken AppToken `json:"data"`
+}
+
 type AppTokens struct {
 	AppTokens  []AppToken          `json:"data"`
 	Pagination entities.Pagination `json:"pagination"`
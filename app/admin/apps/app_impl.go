@@ -0,0 +1,268 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+type AppRepo struct {
+	Repo *admin.Config
+}
+
+func NewRepo(repo *admin.Config) AppRepo {
+	return AppRepo{Repo: repo}
+}
+
+func (letmein *AppRepo) Create(newApp App) (App, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps", nil, newApp.OrganizationID)
+	if err != nil {
+		return App{}, fmt.Errorf("error building url for create app: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.Create", url, http.MethodPost)
+	letmein.Repo.Idempotent(req)
+	req.AddBody("name", newApp.Name)
+	req.AddBody("description", newApp.Description)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return App{}, fmt.Errorf("error requesting for create app: %w", err)
+	}
+
+	return parseAppResponse(http.StatusCreated, statusCode, body)
+}
+
+func (letmein *AppRepo) List(organizationID string, page, perPage int) (Apps, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps", restapi.PageQuery(page, perPage), organizationID)
+	if err != nil {
+		return Apps{}, fmt.Errorf("error building url for list apps: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.List", url, http.MethodGet)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return Apps{}, fmt.Errorf("error requesting for list apps: %w", err)
+	}
+
+	var apps Apps
+	err = parseResponse(http.StatusOK, statusCode, body, &apps)
+
+	return apps, err
+}
+
+func (letmein *AppRepo) Find(organizationID string, id string) (App, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{id}", nil, organizationID, id)
+	if err != nil {
+		return App{}, fmt.Errorf("error building url for find app: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.Find", url, http.MethodGet)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return App{}, fmt.Errorf("error requesting for find app: %w", err)
+	}
+
+	return parseAppResponse(http.StatusOK, statusCode, body)
+}
+
+func (letmein *AppRepo) Update(app App) (App, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{id}", nil, app.OrganizationID, app.ID)
+	if err != nil {
+		return App{}, fmt.Errorf("error building url for update app: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.Update", url, http.MethodPut)
+	req.AddBody("name", app.Name)
+	req.AddBody("description", app.Description)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return App{}, fmt.Errorf("error requesting for update app: %w", err)
+	}
+
+	return parseAppResponse(http.StatusOK, statusCode, body)
+}
+
+func (letmein *AppRepo) Delete(organizationID string, id string) error {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{id}", nil, organizationID, id)
+	if err != nil {
+		return fmt.Errorf("error building url for delete app: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.Delete", url, http.MethodDelete)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return fmt.Errorf("error requesting for delete app: %w", err)
+	}
+
+	return parseNoContentResponse(statusCode, body)
+}
+
+func (letmein *AppRepo) Users(organizationID string, appID string, page, perPage int) (AppUsers, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{appID}/users", restapi.PageQuery(page, perPage), organizationID, appID)
+	if err != nil {
+		return AppUsers{}, fmt.Errorf("error building url for list app users: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.Users", url, http.MethodGet)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return AppUsers{}, fmt.Errorf("error requesting for list app users: %w", err)
+	}
+
+	var appUsers AppUsers
+	err = parseResponse(http.StatusOK, statusCode, body, &appUsers)
+
+	return appUsers, err
+}
+
+func (letmein *AppRepo) AddUser(organizationID string, appID string, user User) (AppUser, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{appID}/users", nil, organizationID, appID)
+	if err != nil {
+		return AppUser{}, fmt.Errorf("error building url for add app user: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.AddUser", url, http.MethodPost)
+	letmein.Repo.Idempotent(req)
+	req.AddBody("name", user.Name)
+	req.AddBody("email", user.Email)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return AppUser{}, fmt.Errorf("error requesting for add app user: %w", err)
+	}
+
+	var appUserData AppUserData
+	err = parseResponse(http.StatusCreated, statusCode, body, &appUserData)
+
+	return appUserData.AppUser, err
+}
+
+func (letmein *AppRepo) RemoveUser(organizationID string, appID string, appUserID string) error {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{appID}/users/{id}", nil, organizationID, appID, appUserID)
+	if err != nil {
+		return fmt.Errorf("error building url for remove app user: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.RemoveUser", url, http.MethodDelete)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return fmt.Errorf("error requesting for remove app user: %w", err)
+	}
+
+	return parseNoContentResponse(statusCode, body)
+}
+
+func (letmein *AppRepo) CreateToken(organizationID string, appID string) (AppToken, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{appID}/tokens", nil, organizationID, appID)
+	if err != nil {
+		return AppToken{}, fmt.Errorf("error building url for create app token: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.CreateToken", url, http.MethodPost)
+	letmein.Repo.Idempotent(req)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return AppToken{}, fmt.Errorf("error requesting for create app token: %w", err)
+	}
+
+	var appTokenData AppTokenData
+	err = parseResponse(http.StatusCreated, statusCode, body, &appTokenData)
+
+	return appTokenData.AppToken, err
+}
+
+func (letmein *AppRepo) ListTokens(organizationID string, appID string) (AppTokens, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{appID}/tokens", nil, organizationID, appID)
+	if err != nil {
+		return AppTokens{}, fmt.Errorf("error building url for list app tokens: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.ListTokens", url, http.MethodGet)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return AppTokens{}, fmt.Errorf("error requesting for list app tokens: %w", err)
+	}
+
+	var appTokens AppTokens
+	err = parseResponse(http.StatusOK, statusCode, body, &appTokens)
+
+	return appTokens, err
+}
+
+func (letmein *AppRepo) FindToken(organizationID string, appID, id string) (AppToken, error) {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{appID}/tokens/{id}", nil, organizationID, appID, id)
+	if err != nil {
+		return AppToken{}, fmt.Errorf("error building url for find app token: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.FindToken", url, http.MethodGet)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return AppToken{}, fmt.Errorf("error requesting for find app token: %w", err)
+	}
+
+	var appTokenData AppTokenData
+	err = parseResponse(http.StatusOK, statusCode, body, &appTokenData)
+
+	return appTokenData.AppToken, err
+}
+
+func (letmein *AppRepo) RevokeToken(organizationID string, appID, id string) error {
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/apps/{appID}/tokens/{id}", nil, organizationID, appID, id)
+	if err != nil {
+		return fmt.Errorf("error building url for revoke app token: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("apps.RevokeToken", url, http.MethodDelete)
+	statusCode, body, err := req.DoRequest()
+
+	if err != nil {
+		return fmt.Errorf("error requesting for revoke app token: %w", err)
+	}
+
+	return parseNoContentResponse(statusCode, body)
+}
+
+func parseAppResponse(expectedStatusCode, statusCode int, body []byte) (App, error) {
+	var dataApp DataApp
+	err := parseResponse(expectedStatusCode, statusCode, body, &dataApp)
+
+	return dataApp.App, err
+}
+
+func parseResponse(expectedStatusCode, statusCode int, body []byte, target any) error {
+	if statusCode != expectedStatusCode {
+		return letmeinerr.New(statusCode, body)
+	}
+
+	err := json.Unmarshal(body, target)
+	if err != nil {
+		return fmt.Errorf("json parser error on apps: %w", err)
+	}
+
+	return nil
+}
+
+func parseNoContentResponse(statusCode int, body []byte) error {
+	if statusCode != http.StatusNoContent {
+		return letmeinerr.New(statusCode, body)
+	}
+
+	return nil
+}
+
+var _ AppDao = (*AppRepo)(nil)
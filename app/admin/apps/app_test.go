@@ -0,0 +1,344 @@
+package apps_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+)
+
+func TestCreateSuccess(t *testing.T) {
+	jsonResponse := `{
+		"data": {
+			"id": "app-1",
+			"organization_id": "123456789",
+			"name": "My App",
+			"description": "My App Description"
+		}
+	}`
+
+	var requestedPath, idempotencyKey string
+	var requestBody map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.Method + " " + r.URL.Path
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(jsonResponse))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig)
+	app, err := appRepo.Create(apps.App{OrganizationID: "123456789", Name: "My App", Description: "My App Description"})
+	if err == nil {
+		t.Log("With valid token should create App")
+	} else {
+		t.Errorf("Create App expected no errors, got %s", err)
+	}
+
+	if app.ID == "app-1" {
+		t.Log("Create App returned expected ID")
+	} else {
+		t.Errorf("App ID expected to be app-1, got %s", app.ID)
+	}
+
+	if requestedPath == "POST /rest/admin/organizations/123456789/apps" && requestBody["name"] == "My App" {
+		t.Log("Create App posts the app to its organization")
+	} else {
+		t.Errorf("Create App sent unexpected request %s %v", requestedPath, requestBody)
+	}
+
+	if len(idempotencyKey) == 36 {
+		t.Log("Create App sends a generated Idempotency-Key")
+	} else {
+		t.Errorf("Create App expected an Idempotency-Key, got %q", idempotencyKey)
+	}
+}
+
+func TestCreateInputFails(t *testing.T) {
+	jsonResponse := `{
+		"errors": {
+			"name": ["can't be blank"]
+		}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(jsonResponse))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig)
+	_, err := appRepo.Create(apps.App{OrganizationID: "123456789"})
+
+	var letmeinError *letmeinerr.LetmeinError
+	if errors.As(err, &letmeinError) && letmeinError.MainError == letmeinerr.ErrUnprocessableEntity {
+		t.Log("With blank name returns UnprocessableEntityError")
+	} else {
+		t.Errorf("With blank name does not return UnprocessableEntityError, got %v", err)
+	}
+}
+
+func TestCreateWithoutOrganizationFails(t *testing.T) {
+	adminConfig := admin.NewConfig("http://127.0.0.1:0", "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig)
+	_, err := appRepo.Create(apps.App{Name: "My App"})
+	if err != nil {
+		t.Log("Create App without organization ID returns an error")
+	} else {
+		t.Error("Create App without organization ID expected an error")
+	}
+}
+
+func TestCreateWithSuppliedIdempotencyKey(t *testing.T) {
+	var idempotencyKey string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data": {"id": "token-1", "app_id": "app-1", "token": "a-new-app-token", "created_at": "2024-01-01T00:00:00Z"}}`))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig.WithIdempotencyKey("my-own-key"))
+	appToken, err := appRepo.CreateToken("123456789", "app-1")
+	if err != nil {
+		t.Errorf("Create App Token expected no errors, got %s", err)
+	}
+
+	if appToken.Token != nil && *appToken.Token == "a-new-app-token" {
+		t.Log("Create App Token returns the new token")
+	} else {
+		t.Errorf("Create App Token returned unexpected token %+v", appToken)
+	}
+
+	if idempotencyKey == "my-own-key" {
+		t.Log("Create App Token sends the supplied Idempotency-Key as given")
+	} else {
+		t.Errorf("Create App Token expected Idempotency-Key my-own-key, got %q", idempotencyKey)
+	}
+}
+
+func TestCreateWithIdempotencySeed(t *testing.T) {
+	var idempotencyKeys []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKeys = append(idempotencyKeys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data": {"id": "token-1", "app_id": "app-1", "token": "a-new-app-token", "created_at": "2024-01-01T00:00:00Z"}}`))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig.WithIdempotencySeed("my-seed"))
+	_, _ = appRepo.CreateToken("123456789", "app-1")
+	_, _ = appRepo.CreateToken("123456789", "app-1")
+	_, _ = appRepo.CreateToken("123456789", "app-2")
+
+	if len(idempotencyKeys) == 3 && idempotencyKeys[0] != "" && idempotencyKeys[0] != "my-seed" && idempotencyKeys[0] == idempotencyKeys[1] {
+		t.Log("repeating a call with the seed repeats its derived Idempotency-Key")
+	} else {
+		t.Errorf("expected the same derived Idempotency-Key for the repeated call, got %q", idempotencyKeys)
+	}
+
+	if len(idempotencyKeys) == 3 && idempotencyKeys[2] != idempotencyKeys[0] {
+		t.Log("another call made with the seed gets its own Idempotency-Key")
+	} else {
+		t.Errorf("expected a different Idempotency-Key for another app, got %q", idempotencyKeys)
+	}
+}
+
+func TestAddUserSuccess(t *testing.T) {
+	jsonResponse := `{
+		"data": {
+			"id": "app-user-1",
+			"user": {
+				"name": "John Doe",
+				"email": "john.doe@example.com"
+			}
+		}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(jsonResponse))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig)
+	appUser, err := appRepo.AddUser("123456789", "app-1", apps.User{Email: "john.doe@example.com"})
+	if err != nil {
+		t.Errorf("Add App User expected no errors, got %s", err)
+	}
+
+	if appUser.ID == "app-user-1" && appUser.User.Email == "john.doe@example.com" {
+		t.Log("Add App User returns the added user")
+	} else {
+		t.Errorf("Add App User returned unexpected user %+v", appUser)
+	}
+}
+
+func TestListSuccess(t *testing.T) {
+	jsonResponse := `{
+		"data": [
+			{"id": "app-1", "organization_id": "123456789", "name": "My App", "description": ""},
+			{"id": "app-2", "organization_id": "123456789", "name": "My App 2", "description": ""}
+		],
+		"pagination": {"count": 2, "first": 1, "last": 1, "next": null, "page": 1, "per_page": 20, "prev": null, "serie": [1]}
+	}`
+
+	var query string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(jsonResponse))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig)
+	appList, err := appRepo.List("123456789", 1, 20)
+	if err != nil {
+		t.Errorf("List Apps expected no errors, got %s", err)
+	}
+
+	if len(appList.Apps) == 2 && appList.Apps[1].ID == "app-2" && query == "page=1&perPage=20" {
+		t.Log("List Apps returns the page of apps")
+	} else {
+		t.Errorf("List Apps returned unexpected result %+v for query %s", appList, query)
+	}
+}
+
+func TestRevokeTokenSuccess(t *testing.T) {
+	var requestedPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.Method + " " + r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	appRepo := apps.NewRepo(adminConfig)
+	err := appRepo.RevokeToken("123456789", "app-1", "token-1")
+	if err != nil {
+		t.Errorf("Revoke App Token expected no errors, got %s", err)
+	}
+
+	if requestedPath == "DELETE /rest/admin/organizations/123456789/apps/app-1/tokens/token-1" {
+		t.Log("Revoke App Token deletes the token")
+	} else {
+		t.Errorf("Revoke App Token sent unexpected request %s", requestedPath)
+	}
+}
+
+func TestFindWithInvalidTokenFails(t *testing.T) {
+	jsonResponse := `{
+		"errors": {
+			"detail": "Forbidden"
+		}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(jsonResponse))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "an-invalid-token")
+	appRepo := apps.NewRepo(adminConfig)
+	_, err := appRepo.Find("123456789", "app-1")
+
+	var letmeinError *letmeinerr.LetmeinError
+	if errors.As(err, &letmeinError) && letmeinError.MainError == letmeinerr.ErrForbidden {
+		t.Log("With invalid token returns ForbiddenError")
+	} else {
+		t.Errorf("With invalid token does not return ForbiddenError, got %v", err)
+	}
+}
+
+func TestDryRunSynthesizesAppWrites(t *testing.T) {
+	requested := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.EnableDryRun()
+	appRepo := apps.NewRepo(adminConfig)
+
+	app, err := appRepo.Create(apps.App{OrganizationID: "1", Name: "Store"})
+	if err == nil && app.ID == "dry-run-1" && app.OrganizationID == "1" {
+		t.Log("Create in dry run returns a synthesized app")
+	} else {
+		t.Errorf("Create in dry run expected a synthesized app, got %+v, %v", app, err)
+	}
+
+	appUser, err := appRepo.AddUser("1", app.ID, apps.User{Name: "Carol", Email: "carol@acme.com"})
+	if err == nil && appUser.ID == "dry-run-2" && appUser.User.Email == "carol@acme.com" {
+		t.Log("AddUser in dry run returns a synthesized app user")
+	} else {
+		t.Errorf("AddUser in dry run expected a synthesized app user, got %+v, %v", appUser, err)
+	}
+
+	appToken, err := appRepo.CreateToken("1", app.ID)
+	if err == nil && appToken.AppID == app.ID {
+		t.Log("CreateToken in dry run returns a synthesized token")
+	} else {
+		t.Errorf("CreateToken in dry run expected a synthesized token, got %+v, %v", appToken, err)
+	}
+
+	if err := appRepo.RevokeToken("1", app.ID, appToken.ID); err != nil {
+		t.Errorf("RevokeToken in dry run expected no errors, got %s", err)
+	}
+
+	if !requested && len(adminConfig.PlannedRequests()) == 4 {
+		t.Log("no write reaches the server in dry run")
+	} else {
+		t.Errorf("expected 4 planned requests and none sent, got %d and requested=%v", len(adminConfig.PlannedRequests()), requested)
+	}
+}
+
+func TestLetmeinDelegatesToAppRepo(t *testing.T) {
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"id": "app-1", "organization_id": "123456789", "name": "My App", "description": ""}}`))
+	}))
+	defer server.Close()
+
+	letmein := apps.NewLetmein(server.URL, "a-valid-token")
+	app, err := letmein.Find("123456789", "app-1")
+	if err == nil && app.ID == "app-1" && authorization == "Bearer a-valid-token" {
+		t.Log("Letmein finds apps through AppRepo with its session token")
+	} else {
+		t.Errorf("Letmein Find expected app-1 with the session token, got %+v, %v and %q", app, err, authorization)
+	}
+}
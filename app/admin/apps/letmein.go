@@ -1,13 +1,22 @@
 package apps
 
+import "github.com/adilsonchacon/goeli/config/admin"
+
+// Letmein is the original entry point of this package. It implements AppDao
+// through an AppRepo built from BaseURL and SessionToken, so create it with
+// NewLetmein.
 type Letmein struct {
+	AppRepo
 	BaseURL      string
 	SessionToken string
 }
 
 func NewLetmein(baseURL, sessionToken string) *Letmein {
 	return &Letmein{
+		AppRepo:      NewRepo(admin.NewConfig(baseURL, sessionToken)),
 		BaseURL:      baseURL,
 		SessionToken: sessionToken,
 	}
 }
+
+var _ AppDao = (*Letmein)(nil)
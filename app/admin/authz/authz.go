@@ -0,0 +1,284 @@
+// Package authz adds authorization on top of Letmein authentication: http
+// middleware that lets a request through only when the signed in user is an
+// admin of an organization or a member of an app.
+package authz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/internal/httperr"
+	"github.com/adilsonchacon/goeli/lib/bff"
+	"github.com/adilsonchacon/goeli/lib/paging"
+)
+
+const (
+	DefaultTTL     = time.Minute
+	DefaultPerPage = 100
+
+	maxCacheEntries = 10000
+)
+
+var ErrNoSessionToken = errors.New("no session token")
+
+// Authorizer resolves the caller with Auth.CurrentUser and checks membership
+// with AdminUsers and Apps, which must be backed by an admin session allowed
+// to list them. Callers and memberships are cached for TTL, so a removed
+// admin keeps access for at most that long unless Forget is called.
+type Authorizer struct {
+	Auth       *goeli.Config
+	AdminUsers organizations.AdminUserDao
+	Apps       apps.AppDao
+	TTL        time.Duration
+	PerPage    int
+
+	// SessionToken extracts the caller's session token. The default reads
+	// the token set by bff.Protect, then a Bearer Authorization header.
+	SessionToken func(r *http.Request) (string, bool)
+
+	once    sync.Once
+	users   *cache[string, *entities.User]
+	members *cache[membershipKey, map[string]bool]
+}
+
+// membershipKey identifies a cached member set. IDs are kept apart rather
+// than joined, so no org ID can be crafted to collide with another app.
+type membershipKey struct {
+	kind  string
+	orgID string
+	appID string
+}
+
+func New(auth *goeli.Config, adminUsers organizations.AdminUserDao, appDao apps.AppDao) *Authorizer {
+	return &Authorizer{
+		Auth:       auth,
+		AdminUsers: adminUsers,
+		Apps:       appDao,
+		TTL:        DefaultTTL,
+		PerPage:    DefaultPerPage,
+	}
+}
+
+type userKey struct{}
+
+// User returns the caller resolved by RequireOrgAdmin or RequireAppMember.
+func User(ctx context.Context) (*entities.User, bool) {
+	user, ok := ctx.Value(userKey{}).(*entities.User)
+	return user, ok
+}
+
+// RequireOrgAdmin lets the request through only if the caller is an admin of
+// the organization orgID returns for it.
+func (authorizer *Authorizer) RequireOrgAdmin(orgID func(r *http.Request) string) func(http.Handler) http.Handler {
+	return authorizer.require(func(r *http.Request) (membershipKey, func() (map[string]bool, error)) {
+		id := orgID(r)
+		if id == "" {
+			return membershipKey{}, nil
+		}
+
+		return membershipKey{kind: "org", orgID: id}, func() (map[string]bool, error) { return authorizer.orgAdmins(id) }
+	})
+}
+
+// RequireAppMember lets the request through only if the caller is a user of
+// the app that app returns for it. Apps belong to an organization, so both
+// IDs are needed to look it up.
+func (authorizer *Authorizer) RequireAppMember(app func(r *http.Request) (orgID, appID string)) func(http.Handler) http.Handler {
+	return authorizer.require(func(r *http.Request) (membershipKey, func() (map[string]bool, error)) {
+		orgID, appID := app(r)
+		if orgID == "" || appID == "" {
+			return membershipKey{}, nil
+		}
+
+		return membershipKey{kind: "app", orgID: orgID, appID: appID}, func() (map[string]bool, error) { return authorizer.appMembers(orgID, appID) }
+	})
+}
+
+// Forget drops every cached caller and membership, for example after
+// changing admins or app users.
+func (authorizer *Authorizer) Forget() {
+	authorizer.init()
+	authorizer.users.clear()
+	authorizer.members.clear()
+}
+
+// init creates the caches on first use, so TTL can be changed after New.
+func (authorizer *Authorizer) init() {
+	authorizer.once.Do(func() {
+		authorizer.users = newCache[string, *entities.User](authorizer.ttl(), time.Now)
+		authorizer.members = newCache[membershipKey, map[string]bool](authorizer.ttl(), time.Now)
+	})
+}
+
+func (authorizer *Authorizer) require(target func(r *http.Request) (membershipKey, func() (map[string]bool, error))) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizer.init()
+
+			user, statusCode := authorizer.currentUser(r)
+			if user == nil {
+				writeError(w, statusCode)
+				return
+			}
+
+			key, load := target(r)
+			if load == nil {
+				writeError(w, http.StatusForbidden)
+				return
+			}
+
+			members, err := authorizer.membership(key, load)
+			if err != nil {
+				writeError(w, http.StatusServiceUnavailable)
+				return
+			}
+
+			if !members[strings.ToLower(user.Email)] {
+				writeError(w, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+		})
+	}
+}
+
+// currentUser resolves the caller, or returns the status to answer with.
+func (authorizer *Authorizer) currentUser(r *http.Request) (*entities.User, int) {
+	sessionToken, ok := authorizer.sessionToken(r)
+	if !ok {
+		return nil, http.StatusUnauthorized
+	}
+
+	// Tokens are only kept hashed so the cache never holds credentials.
+	sum := sha256.Sum256([]byte(sessionToken))
+	key := hex.EncodeToString(sum[:])
+	if user, ok := authorizer.users.get(key); ok {
+		return user, http.StatusOK
+	}
+
+	user, statusCode, err := authorizer.Auth.WithContext(r.Context()).CurrentUser(sessionToken)
+	if err != nil || user == nil {
+		if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden || statusCode == http.StatusNotFound {
+			return nil, http.StatusUnauthorized
+		}
+		return nil, http.StatusServiceUnavailable
+	}
+
+	authorizer.users.set(key, user)
+
+	return user, http.StatusOK
+}
+
+func (authorizer *Authorizer) sessionToken(r *http.Request) (string, bool) {
+	if authorizer.SessionToken != nil {
+		return authorizer.SessionToken(r)
+	}
+
+	if sessionToken, ok := bff.SessionToken(r.Context()); ok {
+		return sessionToken, true
+	}
+
+	scheme, sessionToken, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(sessionToken) == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(sessionToken), true
+}
+
+func (authorizer *Authorizer) membership(key membershipKey, load func() (map[string]bool, error)) (map[string]bool, error) {
+	if members, ok := authorizer.members.get(key); ok {
+		return members, nil
+	}
+
+	members, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer.members.set(key, members)
+
+	return members, nil
+}
+
+func (authorizer *Authorizer) orgAdmins(orgID string) (map[string]bool, error) {
+	adminUsers, err := paging.All(authorizer.perPage(), func(page, perPage int) ([]organizations.AdminUser, *entities.Pagination, error) {
+		list, err := authorizer.AdminUsers.ListAdminUsers(orgID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Data, &list.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing admin users of organization %s: %w", orgID, err)
+	}
+
+	emails := make(map[string]bool)
+	for _, adminUser := range adminUsers {
+		emails[strings.ToLower(adminUser.User.Email)] = true
+	}
+
+	return emails, nil
+}
+
+// appMembers passes no pagination, since the users endpoint does not return
+// it.
+func (authorizer *Authorizer) appMembers(orgID, appID string) (map[string]bool, error) {
+	appUsers, err := paging.All(authorizer.perPage(), func(page, perPage int) ([]apps.AppUser, *entities.Pagination, error) {
+		list, err := authorizer.Apps.Users(orgID, appID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Users, nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing users of app %s: %w", appID, err)
+	}
+
+	emails := make(map[string]bool)
+	for _, appUser := range appUsers {
+		emails[strings.ToLower(appUser.User.Email)] = true
+	}
+
+	return emails, nil
+}
+
+func (authorizer *Authorizer) perPage() int {
+	if authorizer.PerPage <= 0 {
+		return DefaultPerPage
+	}
+
+	return authorizer.PerPage
+}
+
+func (authorizer *Authorizer) ttl() time.Duration {
+	if authorizer.TTL <= 0 {
+		return DefaultTTL
+	}
+
+	return authorizer.TTL
+}
+
+// writeError answers like Letmein does, so letmeinerr.New maps the response
+// to the matching sentinel, for example ErrForbidden.
+func writeError(w http.ResponseWriter, statusCode int) {
+	detail := map[int]string{
+		http.StatusUnauthorized:       "unauthorized",
+		http.StatusForbidden:          "forbidden",
+		http.StatusServiceUnavailable: "authorization unavailable",
+	}[statusCode]
+
+	httperr.Write(w, statusCode, detail)
+}
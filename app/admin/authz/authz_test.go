@@ -0,0 +1,192 @@
+package authz_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/authz"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+)
+
+type fakeAdminUsers struct {
+	organizations.AdminUserDao
+	calls int
+}
+
+func (dao *fakeAdminUsers) ListAdminUsers(orgID string, page, perPage int) (*organizations.AdminUsers, error) {
+	dao.calls++
+	if orgID != "acme" {
+		return &organizations.AdminUsers{}, nil
+	}
+
+	return &organizations.AdminUsers{Data: []organizations.AdminUser{
+		{ID: "1", User: organizations.User{Email: "Admin@Acme.com"}},
+	}}, nil
+}
+
+type fakeApps struct {
+	apps.AppDao
+}
+
+func (dao *fakeApps) Users(orgID, appID string, page, perPage int) (apps.AppUsers, error) {
+	if orgID != "acme" || (appID != "store" && appID != "team/store") {
+		return apps.AppUsers{}, nil
+	}
+
+	return apps.AppUsers{Users: []apps.AppUser{{ID: "7", User: apps.User{Email: "member@acme.com"}}}}, nil
+}
+
+type testAuthz struct {
+	server          *httptest.Server
+	adminUsers      *fakeAdminUsers
+	currentUserHits int
+}
+
+func newTestAuthz(t *testing.T) *testAuthz {
+	t.Helper()
+	test := &testAuthz{adminUsers: &fakeAdminUsers{}}
+
+	letmein := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.currentUserHits++
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Header.Get("authorization") {
+		case "Bearer admin-token":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": {"id": "1", "email": "admin@acme.com"}}`))
+		case "Bearer member-token":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": {"id": "7", "email": "member@acme.com"}}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": {"detail": "invalid token"}}`))
+		}
+	}))
+	t.Cleanup(letmein.Close)
+
+	authorizer := authz.New(goeli.NewServiceConfig("", letmein.URL, "some-app-token"), test.adminUsers, &fakeApps{})
+
+	mux := http.NewServeMux()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := authz.User(r.Context())
+		w.Write([]byte(user.Email))
+	})
+	mux.Handle("/orgs/{orgID}", authorizer.RequireOrgAdmin(func(r *http.Request) string {
+		return r.PathValue("orgID")
+	})(ok))
+	mux.Handle("/orgs/{orgID}/apps/{appID}", authorizer.RequireAppMember(func(r *http.Request) (string, string) {
+		return r.PathValue("orgID"), r.PathValue("appID")
+	})(ok))
+
+	test.server = httptest.NewServer(mux)
+	t.Cleanup(test.server.Close)
+
+	return test
+}
+
+func (test *testAuthz) get(t *testing.T, path, sessionToken string) (int, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, test.server.URL+path, nil)
+	if sessionToken != "" {
+		req.Header.Set("Authorization", "Bearer "+sessionToken)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+
+	return res.StatusCode, body
+}
+
+func TestRequireOrgAdmin(t *testing.T) {
+	test := newTestAuthz(t)
+
+	cases := []struct {
+		name         string
+		path         string
+		sessionToken string
+		expected     int
+	}{
+		{"admin of the organization", "/orgs/acme", "admin-token", http.StatusOK},
+		{"admin of another organization", "/orgs/globex", "admin-token", http.StatusForbidden},
+		{"user who is not an admin", "/orgs/acme", "member-token", http.StatusForbidden},
+		{"invalid session token", "/orgs/acme", "expired-token", http.StatusUnauthorized},
+		{"no session token", "/orgs/acme", "", http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		statusCode, _ := test.get(t, c.path, c.sessionToken)
+		if statusCode == c.expected {
+			t.Logf("%s returns %d", c.name, c.expected)
+		} else {
+			t.Errorf("%s expected %d, got %d", c.name, c.expected, statusCode)
+		}
+	}
+}
+
+func TestRequireAppMember(t *testing.T) {
+	test := newTestAuthz(t)
+
+	if statusCode, body := test.get(t, "/orgs/acme/apps/store", "member-token"); statusCode == http.StatusOK && string(body) == "member@acme.com" {
+		t.Log("app member passes and the handler sees the caller")
+	} else {
+		t.Errorf("app member expected 200, got %d %s", statusCode, body)
+	}
+
+	if statusCode, _ := test.get(t, "/orgs/acme/apps/store", "admin-token"); statusCode == http.StatusForbidden {
+		t.Log("organization admin who is not an app user is rejected")
+	} else {
+		t.Errorf("non member expected 403, got %d", statusCode)
+	}
+}
+
+func TestForbiddenBodyMapsToErrForbidden(t *testing.T) {
+	test := newTestAuthz(t)
+
+	statusCode, body := test.get(t, "/orgs/acme", "member-token")
+	err := letmeinerr.New(statusCode, body)
+
+	if errors.Is(err, letmeinerr.ErrForbidden) && err.Detail() == "forbidden" {
+		t.Log("403 body parses as letmeinerr.ErrForbidden")
+	} else {
+		t.Errorf("expected ErrForbidden with detail forbidden, got %v from %s", err, body)
+	}
+}
+
+func TestMembershipIsCached(t *testing.T) {
+	test := newTestAuthz(t)
+
+	for i := 0; i < 3; i++ {
+		test.get(t, "/orgs/acme", "admin-token")
+	}
+
+	if test.adminUsers.calls == 1 && test.currentUserHits == 1 {
+		t.Log("caller and admin list are looked up once")
+	} else {
+		t.Errorf("expected one lookup each, got %d admin lists and %d current user calls", test.adminUsers.calls, test.currentUserHits)
+	}
+}
+
+func TestMembershipCacheKeepsIDsApart(t *testing.T) {
+	test := newTestAuthz(t)
+
+	if statusCode, _ := test.get(t, "/orgs/acme/apps/team%2Fstore", "member-token"); statusCode != http.StatusOK {
+		t.Fatalf("member of acme app team/store expected 200, got %d", statusCode)
+	}
+
+	if statusCode, _ := test.get(t, "/orgs/acme%2Fteam/apps/store", "member-token"); statusCode == http.StatusForbidden {
+		t.Log("org acme/team app store does not reuse the members of org acme app team/store")
+	} else {
+		t.Errorf("org acme/team app store expected 403, got %d", statusCode)
+	}
+}
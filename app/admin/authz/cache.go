@@ -0,0 +1,80 @@
+package authz
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// cache keeps values for a fixed TTL. Expired entries are dropped when they
+// are read or when the cache is swept on insert. A full cache with nothing
+// expired evicts the entry closest to expiring, so it never holds more than
+// maxCacheEntries.
+type cache[K comparable, V any] struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[K]cacheEntry[V]
+	now     func() time.Time
+}
+
+func newCache[K comparable, V any](ttl time.Duration, now func() time.Time) *cache[K, V] {
+	return &cache[K, V]{ttl: ttl, entries: make(map[K]cacheEntry[V]), now: now}
+}
+
+func (c *cache[K, V]) get(key K) (V, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+func (c *cache[K, V]) set(key K, value V) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		for entryKey, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, entryKey)
+			}
+		}
+	}
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxCacheEntries {
+		c.evictOldest()
+	}
+
+	c.entries[key] = cacheEntry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *cache[K, V]) evictOldest() {
+	var oldestKey K
+	var oldest time.Time
+	found := false
+
+	for entryKey, entry := range c.entries {
+		if !found || entry.expiresAt.Before(oldest) {
+			oldestKey, oldest, found = entryKey, entry.expiresAt, true
+		}
+	}
+
+	delete(c.entries, oldestKey)
+}
+
+func (c *cache[K, V]) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	clear(c.entries)
+}
@@ -0,0 +1,296 @@
+package bulkimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/lib/paging"
+)
+
+const (
+	StatusAdded    = "added"
+	StatusWouldAdd = "would_add"
+	StatusSkipped  = "skipped"
+	StatusInvalid  = "invalid"
+	StatusFailed   = "failed"
+)
+
+var ErrNoOrganization = errors.New("no organization id for row")
+
+type Options struct {
+	// OrganizationID is used for rows that do not name their own organization.
+	OrganizationID string
+	Concurrency    int
+	DryRun         bool
+	PerPage        int
+}
+
+type Row struct {
+	Line           int
+	Email          string
+	OrganizationID string
+}
+
+type Result struct {
+	Line           int    `json:"line"`
+	Email          string `json:"email"`
+	OrganizationID string `json:"organization_id"`
+	Status         string `json:"status"`
+	AdminUserID    string `json:"admin_user_id,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+type Importer struct {
+	Dao     organizations.AdminUserDao
+	Options Options
+
+	mu     sync.Mutex
+	admins map[string]*organizationAdmins
+}
+
+type organizationAdmins struct {
+	once   sync.Once
+	emails map[string]bool
+	err    error
+}
+
+func New(dao organizations.AdminUserDao, options Options) *Importer {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+	if options.PerPage <= 0 {
+		options.PerPage = 100
+	}
+
+	return &Importer{
+		Dao:     dao,
+		Options: options,
+		admins:  make(map[string]*organizationAdmins),
+	}
+}
+
+// ReadCSV reads one admin per row as "email" or "email,organization_id". A
+// first row naming the columns, such as "email,organization_id", is treated
+// as a header and may list the columns in any order.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	emailColumn, organizationColumn := 0, 1
+	var rows []Row
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading admin users csv: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(rows) == 0 && isHeader(record) {
+			emailColumn, organizationColumn = headerColumns(record)
+			continue
+		}
+
+		rows = append(rows, Row{
+			Line:           line,
+			Email:          column(record, emailColumn),
+			OrganizationID: column(record, organizationColumn),
+		})
+	}
+
+	return rows, nil
+}
+
+func (importer *Importer) ImportCSV(r io.Reader) ([]Result, error) {
+	rows, err := ReadCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return importer.Import(rows), nil
+}
+
+// Import adds every row with at most Options.Concurrency calls in flight.
+// Results are returned in row order; a repeated email for the same
+// organization is skipped in favour of its first row.
+func (importer *Importer) Import(rows []Row) []Result {
+	results := make([]Result, len(rows))
+	semaphore := make(chan struct{}, importer.Options.Concurrency)
+	var wg sync.WaitGroup
+	firstLines := make(map[string]int)
+
+	for i, row := range rows {
+		row.Email = strings.TrimSpace(row.Email)
+		row.OrganizationID = strings.TrimSpace(row.OrganizationID)
+		if row.OrganizationID == "" {
+			row.OrganizationID = importer.Options.OrganizationID
+		}
+
+		key := row.OrganizationID + "\x00" + strings.ToLower(row.Email)
+		if firstLine, seen := firstLines[key]; seen {
+			results[i] = Result{
+				Line:           row.Line,
+				Email:          row.Email,
+				OrganizationID: row.OrganizationID,
+				Status:         StatusSkipped,
+				Error:          fmt.Sprintf("duplicate of line %d", firstLine),
+			}
+			continue
+		}
+		firstLines[key] = row.Line
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, row Row) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = importer.importRow(row)
+		}(i, row)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (importer *Importer) importRow(row Row) Result {
+	result := Result{Line: row.Line, Email: row.Email, OrganizationID: row.OrganizationID}
+
+	if !strings.Contains(result.Email, "@") {
+		result.Status = StatusInvalid
+		result.Error = fmt.Sprintf("invalid email %q", result.Email)
+		return result
+	}
+	if result.OrganizationID == "" {
+		result.Status = StatusInvalid
+		result.Error = ErrNoOrganization.Error()
+		return result
+	}
+
+	admins, err := importer.organizationAdmins(result.OrganizationID)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	if !importer.reserve(admins, result.Email) {
+		result.Status = StatusSkipped
+		return result
+	}
+
+	if importer.Options.DryRun {
+		result.Status = StatusWouldAdd
+		return result
+	}
+
+	adminUserData, err := importer.Dao.AddAdminUser(result.OrganizationID, result.Email)
+	if err != nil {
+		importer.release(admins, result.Email)
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = StatusAdded
+	result.AdminUserID = adminUserData.Data.ID
+
+	return result
+}
+
+func (importer *Importer) organizationAdmins(organizationID string) (*organizationAdmins, error) {
+	importer.mu.Lock()
+	admins, ok := importer.admins[organizationID]
+	if !ok {
+		admins = &organizationAdmins{}
+		importer.admins[organizationID] = admins
+	}
+	importer.mu.Unlock()
+
+	admins.once.Do(func() {
+		admins.emails, admins.err = importer.listAdmins(organizationID)
+	})
+
+	return admins, admins.err
+}
+
+func (importer *Importer) listAdmins(organizationID string) (map[string]bool, error) {
+	adminUsers, err := paging.All(importer.Options.PerPage, func(page, perPage int) ([]organizations.AdminUser, *entities.Pagination, error) {
+		list, err := importer.Dao.ListAdminUsers(organizationID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Data, &list.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing admin users of organization %s: %w", organizationID, err)
+	}
+
+	emails := make(map[string]bool)
+	for _, adminUser := range adminUsers {
+		emails[strings.ToLower(adminUser.User.Email)] = true
+	}
+
+	return emails, nil
+}
+
+func (importer *Importer) reserve(admins *organizationAdmins, email string) bool {
+	importer.mu.Lock()
+	defer importer.mu.Unlock()
+
+	key := strings.ToLower(email)
+	if admins.emails[key] {
+		return false
+	}
+	admins.emails[key] = true
+
+	return true
+}
+
+func (importer *Importer) release(admins *organizationAdmins, email string) {
+	importer.mu.Lock()
+	defer importer.mu.Unlock()
+
+	delete(admins.emails, strings.ToLower(email))
+}
+
+func isHeader(record []string) bool {
+	for _, field := range record {
+		if strings.EqualFold(strings.TrimSpace(field), "email") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func headerColumns(record []string) (int, int) {
+	emailColumn, organizationColumn := -1, -1
+	for i, field := range record {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "email":
+			emailColumn = i
+		case "organization_id", "org_id", "organization":
+			organizationColumn = i
+		}
+	}
+
+	return emailColumn, organizationColumn
+}
+
+func column(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[index])
+}
@@ -0,0 +1,179 @@
+package bulkimport_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/app/admin/bulkimport"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+)
+
+type fakeAdminUserDao struct {
+	mu       sync.Mutex
+	existing map[string][]string
+	added    []string
+	inFlight int
+	maxSeen  int
+}
+
+func (dao *fakeAdminUserDao) ListAdminUsers(orgID string, page, perPage int) (*organizations.AdminUsers, error) {
+	if orgID == "missing" {
+		return nil, letmeinerr.New(http.StatusNotFound, nil)
+	}
+
+	emails := dao.existing[orgID]
+	start := (page - 1) * perPage
+	end := start + perPage
+	if end > len(emails) {
+		end = len(emails)
+	}
+
+	adminUsers := &organizations.AdminUsers{}
+	for _, email := range emails[start:end] {
+		adminUsers.Data = append(adminUsers.Data, organizations.AdminUser{ID: "existing", User: organizations.User{Email: email}})
+	}
+	if end < len(emails) {
+		next := page + 1
+		adminUsers.Pagination.Next = &next
+	}
+
+	return adminUsers, nil
+}
+
+func (dao *fakeAdminUserDao) AddAdminUser(orgID, email string) (*organizations.AdminUserData, error) {
+	dao.mu.Lock()
+	dao.inFlight++
+	if dao.inFlight > dao.maxSeen {
+		dao.maxSeen = dao.inFlight
+	}
+	dao.mu.Unlock()
+
+	defer func() {
+		dao.mu.Lock()
+		dao.inFlight--
+		dao.mu.Unlock()
+	}()
+
+	if strings.HasPrefix(email, "rejected") {
+		return nil, letmeinerr.New(http.StatusUnprocessableEntity, []byte(`{"errors": {"email": ["is invalid"]}}`))
+	}
+
+	dao.mu.Lock()
+	dao.added = append(dao.added, orgID+"/"+email)
+	dao.mu.Unlock()
+
+	return &organizations.AdminUserData{Data: organizations.AdminUser{ID: "new-" + email, User: organizations.User{Email: email}}}, nil
+}
+
+func (dao *fakeAdminUserDao) RemoveAdminUser(orgID string, adminUserID string) error {
+	return nil
+}
+
+const csvInput = `email,organization_id
+new@example.com,
+Existing@Example.com,
+other@example.com,org-2
+not-an-email,
+rejected@example.com,
+new@example.com,
+someone@example.com,missing
+`
+
+func statuses(results []bulkimport.Result) []string {
+	var out []string
+	for _, result := range results {
+		out = append(out, result.Status)
+	}
+	return out
+}
+
+func TestImportCSVAddsAndSkips(t *testing.T) {
+	dao := &fakeAdminUserDao{existing: map[string][]string{
+		"org-1": {"a@example.com", "b@example.com", "existing@example.com"},
+	}}
+
+	importer := bulkimport.New(dao, bulkimport.Options{OrganizationID: "org-1", Concurrency: 2, PerPage: 2})
+	results, err := importer.ImportCSV(strings.NewReader(csvInput))
+	if err != nil {
+		t.Fatalf("ImportCSV expected no errors, got %s", err)
+	}
+
+	expected := []string{"added", "skipped", "added", "invalid", "failed", "skipped", "failed"}
+	if strings.Join(statuses(results), ",") == strings.Join(expected, ",") {
+		t.Log("rows are added, skipped or reported as failed in row order")
+	} else {
+		t.Errorf("expected statuses %v, got %v", expected, statuses(results))
+	}
+
+	if results[0].Line == 2 && results[0].AdminUserID == "new-new@example.com" && results[2].OrganizationID == "org-2" {
+		t.Log("results carry line numbers, organization and the new admin user ID")
+	} else {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if len(dao.added) == 2 && dao.maxSeen <= 2 {
+		t.Log("only new admins are added, within the concurrency limit")
+	} else {
+		t.Errorf("expected two adds with at most 2 in flight, got %v (max %d)", dao.added, dao.maxSeen)
+	}
+}
+
+func TestImportDryRunAddsNothing(t *testing.T) {
+	dao := &fakeAdminUserDao{existing: map[string][]string{"org-1": {"existing@example.com"}}}
+
+	importer := bulkimport.New(dao, bulkimport.Options{OrganizationID: "org-1", DryRun: true})
+	results, err := importer.ImportCSV(strings.NewReader("new@example.com\nexisting@example.com\n"))
+	if err != nil {
+		t.Fatalf("ImportCSV expected no errors, got %s", err)
+	}
+
+	if strings.Join(statuses(results), ",") == "would_add,skipped" && len(dao.added) == 0 {
+		t.Log("dry run reports what would be added without adding")
+	} else {
+		t.Errorf("unexpected dry run results %v, added %v", statuses(results), dao.added)
+	}
+}
+
+func TestReports(t *testing.T) {
+	results := []bulkimport.Result{
+		{Line: 2, Email: "new@example.com", OrganizationID: "org-1", Status: "added", AdminUserID: "a1"},
+		{Line: 3, Email: "bad", OrganizationID: "org-1", Status: "invalid", Error: `invalid email "bad"`},
+	}
+
+	var csvReport bytes.Buffer
+	if err := bulkimport.WriteCSV(&csvReport, results); err != nil {
+		t.Fatalf("WriteCSV expected no errors, got %s", err)
+	}
+	expected := "line,email,organization_id,status,admin_user_id,error\n2,new@example.com,org-1,added,a1,\n3,bad,org-1,invalid,,\"invalid email \"\"bad\"\"\"\n"
+	if csvReport.String() == expected {
+		t.Log("CSV report has one line per row")
+	} else {
+		t.Errorf("unexpected CSV report:\n%s", csvReport.String())
+	}
+
+	var jsonReport bytes.Buffer
+	if err := bulkimport.WriteJSON(&jsonReport, results); err != nil {
+		t.Fatalf("WriteJSON expected no errors, got %s", err)
+	}
+	var decoded []bulkimport.Result
+	if err := json.Unmarshal(jsonReport.Bytes(), &decoded); err == nil && len(decoded) == 2 && decoded[1].Status == "invalid" {
+		t.Log("JSON report round-trips")
+	} else {
+		t.Errorf("unexpected JSON report %s", jsonReport.String())
+	}
+}
+
+func TestReadCSVRejectsMalformedInput(t *testing.T) {
+	_, err := bulkimport.ReadCSV(strings.NewReader("email\n\"unterminated\n"))
+	if err != nil && !errors.Is(err, bulkimport.ErrNoOrganization) {
+		t.Log("malformed CSV returns an error")
+	} else {
+		t.Errorf("expected a CSV parse error, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package bulkimport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+func WriteCSV(w io.Writer, results []Result) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"line", "email", "organization_id", "status", "admin_user_id", "error"}); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		record := []string{
+			strconv.Itoa(result.Line),
+			result.Email,
+			result.OrganizationID,
+			result.Status,
+			result.AdminUserID,
+			result.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func WriteJSON(w io.Writer, results []Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(results)
+}
@@ -2,6 +2,6 @@ package organizations
 
 type AdminUserDao interface {
 	ListAdminUsers(orgID string, page, perPage int) (*AdminUsers, error)
-	AddAdminUser(orgID, email string) (*AdminUser, error)
+	AddAdminUser(orgID, email string) (*AdminUserData, error)
 	RemoveAdminUser(orgID string, adminUserID string) error
 }
@@ -4,46 +4,56 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/adilsonchacon/goeli/lib/letmeinerr"
 	"github.com/adilsonchacon/goeli/lib/restapi"
 )
 
 func (letmein *OrganizationRepo) ListAdminUsers(orgID string, page, perPage int) (*AdminUsers, error) {
-	url := letmein.Repo.BaseURL + "/rest/admin/organizations/" + orgID + "/admin_users?page=" + strconv.Itoa(page) + "&perPage=" + strconv.Itoa(perPage)
-	req := restapi.New(url, http.MethodGet)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/admin_users", restapi.PageQuery(page, perPage), orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for list of organization's admin users: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.ListAdminUsers", url, http.MethodGet)
 	statusCode, body, err := req.DoRequest()
 
 	if err != nil {
-		return nil, fmt.Errorf("error requesting list of organization's admin users: %s", err)
+		return nil, fmt.Errorf("error requesting list of organization's admin users: %w", err)
 	}
 
 	return parseListAdminUsersResponse(statusCode, body)
 }
 
 func (letmein *OrganizationRepo) AddAdminUser(orgID, email string) (*AdminUserData, error) {
-	url := letmein.Repo.BaseURL + "/rest/admin/organizations/" + orgID + "/admin_users"
-	req := restapi.New(url, http.MethodPost)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/admin_users", nil, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for add organization's admin user: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.AddAdminUser", url, http.MethodPost)
+	letmein.Repo.Idempotent(req)
+	req.AddBody("email", email)
 	statusCode, body, err := req.DoRequest()
 
 	if err != nil {
-		return nil, fmt.Errorf("error requesting list of organization's admin users: %s", err)
+		return nil, fmt.Errorf("error requesting list of organization's admin users: %w", err)
 	}
 
 	return parseAddAdminUserResponse(statusCode, body)
 }
 
 func (letmein *OrganizationRepo) RemoveAdminUser(orgID, adminUserID string) error {
-	url := letmein.Repo.BaseURL + "/rest/admin/organizations/" + orgID + "/admin_users/" + adminUserID
-	req := restapi.New(url, http.MethodPost)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{orgID}/admin_users/{adminUserID}", nil, orgID, adminUserID)
+	if err != nil {
+		return fmt.Errorf("error building url for delete organization's admin user: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.RemoveAdminUser", url, http.MethodDelete)
 	statusCode, body, err := req.DoRequest()
 
 	if err != nil {
-		return fmt.Errorf("error requesting delete organization's admin users: %s", err)
+		return fmt.Errorf("error requesting delete organization's admin users: %w", err)
 	}
 
 	return parseRemoveResponse(statusCode, body)
@@ -102,3 +112,5 @@ func parseRemoveResponse(statusCode int, body []byte) error {
 
 	return err
 }
+
+var _ AdminUserDao = (*OrganizationRepo)(nil)
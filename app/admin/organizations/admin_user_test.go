@@ -43,6 +43,7 @@ func TestListAdminUsersSuccess(t *testing.T) {
 
 	sessionToken := "a-valid-token"
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -92,6 +93,7 @@ func TestListAdminUsersWithInvalidTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -128,6 +130,7 @@ func TestListAdminUsersWithInvalidOrgIdFails(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -167,6 +170,7 @@ func TestAddAdminUserSuccess(t *testing.T) {
 
 	sessionToken := "a-valid-token"
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -198,6 +202,7 @@ func TestAddAdminUserWithInvalidTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -234,6 +239,7 @@ func TestAddAdminUserWithInvalidInputFails(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -265,6 +271,7 @@ func TestRemoveAdminUserSuccess(t *testing.T) {
 
 	sessionToken := "a-valid-token"
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNoContent)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -290,6 +297,7 @@ func TestRemoveAdminUserWithInvalidTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -326,6 +334,7 @@ func TestRemoveAdminUserWithInvalidInputFails(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(jsonResponse))
 	}))
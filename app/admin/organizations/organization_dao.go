@@ -3,7 +3,7 @@ package organizations
 type OrganizationDao interface {
 	List(page, perPage int) (*Organizations, error)
 	Find(id string) (*Organization, error)
-	Create(newOrganization Organization) (Organization, error)
-	Update(organization Organization) error
+	Create(newOrganization Organization) (*Organization, error)
+	Update(organization Organization) (*Organization, error)
 	Delete(id string) error
 }
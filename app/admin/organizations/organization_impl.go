@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/adilsonchacon/goeli/config/admin"
 	"github.com/adilsonchacon/goeli/lib/letmeinerr"
@@ -20,8 +19,13 @@ func NewRepo(repo *admin.Config) OrganizationRepo {
 }
 
 func (letmein *OrganizationRepo) Create(newOrganization Organization) (*Organization, error) {
-	req := restapi.New(letmein.Repo.BaseURL+"/rest/admin/organizations", http.MethodPost)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for create organization: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.Create", url, http.MethodPost)
+	letmein.Repo.Idempotent(req)
 	req.AddBody("name", newOrganization.Name)
 	req.AddBody("description", newOrganization.Description)
 	statusCode, body, err := req.DoRequest()
@@ -34,51 +38,66 @@ func (letmein *OrganizationRepo) Create(newOrganization Organization) (*Organiza
 }
 
 func (letmein *OrganizationRepo) Update(organization Organization) (*Organization, error) {
-	req := restapi.New(letmein.Repo.BaseURL+"/rest/admin/organizations/"+organization.ID, http.MethodPut)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{id}", nil, organization.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for update organization: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.Update", url, http.MethodPut)
 	req.AddBody("name", organization.Name)
 	req.AddBody("description", organization.Description)
 	statusCode, body, err := req.DoRequest()
 
 	if err != nil {
-		return nil, fmt.Errorf("error requesting for create organization: %w", err)
+		return nil, fmt.Errorf("error requesting for update organization: %w", err)
 	}
 
 	return parseCreateResponse(statusCode, body)
 }
 
 func (letmein *OrganizationRepo) Find(id string) (*Organization, error) {
-	req := restapi.New(letmein.Repo.BaseURL+"/rest/admin/organizations/"+id, http.MethodGet)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{id}", nil, id)
+	if err != nil {
+		return nil, fmt.Errorf("error building url for Find Organization: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.Find", url, http.MethodGet)
 	statusCode, body, err := req.DoRequest()
 
 	if err != nil {
-		return nil, fmt.Errorf("error requesting Find Organization: %s", err)
+		return nil, fmt.Errorf("error requesting Find Organization: %w", err)
 	}
 
 	return parseFindResponse(statusCode, body)
 }
 
 func (letmein *OrganizationRepo) Delete(id string) error {
-	req := restapi.New(letmein.Repo.BaseURL+"/rest/admin/organizations/"+id, http.MethodGet)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations/{id}", nil, id)
+	if err != nil {
+		return fmt.Errorf("error building url for delete organization: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.Delete", url, http.MethodDelete)
 	statusCode, body, err := req.DoRequest()
 
 	if err != nil {
-		return fmt.Errorf("error requesting delete organization: %s", err)
+		return fmt.Errorf("error requesting delete organization: %w", err)
 	}
 
 	return parseDeleteResponse(statusCode, body)
 }
 
 func (letmein *OrganizationRepo) List(page, perPage int) (*Organizations, error) {
-	url := letmein.Repo.BaseURL + "/rest/admin/organizations?page=" + strconv.Itoa(page) + "&perPage=" + strconv.Itoa(perPage)
-	req := restapi.New(url, http.MethodGet)
-	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", letmein.Repo.SessionToken))
+	url, err := restapi.BuildURL(letmein.Repo.BaseURL, "/rest/admin/organizations", restapi.PageQuery(page, perPage))
+	if err != nil {
+		return nil, fmt.Errorf("error building url for list organizations: %w", err)
+	}
+
+	req := letmein.Repo.NewRequest("organizations.List", url, http.MethodGet)
 	statusCode, body, err := req.DoRequest()
 
 	if err != nil {
-		return nil, fmt.Errorf("error requesting delete organization: %s", err)
+		return nil, fmt.Errorf("error requesting delete organization: %w", err)
 	}
 
 	return parseListResponse(statusCode, body)
@@ -151,3 +170,5 @@ func parseOrganizationResponse(body []byte) (*Organization, error) {
 
 	return &dataOrganization.Organization, nil
 }
+
+var _ OrganizationDao = (*OrganizationRepo)(nil)
@@ -9,6 +9,7 @@ import (
 	"github.com/adilsonchacon/goeli/app/admin/organizations"
 	"github.com/adilsonchacon/goeli/config/admin"
 	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
 )
 
 func TestCreateSuccess(t *testing.T) {
@@ -23,6 +24,7 @@ func TestCreateSuccess(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -58,6 +60,7 @@ func TestCreateInputFails(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -100,6 +103,7 @@ func TestCreateTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -143,6 +147,7 @@ func TestFindSuccess(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -173,6 +178,7 @@ func TestFindWithNotExistentIdFails(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -209,6 +215,7 @@ func TestFindWithInvalidTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -248,11 +255,13 @@ func TestUpdateSuccess(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte(jsonResponse))
 	}))
 
 	updateOrganization := organizations.Organization{
+		ID:          "123456789",
 		Name:        "Updated Organization Name",
 		Description: "UpdatedUpdated Organization Description",
 	}
@@ -277,11 +286,13 @@ func TestUpdateInputFails(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		w.Write([]byte(jsonResponse))
 	}))
 
 	updateOrganization := organizations.Organization{
+		ID:          "123456789",
 		Name:        "",
 		Description: "UpdatedUpdated Organization Description",
 	}
@@ -318,11 +329,13 @@ func TestUpdateTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
 
 	updateOrganization := organizations.Organization{
+		ID:          "123456789",
 		Name:        "Updated Organization Name",
 		Description: "UpdatedUpdated Organization Description",
 	}
@@ -355,6 +368,7 @@ func TestDeleteSuccess(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNoContent)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -379,6 +393,7 @@ func TestDeleteInputFails(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -415,6 +430,7 @@ func TestDeleteTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -470,6 +486,7 @@ func TestListSuccess(t *testing.T) {
 	sessionToken := "a-valid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -500,6 +517,7 @@ func TestListWithInvalidTokenFails(t *testing.T) {
 	sessionToken := "an-invalid-token"
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(jsonResponse))
 	}))
@@ -526,3 +544,159 @@ func TestListWithInvalidTokenFails(t *testing.T) {
 	}
 
 }
+
+func TestFindWithUnsafeIdFailsWithoutRequesting(t *testing.T) {
+	requested := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	organizationRepo := organizations.NewRepo(adminConfig)
+
+	for _, id := range []string{"", ".."} {
+		_, err := organizationRepo.Find(id)
+		if err != nil {
+			t.Logf("With ID %q should return error", id)
+		} else {
+			t.Errorf("With ID %q expected error, got none", id)
+		}
+	}
+
+	if !requested {
+		t.Log("Unsafe IDs are rejected before sending")
+	} else {
+		t.Error("Unsafe IDs should not reach the server")
+	}
+}
+
+func TestFindEscapesId(t *testing.T) {
+	jsonResponse := `{
+		"data": {
+			"id": "a/b?c",
+			"name": "My Organization",
+			"description": "My Organization Description"
+		}
+	}`
+
+	var requestedPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.EscapedPath()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(jsonResponse))
+	}))
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	organizationRepo := organizations.NewRepo(adminConfig)
+	_, err := organizationRepo.Find("a/b?c")
+	if err != nil {
+		t.Errorf("Find Organization expected no errors, got %s", err)
+	}
+
+	if requestedPath == "/rest/admin/organizations/a%2Fb%3Fc" {
+		t.Log("Find Organization escapes the ID as a single segment")
+	} else {
+		t.Errorf("Find Organization requested unexpected path %s", requestedPath)
+	}
+}
+
+func TestRequestsUseStoredSessionToken(t *testing.T) {
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := tokenstore.NewMemory()
+	adminConfig := admin.NewConfig(server.URL, "")
+	adminConfig.TokenStore = store
+	adminConfig.TokenKey = "default"
+	organizationRepo := organizations.NewRepo(adminConfig)
+
+	if err := organizationRepo.Delete("123456789"); errors.Is(err, tokenstore.ErrNotFound) && authorization == "" {
+		t.Log("Without a stored token the request is not sent")
+	} else {
+		t.Errorf("Without a stored token expected ErrNotFound and no request, got %v", err)
+	}
+
+	store.Put("default", "a-stored-token")
+
+	if err := organizationRepo.Delete("123456789"); err == nil && authorization == "Bearer a-stored-token" {
+		t.Log("An empty SessionToken is read from the token store")
+	} else {
+		t.Errorf("Expected the stored token to be sent, got %q, %v", authorization, err)
+	}
+}
+
+func TestDryRunRecordsMutations(t *testing.T) {
+	var methods []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": [], "pagination": {}}`))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.EnableDryRun()
+	organizationRepo := organizations.NewRepo(adminConfig)
+
+	created, err := organizationRepo.Create(organizations.Organization{Name: "My Organization", Description: "My Organization Description"})
+	if err == nil && created.ID == "dry-run-1" && created.Name == "My Organization" {
+		t.Log("Create in dry run returns a synthesized organization")
+	} else {
+		t.Errorf("Create in dry run expected a synthesized organization, got %+v, %v", created, err)
+	}
+
+	updated, err := organizationRepo.Update(organizations.Organization{ID: created.ID, Name: "Renamed"})
+	if err == nil && updated.ID == created.ID && updated.Name == "Renamed" {
+		t.Log("Update in dry run returns the updated organization")
+	} else {
+		t.Errorf("Update in dry run expected the updated organization, got %+v, %v", updated, err)
+	}
+
+	adminUser, err := organizationRepo.AddAdminUser(created.ID, "admin@acme.com")
+	if err == nil && adminUser.Data.User.Email == "admin@acme.com" {
+		t.Log("AddAdminUser in dry run returns a synthesized admin user")
+	} else {
+		t.Errorf("AddAdminUser in dry run expected a synthesized admin user, got %+v, %v", adminUser, err)
+	}
+
+	if err := organizationRepo.Delete(created.ID); err != nil {
+		t.Errorf("Delete in dry run expected no errors, got %s", err)
+	}
+
+	if _, err := organizationRepo.List(1, 10); err != nil {
+		t.Errorf("List in dry run expected no errors, got %s", err)
+	}
+
+	if len(methods) == 1 && methods[0] == http.MethodGet {
+		t.Log("only reads reach the server in dry run")
+	} else {
+		t.Errorf("expected only the List request to be sent, got %v", methods)
+	}
+
+	planned := adminConfig.PlannedRequests()
+	if len(planned) == 4 && planned[0].Method == http.MethodPost && planned[0].Path == "/rest/admin/organizations" &&
+		planned[3].Method == http.MethodDelete && planned[3].Path == "/rest/admin/organizations/dry-run-1" {
+		t.Log("PlannedRequests lists the mutating calls in order")
+	} else {
+		t.Errorf("unexpected planned requests %+v", planned)
+	}
+
+	if string(planned[2].Body) == `{"email":"admin@acme.com"}` && planned[2].Operation == "organizations.AddAdminUser" {
+		t.Log("planned requests carry operation and body")
+	} else {
+		t.Errorf("unexpected planned AddAdminUser %+v", planned[2])
+	}
+}
@@ -0,0 +1,219 @@
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+)
+
+func createOrganization(reconciler *Reconciler, organization OrganizationState) Change {
+	return Change{
+		Action:       ActionCreate,
+		Kind:         KindOrganization,
+		Organization: organization.Name,
+		Target:       organization.Name,
+		apply: func(ids *resolvedIDs) error {
+			created, err := reconciler.Organizations.Create(organizations.Organization{
+				Name:        organization.Name,
+				Description: organization.Description,
+			})
+			if err != nil {
+				return err
+			}
+
+			ids.organizations[organization.Name] = created.ID
+			return nil
+		},
+	}
+}
+
+func updateOrganization(reconciler *Reconciler, id string, organization OrganizationState, details []string) Change {
+	return Change{
+		Action:       ActionUpdate,
+		Kind:         KindOrganization,
+		Organization: organization.Name,
+		Target:       organization.Name,
+		ID:           id,
+		Details:      details,
+		apply: func(ids *resolvedIDs) error {
+			_, err := reconciler.Organizations.Update(organizations.Organization{
+				ID:          id,
+				Name:        organization.Name,
+				Description: organization.Description,
+			})
+			return err
+		},
+	}
+}
+
+func deleteOrganization(reconciler *Reconciler, organization organizations.Organization) Change {
+	return Change{
+		Action:       ActionDelete,
+		Kind:         KindOrganization,
+		Organization: organization.Name,
+		Target:       organization.Name,
+		ID:           organization.ID,
+		apply: func(ids *resolvedIDs) error {
+			return reconciler.Organizations.Delete(organization.ID)
+		},
+	}
+}
+
+func addAdminUser(reconciler *Reconciler, organization, email string) Change {
+	return Change{
+		Action:       ActionAdd,
+		Kind:         KindAdminUser,
+		Organization: organization,
+		Target:       email,
+		apply: func(ids *resolvedIDs) error {
+			organizationID, err := ids.organization(organization)
+			if err != nil {
+				return err
+			}
+
+			_, err = reconciler.AdminUsers.AddAdminUser(organizationID, email)
+			return err
+		},
+	}
+}
+
+func removeAdminUser(reconciler *Reconciler, organization string, adminUser organizations.AdminUser) Change {
+	return Change{
+		Action:       ActionRemove,
+		Kind:         KindAdminUser,
+		Organization: organization,
+		Target:       adminUser.User.Email,
+		ID:           adminUser.ID,
+		apply: func(ids *resolvedIDs) error {
+			organizationID, err := ids.organization(organization)
+			if err != nil {
+				return err
+			}
+
+			return reconciler.AdminUsers.RemoveAdminUser(organizationID, adminUser.ID)
+		},
+	}
+}
+
+func createApp(reconciler *Reconciler, organization string, app AppState) Change {
+	return Change{
+		Action:       ActionCreate,
+		Kind:         KindApp,
+		Organization: organization,
+		Target:       app.Name,
+		apply: func(ids *resolvedIDs) error {
+			organizationID, err := ids.organization(organization)
+			if err != nil {
+				return err
+			}
+
+			created, err := reconciler.Apps.Create(apps.App{
+				OrganizationID: organizationID,
+				Name:           app.Name,
+				Description:    app.Description,
+			})
+			if err != nil {
+				return err
+			}
+
+			ids.apps[appKey(organization, app.Name)] = created.ID
+			return nil
+		},
+	}
+}
+
+func updateApp(reconciler *Reconciler, organization, id string, app AppState, details []string) Change {
+	return Change{
+		Action:       ActionUpdate,
+		Kind:         KindApp,
+		Organization: organization,
+		Target:       app.Name,
+		ID:           id,
+		Details:      details,
+		apply: func(ids *resolvedIDs) error {
+			organizationID, err := ids.organization(organization)
+			if err != nil {
+				return err
+			}
+
+			_, err = reconciler.Apps.Update(apps.App{
+				ID:             id,
+				OrganizationID: organizationID,
+				Name:           app.Name,
+				Description:    app.Description,
+			})
+			return err
+		},
+	}
+}
+
+func deleteApp(reconciler *Reconciler, organization string, app apps.App) Change {
+	return Change{
+		Action:       ActionDelete,
+		Kind:         KindApp,
+		Organization: organization,
+		Target:       app.Name,
+		ID:           app.ID,
+		apply: func(ids *resolvedIDs) error {
+			organizationID, err := ids.organization(organization)
+			if err != nil {
+				return err
+			}
+
+			return reconciler.Apps.Delete(organizationID, app.ID)
+		},
+	}
+}
+
+func addAppUser(reconciler *Reconciler, organization, app string, user UserState) Change {
+	return Change{
+		Action:       ActionAdd,
+		Kind:         KindAppUser,
+		Organization: organization,
+		App:          app,
+		Target:       user.Email,
+		apply: func(ids *resolvedIDs) error {
+			organizationID, appID, err := appIDs(ids, organization, app)
+			if err != nil {
+				return err
+			}
+
+			_, err = reconciler.Apps.AddUser(organizationID, appID, apps.User{Name: user.Name, Email: user.Email})
+			return err
+		},
+	}
+}
+
+func removeAppUser(reconciler *Reconciler, organization, app string, appUser apps.AppUser) Change {
+	return Change{
+		Action:       ActionRemove,
+		Kind:         KindAppUser,
+		Organization: organization,
+		App:          app,
+		Target:       appUser.User.Email,
+		ID:           appUser.ID,
+		apply: func(ids *resolvedIDs) error {
+			organizationID, appID, err := appIDs(ids, organization, app)
+			if err != nil {
+				return err
+			}
+
+			return reconciler.Apps.RemoveUser(organizationID, appID, appUser.ID)
+		},
+	}
+}
+
+func appIDs(ids *resolvedIDs, organization, app string) (string, string, error) {
+	organizationID, err := ids.organization(organization)
+	if err != nil {
+		return "", "", err
+	}
+
+	appID, err := ids.app(organization, app)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot change users: %w", err)
+	}
+
+	return organizationID, appID, nil
+}
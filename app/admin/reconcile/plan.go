@@ -0,0 +1,152 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionAdd    = "add"
+	ActionRemove = "remove"
+
+	KindOrganization = "organization"
+	KindAdminUser    = "admin_user"
+	KindApp          = "app"
+	KindAppUser      = "app_user"
+)
+
+// Change is one step of a plan. Organization and App name what it belongs
+// to, Target is the name or email it acts on and ID the live object it
+// changes, empty for creates and adds.
+type Change struct {
+	Action       string   `json:"action"`
+	Kind         string   `json:"kind"`
+	Organization string   `json:"organization"`
+	App          string   `json:"app,omitempty"`
+	Target       string   `json:"target"`
+	ID           string   `json:"id,omitempty"`
+	Details      []string `json:"details,omitempty"`
+
+	apply func(ids *resolvedIDs) error
+}
+
+func (change Change) String() string {
+	symbol := map[string]string{
+		ActionCreate: "+",
+		ActionAdd:    "+",
+		ActionUpdate: "~",
+		ActionDelete: "-",
+		ActionRemove: "-",
+	}[change.Action]
+
+	text := fmt.Sprintf("%s %s %s %q", symbol, change.Action, strings.ReplaceAll(change.Kind, "_", " "), change.Target)
+	switch {
+	case change.Kind == KindAppUser:
+		text += fmt.Sprintf(" in app %q of organization %q", change.App, change.Organization)
+	case change.Kind != KindOrganization:
+		text += fmt.Sprintf(" in organization %q", change.Organization)
+	}
+
+	if len(change.Details) > 0 {
+		text += " (" + strings.Join(change.Details, ", ") + ")"
+	}
+
+	return text
+}
+
+// Plan is built by Reconciler.Plan and only applies with the reconciler
+// that built it.
+type Plan struct {
+	Changes []Change `json:"changes"`
+
+	ids resolvedIDs
+}
+
+func (plan *Plan) Empty() bool {
+	return len(plan.Changes) == 0
+}
+
+func (plan *Plan) WriteText(w io.Writer) error {
+	if plan.Empty() {
+		_, err := fmt.Fprintln(w, "No changes. Live state matches the desired state.")
+		return err
+	}
+
+	for _, change := range plan.Changes {
+		if _, err := fmt.Fprintln(w, change); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\nPlan: %s.\n", plan.summary())
+	return err
+}
+
+func (plan *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(plan)
+}
+
+func (plan *Plan) summary() string {
+	counts := make(map[string]int)
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+	}
+
+	var parts []string
+	for _, action := range []string{ActionCreate, ActionUpdate, ActionAdd, ActionRemove, ActionDelete} {
+		if counts[action] > 0 {
+			parts = append(parts, fmt.Sprintf("%d to %s", counts[action], action))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Report is the outcome of Apply. When a change fails, Failed holds it, Error
+// says why and Pending lists the changes that were not attempted.
+type Report struct {
+	Applied []Change `json:"applied"`
+	Failed  *Change  `json:"failed,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Pending []Change `json:"pending,omitempty"`
+}
+
+func (report *Report) WriteText(w io.Writer) error {
+	for _, change := range report.Applied {
+		if _, err := fmt.Fprintf(w, "done    %s\n", change); err != nil {
+			return err
+		}
+	}
+
+	if report.Failed == nil {
+		_, err := fmt.Fprintf(w, "\nApplied %d changes.\n", len(report.Applied))
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "failed  %s: %s\n", *report.Failed, report.Error); err != nil {
+		return err
+	}
+	for _, change := range report.Pending {
+		if _, err := fmt.Fprintf(w, "skipped %s\n", change); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\nStopped after %d of %d changes.\n", len(report.Applied), len(report.Applied)+1+len(report.Pending))
+	return err
+}
+
+func (report *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
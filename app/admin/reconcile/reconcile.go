@@ -0,0 +1,407 @@
+// Package reconcile brings organizations, their admins, apps and app users in
+// line with a desired state. Plan compares the desired state with the live
+// one without changing anything; Apply runs a plan in order and stops at the
+// first failure.
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/lib/paging"
+)
+
+const DefaultPerPage = 100
+
+type Options struct {
+	// PruneOrganizations deletes live organizations missing from the desired
+	// state. Without it only listed organizations are managed; their admins,
+	// apps and app users are always reconciled in full.
+	PruneOrganizations bool
+	PerPage            int
+}
+
+type Reconciler struct {
+	Organizations organizations.OrganizationDao
+	AdminUsers    organizations.AdminUserDao
+	Apps          apps.AppDao
+	Options       Options
+}
+
+func New(organizationDao organizations.OrganizationDao, adminUserDao organizations.AdminUserDao, appDao apps.AppDao, options Options) *Reconciler {
+	if options.PerPage <= 0 {
+		options.PerPage = DefaultPerPage
+	}
+
+	return &Reconciler{
+		Organizations: organizationDao,
+		AdminUsers:    adminUserDao,
+		Apps:          appDao,
+		Options:       options,
+	}
+}
+
+// resolvedIDs maps names to IDs, so changes on objects created earlier in
+// the same run can find them.
+type resolvedIDs struct {
+	organizations map[string]string
+	apps          map[string]string
+}
+
+func newResolvedIDs() resolvedIDs {
+	return resolvedIDs{organizations: make(map[string]string), apps: make(map[string]string)}
+}
+
+func (ids *resolvedIDs) organization(name string) (string, error) {
+	id, ok := ids.organizations[name]
+	if !ok {
+		return "", fmt.Errorf("organization %q has no ID", name)
+	}
+
+	return id, nil
+}
+
+func (ids *resolvedIDs) app(organization, name string) (string, error) {
+	id, ok := ids.apps[appKey(organization, name)]
+	if !ok {
+		return "", fmt.Errorf("app %q of organization %q has no ID", name, organization)
+	}
+
+	return id, nil
+}
+
+func appKey(organization, app string) string {
+	return organization + "\x00" + app
+}
+
+// Plan reads the live state and returns the changes that would make it match
+// desired. It does not change anything.
+func (reconciler *Reconciler) Plan(desired State) (*Plan, error) {
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+
+	liveOrganizations, err := reconciler.listOrganizations()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{ids: newResolvedIDs()}
+	matched := make(map[string]bool)
+
+	for _, organization := range desired.Organizations {
+		live, err := matchOrganization(liveOrganizations, organization)
+		if err != nil {
+			return nil, err
+		}
+
+		if live == nil {
+			plan.Changes = append(plan.Changes, createOrganization(reconciler, organization))
+			plan.Changes = append(plan.Changes, reconciler.planNewOrganization(organization)...)
+			continue
+		}
+
+		matched[live.ID] = true
+		plan.ids.organizations[organization.Name] = live.ID
+		changes, err := reconciler.planOrganization(plan, organization, *live)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+
+	if reconciler.Options.PruneOrganizations {
+		for _, live := range liveOrganizations {
+			if !matched[live.ID] {
+				plan.Changes = append(plan.Changes, deleteOrganization(reconciler, live))
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// Apply runs the changes of plan in order and stops at the first failure.
+// The returned error is the failure, also described in the report.
+func (reconciler *Reconciler) Apply(plan *Plan) (*Report, error) {
+	ids := newResolvedIDs()
+	for name, id := range plan.ids.organizations {
+		ids.organizations[name] = id
+	}
+	for key, id := range plan.ids.apps {
+		ids.apps[key] = id
+	}
+
+	report := &Report{}
+	for i, change := range plan.Changes {
+		if change.apply == nil {
+			return report, fmt.Errorf("change %q was not planned by a Reconciler", change.String())
+		}
+
+		if err := change.apply(&ids); err != nil {
+			failed := change
+			report.Failed = &failed
+			report.Error = err.Error()
+			report.Pending = plan.Changes[i+1:]
+
+			return report, fmt.Errorf("error applying %q: %w", change.String(), err)
+		}
+
+		report.Applied = append(report.Applied, change)
+	}
+
+	return report, nil
+}
+
+func matchOrganization(live []organizations.Organization, desired OrganizationState) (*organizations.Organization, error) {
+	var found *organizations.Organization
+
+	for i := range live {
+		if desired.ID != "" {
+			if live[i].ID == desired.ID {
+				return &live[i], nil
+			}
+			continue
+		}
+
+		if live[i].Name == desired.Name {
+			if found != nil {
+				return nil, fmt.Errorf("%w: several organizations are named %q, give its id", ErrInvalidState, desired.Name)
+			}
+			found = &live[i]
+		}
+	}
+
+	if desired.ID != "" {
+		return nil, fmt.Errorf("%w: organization id %q does not exist", ErrInvalidState, desired.ID)
+	}
+
+	return found, nil
+}
+
+func matchApp(live []apps.App, organization string, desired AppState) (*apps.App, error) {
+	var found *apps.App
+
+	for i := range live {
+		if desired.ID != "" {
+			if live[i].ID == desired.ID {
+				return &live[i], nil
+			}
+			continue
+		}
+
+		if live[i].Name == desired.Name {
+			if found != nil {
+				return nil, fmt.Errorf("%w: several apps in organization %q are named %q, give its id", ErrInvalidState, organization, desired.Name)
+			}
+			found = &live[i]
+		}
+	}
+
+	if desired.ID != "" {
+		return nil, fmt.Errorf("%w: app id %q does not exist in organization %q", ErrInvalidState, desired.ID, organization)
+	}
+
+	return found, nil
+}
+
+func (reconciler *Reconciler) planNewOrganization(organization OrganizationState) []Change {
+	var changes []Change
+
+	for _, email := range organization.Admins {
+		changes = append(changes, addAdminUser(reconciler, organization.Name, email))
+	}
+
+	for _, app := range organization.Apps {
+		changes = append(changes, createApp(reconciler, organization.Name, app))
+		for _, user := range app.Users {
+			changes = append(changes, addAppUser(reconciler, organization.Name, app.Name, user))
+		}
+	}
+
+	return changes
+}
+
+func (reconciler *Reconciler) planOrganization(plan *Plan, organization OrganizationState, live organizations.Organization) ([]Change, error) {
+	var changes []Change
+
+	if details := diff("name", live.Name, organization.Name, "description", live.Description, organization.Description); len(details) > 0 {
+		changes = append(changes, updateOrganization(reconciler, live.ID, organization, details))
+	}
+
+	adminUsers, err := reconciler.listAdminUsers(live.ID)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, planAdminUsers(reconciler, organization, adminUsers)...)
+
+	liveApps, err := reconciler.listApps(live.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool)
+	for _, app := range organization.Apps {
+		liveApp, err := matchApp(liveApps, organization.Name, app)
+		if err != nil {
+			return nil, err
+		}
+
+		if liveApp == nil {
+			changes = append(changes, createApp(reconciler, organization.Name, app))
+			for _, user := range app.Users {
+				changes = append(changes, addAppUser(reconciler, organization.Name, app.Name, user))
+			}
+			continue
+		}
+
+		matched[liveApp.ID] = true
+		plan.ids.apps[appKey(organization.Name, app.Name)] = liveApp.ID
+		if details := diff("name", liveApp.Name, app.Name, "description", liveApp.Description, app.Description); len(details) > 0 {
+			changes = append(changes, updateApp(reconciler, organization.Name, liveApp.ID, app, details))
+		}
+
+		appUsers, err := reconciler.listAppUsers(live.ID, liveApp.ID)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, planAppUsers(reconciler, organization.Name, app, appUsers)...)
+	}
+
+	for _, liveApp := range liveApps {
+		if !matched[liveApp.ID] {
+			changes = append(changes, deleteApp(reconciler, organization.Name, liveApp))
+		}
+	}
+
+	return changes, nil
+}
+
+// planAdminUsers adds missing admins before removing extra ones, so an
+// organization is never left without admins halfway through.
+func planAdminUsers(reconciler *Reconciler, organization OrganizationState, live []organizations.AdminUser) []Change {
+	var changes []Change
+
+	liveEmails := make(map[string]bool)
+	for _, adminUser := range live {
+		liveEmails[normalizeEmail(adminUser.User.Email)] = true
+	}
+
+	desiredEmails := make(map[string]bool)
+	for _, email := range organization.Admins {
+		desiredEmails[normalizeEmail(email)] = true
+		if !liveEmails[normalizeEmail(email)] {
+			changes = append(changes, addAdminUser(reconciler, organization.Name, email))
+		}
+	}
+
+	for _, adminUser := range live {
+		if !desiredEmails[normalizeEmail(adminUser.User.Email)] {
+			changes = append(changes, removeAdminUser(reconciler, organization.Name, adminUser))
+		}
+	}
+
+	return changes
+}
+
+func planAppUsers(reconciler *Reconciler, organization string, app AppState, live []apps.AppUser) []Change {
+	var changes []Change
+
+	liveEmails := make(map[string]bool)
+	for _, appUser := range live {
+		liveEmails[normalizeEmail(appUser.User.Email)] = true
+	}
+
+	desiredEmails := make(map[string]bool)
+	for _, user := range app.Users {
+		desiredEmails[normalizeEmail(user.Email)] = true
+		if !liveEmails[normalizeEmail(user.Email)] {
+			changes = append(changes, addAppUser(reconciler, organization, app.Name, user))
+		}
+	}
+
+	for _, appUser := range live {
+		if !desiredEmails[normalizeEmail(appUser.User.Email)] {
+			changes = append(changes, removeAppUser(reconciler, organization, app.Name, appUser))
+		}
+	}
+
+	return changes
+}
+
+// diff takes field, live, desired triples and describes the fields that
+// differ.
+func diff(fields ...string) []string {
+	var details []string
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+1] != fields[i+2] {
+			details = append(details, fmt.Sprintf("%s: %q -> %q", fields[i], fields[i+1], fields[i+2]))
+		}
+	}
+
+	return details
+}
+
+func (reconciler *Reconciler) listOrganizations() ([]organizations.Organization, error) {
+	all, err := paging.All(reconciler.Options.PerPage, func(page, perPage int) ([]organizations.Organization, *entities.Pagination, error) {
+		list, err := reconciler.Organizations.List(page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Data, &list.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing organizations: %w", err)
+	}
+
+	return all, nil
+}
+
+func (reconciler *Reconciler) listAdminUsers(organizationID string) ([]organizations.AdminUser, error) {
+	all, err := paging.All(reconciler.Options.PerPage, func(page, perPage int) ([]organizations.AdminUser, *entities.Pagination, error) {
+		list, err := reconciler.AdminUsers.ListAdminUsers(organizationID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Data, &list.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing admin users of organization %s: %w", organizationID, err)
+	}
+
+	return all, nil
+}
+
+func (reconciler *Reconciler) listApps(organizationID string) ([]apps.App, error) {
+	all, err := paging.All(reconciler.Options.PerPage, func(page, perPage int) ([]apps.App, *entities.Pagination, error) {
+		list, err := reconciler.Apps.List(organizationID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Apps, &list.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing apps of organization %s: %w", organizationID, err)
+	}
+
+	return all, nil
+}
+
+// listAppUsers passes no pagination, since the users endpoint does not
+// return it.
+func (reconciler *Reconciler) listAppUsers(organizationID, appID string) ([]apps.AppUser, error) {
+	all, err := paging.All(reconciler.Options.PerPage, func(page, perPage int) ([]apps.AppUser, *entities.Pagination, error) {
+		list, err := reconciler.Apps.Users(organizationID, appID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Users, nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing users of app %s: %w", appID, err)
+	}
+
+	return all, nil
+}
@@ -0,0 +1,314 @@
+package reconcile_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/app/admin/reconcile"
+)
+
+// fakeLetmein keeps organizations, admins, apps and app users in memory and
+// implements the three DAOs the reconciler uses.
+type fakeLetmein struct {
+	nextID        int
+	organizations []organizations.Organization
+	admins        map[string][]organizations.AdminUser
+	apps          map[string][]apps.App
+	appUsers      map[string][]apps.AppUser
+	failOn        string
+}
+
+func newFakeLetmein() *fakeLetmein {
+	return &fakeLetmein{
+		admins:   make(map[string][]organizations.AdminUser),
+		apps:     make(map[string][]apps.App),
+		appUsers: make(map[string][]apps.AppUser),
+	}
+}
+
+func (fake *fakeLetmein) id() string {
+	fake.nextID++
+	return fmt.Sprintf("id-%d", fake.nextID)
+}
+
+func (fake *fakeLetmein) List(page, perPage int) (*organizations.Organizations, error) {
+	return &organizations.Organizations{Data: append([]organizations.Organization(nil), fake.organizations...)}, nil
+}
+
+func (fake *fakeLetmein) Find(id string) (*organizations.Organization, error) {
+	return nil, errors.New("not used")
+}
+
+func (fake *fakeLetmein) Create(organization organizations.Organization) (*organizations.Organization, error) {
+	organization.ID = fake.id()
+	fake.organizations = append(fake.organizations, organization)
+	return &organization, nil
+}
+
+func (fake *fakeLetmein) Update(organization organizations.Organization) (*organizations.Organization, error) {
+	for i := range fake.organizations {
+		if fake.organizations[i].ID == organization.ID {
+			fake.organizations[i] = organization
+		}
+	}
+	return &organization, nil
+}
+
+func (fake *fakeLetmein) Delete(id string) error {
+	for i := range fake.organizations {
+		if fake.organizations[i].ID == id {
+			fake.organizations = append(fake.organizations[:i], fake.organizations[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (fake *fakeLetmein) ListAdminUsers(orgID string, page, perPage int) (*organizations.AdminUsers, error) {
+	return &organizations.AdminUsers{Data: append([]organizations.AdminUser(nil), fake.admins[orgID]...)}, nil
+}
+
+func (fake *fakeLetmein) AddAdminUser(orgID, email string) (*organizations.AdminUserData, error) {
+	if email == fake.failOn {
+		return nil, errors.New("user does not exist")
+	}
+
+	adminUser := organizations.AdminUser{ID: fake.id(), User: organizations.User{Email: email}}
+	fake.admins[orgID] = append(fake.admins[orgID], adminUser)
+	return &organizations.AdminUserData{Data: adminUser}, nil
+}
+
+func (fake *fakeLetmein) RemoveAdminUser(orgID, adminUserID string) error {
+	list := fake.admins[orgID]
+	for i := range list {
+		if list[i].ID == adminUserID {
+			fake.admins[orgID] = append(list[:i], list[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+type appDao struct {
+	apps.AppDao
+	fake *fakeLetmein
+}
+
+func (dao appDao) Create(app apps.App) (apps.App, error) {
+	app.ID = dao.fake.id()
+	dao.fake.apps[app.OrganizationID] = append(dao.fake.apps[app.OrganizationID], app)
+	return app, nil
+}
+
+func (dao appDao) List(orgID string, page, perPage int) (apps.Apps, error) {
+	return apps.Apps{Apps: append([]apps.App(nil), dao.fake.apps[orgID]...)}, nil
+}
+
+func (dao appDao) Update(app apps.App) (apps.App, error) {
+	list := dao.fake.apps[app.OrganizationID]
+	for i := range list {
+		if list[i].ID == app.ID {
+			list[i] = app
+		}
+	}
+	return app, nil
+}
+
+func (dao appDao) Delete(orgID, id string) error {
+	list := dao.fake.apps[orgID]
+	for i := range list {
+		if list[i].ID == id {
+			dao.fake.apps[orgID] = append(list[:i], list[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (dao appDao) Users(orgID, appID string, page, perPage int) (apps.AppUsers, error) {
+	if page > 1 {
+		return apps.AppUsers{}, nil
+	}
+	return apps.AppUsers{Users: append([]apps.AppUser(nil), dao.fake.appUsers[appID]...)}, nil
+}
+
+func (dao appDao) AddUser(orgID, appID string, user apps.User) (apps.AppUser, error) {
+	appUser := apps.AppUser{ID: dao.fake.id(), User: user}
+	dao.fake.appUsers[appID] = append(dao.fake.appUsers[appID], appUser)
+	return appUser, nil
+}
+
+func (dao appDao) RemoveUser(orgID, appID, appUserID string) error {
+	list := dao.fake.appUsers[appID]
+	for i := range list {
+		if list[i].ID == appUserID {
+			dao.fake.appUsers[appID] = append(list[:i], list[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+const desiredJSON = `{
+	"organizations": [
+		{
+			"name": "Acme",
+			"description": "Acme Inc.",
+			"admins": ["alice@acme.com", "Bob@acme.com"],
+			"apps": [
+				{"name": "Store", "description": "Web store", "users": [{"email": "carol@acme.com", "name": "Carol"}]}
+			]
+		},
+		{
+			"name": "Globex",
+			"description": "Globex Corporation",
+			"admins": ["dave@globex.com"],
+			"apps": [{"name": "CRM", "users": [{"email": "erin@globex.com"}]}]
+		}
+	]
+}`
+
+func newLiveState() (*fakeLetmein, *reconcile.Reconciler) {
+	fake := newFakeLetmein()
+	acme, _ := fake.Create(organizations.Organization{Name: "Acme", Description: "Old description"})
+	fake.AddAdminUser(acme.ID, "bob@acme.com")
+	fake.AddAdminUser(acme.ID, "mallory@acme.com")
+	store, _ := appDao{fake: fake}.Create(apps.App{OrganizationID: acme.ID, Name: "Store", Description: "Web store"})
+	appDao{fake: fake}.AddUser(acme.ID, store.ID, apps.User{Email: "trent@acme.com"})
+	appDao{fake: fake}.Create(apps.App{OrganizationID: acme.ID, Name: "Legacy"})
+	fake.Create(organizations.Organization{Name: "Initech"})
+
+	return fake, reconcile.New(fake, fake, appDao{fake: fake}, reconcile.Options{})
+}
+
+func TestPlanListsEveryDifference(t *testing.T) {
+	_, reconciler := newLiveState()
+
+	state, err := reconcile.ReadState(strings.NewReader(desiredJSON))
+	if err != nil {
+		t.Fatalf("ReadState expected no errors, got %s", err)
+	}
+
+	plan, err := reconciler.Plan(state)
+	if err != nil {
+		t.Fatalf("Plan expected no errors, got %s", err)
+	}
+
+	var out bytes.Buffer
+	plan.WriteText(&out)
+	text := out.String()
+
+	expected := []string{
+		`~ update organization "Acme" (description: "Old description" -> "Acme Inc.")`,
+		`+ add admin user "alice@acme.com" in organization "Acme"`,
+		`- remove admin user "mallory@acme.com" in organization "Acme"`,
+		`+ add app user "carol@acme.com" in app "Store" of organization "Acme"`,
+		`- remove app user "trent@acme.com" in app "Store" of organization "Acme"`,
+		`- delete app "Legacy" in organization "Acme"`,
+		`+ create organization "Globex"`,
+		`+ add admin user "dave@globex.com" in organization "Globex"`,
+		`+ create app "CRM" in organization "Globex"`,
+		`+ add app user "erin@globex.com" in app "CRM" of organization "Globex"`,
+	}
+	for _, line := range expected {
+		if strings.Contains(text, line) {
+			t.Logf("plan contains %s", line)
+		} else {
+			t.Errorf("plan expected to contain %s, got:\n%s", line, text)
+		}
+	}
+
+	if len(plan.Changes) == len(expected) && !strings.Contains(text, "Bob") && !strings.Contains(text, "Initech") {
+		t.Log("matching admins and unlisted organizations are left alone")
+	} else {
+		t.Errorf("plan expected %d changes, got:\n%s", len(expected), text)
+	}
+}
+
+func TestApplyReachesDesiredState(t *testing.T) {
+	fake, reconciler := newLiveState()
+	state, _ := reconcile.ReadState(strings.NewReader(desiredJSON))
+
+	plan, _ := reconciler.Plan(state)
+	report, err := reconciler.Apply(plan)
+	if err != nil || len(report.Applied) != len(plan.Changes) {
+		t.Fatalf("Apply expected every change applied, got %v and %+v", err, report)
+	}
+
+	replan, err := reconciler.Plan(state)
+	if err == nil && replan.Empty() {
+		t.Log("after Apply the plan is empty")
+	} else {
+		t.Errorf("after Apply expected an empty plan, got %v and %+v", err, replan.Changes)
+	}
+
+	if len(fake.organizations) == 3 {
+		t.Log("unlisted organizations are kept without PruneOrganizations")
+	} else {
+		t.Errorf("expected 3 organizations, got %d", len(fake.organizations))
+	}
+}
+
+func TestApplyStopsOnFirstFailure(t *testing.T) {
+	fake, reconciler := newLiveState()
+	fake.failOn = "alice@acme.com"
+	state, _ := reconcile.ReadState(strings.NewReader(desiredJSON))
+
+	plan, _ := reconciler.Plan(state)
+	report, err := reconciler.Apply(plan)
+
+	if err != nil && report.Failed != nil && report.Failed.Target == "alice@acme.com" && strings.Contains(report.Error, "user does not exist") {
+		t.Log("Apply reports the failed change and why")
+	} else {
+		t.Fatalf("Apply expected to fail on alice@acme.com, got %v and %+v", err, report)
+	}
+
+	if len(report.Applied) == 1 && len(report.Pending) == len(plan.Changes)-2 {
+		t.Log("changes after the failure are not attempted")
+	} else {
+		t.Errorf("expected 1 applied and %d pending, got %d and %d", len(plan.Changes)-2, len(report.Applied), len(report.Pending))
+	}
+
+	if len(fake.admins[fake.organizations[0].ID]) == 2 {
+		t.Log("admins after the failure are left untouched")
+	} else {
+		t.Errorf("expected the live admins unchanged, got %+v", fake.admins[fake.organizations[0].ID])
+	}
+}
+
+func TestPruneOrganizations(t *testing.T) {
+	fake, _ := newLiveState()
+	reconciler := reconcile.New(fake, fake, appDao{fake: fake}, reconcile.Options{PruneOrganizations: true})
+	state, _ := reconcile.ReadState(strings.NewReader(desiredJSON))
+
+	plan, _ := reconciler.Plan(state)
+	last := plan.Changes[len(plan.Changes)-1]
+	if last.Action == reconcile.ActionDelete && last.Target == "Initech" {
+		t.Log("PruneOrganizations deletes unlisted organizations last")
+	} else {
+		t.Errorf("expected the plan to end deleting Initech, got %s", last)
+	}
+}
+
+func TestReadStateRejectsDuplicates(t *testing.T) {
+	cases := map[string]string{
+		"duplicate organization": `{"organizations": [{"name": "Acme"}, {"name": "Acme"}]}`,
+		"duplicate admin":        `{"organizations": [{"name": "Acme", "admins": ["a@acme.com", "A@acme.com"]}]}`,
+		"app without name":       `{"organizations": [{"name": "Acme", "apps": [{"description": "x"}]}]}`,
+		"unknown field":          `{"organisations": []}`,
+	}
+
+	for name, content := range cases {
+		_, err := reconcile.ReadState(strings.NewReader(content))
+		if errors.Is(err, reconcile.ErrInvalidState) {
+			t.Logf("%s is rejected", name)
+		} else {
+			t.Errorf("%s expected ErrInvalidState, got %v", name, err)
+		}
+	}
+}
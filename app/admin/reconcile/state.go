@@ -0,0 +1,111 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrInvalidState = errors.New("invalid desired state")
+
+// State is the desired access configuration. Organizations and apps are
+// matched to live ones by name, or by ID when one is given. Admins and app
+// users are matched by email, case insensitively.
+type State struct {
+	Organizations []OrganizationState `json:"organizations"`
+}
+
+type OrganizationState struct {
+	ID          string     `json:"id,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Admins      []string   `json:"admins"`
+	Apps        []AppState `json:"apps"`
+}
+
+type AppState struct {
+	ID          string      `json:"id,omitempty"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Users       []UserState `json:"users"`
+}
+
+type UserState struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+func ReadState(r io.Reader) (State, error) {
+	var state State
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&state); err != nil {
+		return state, fmt.Errorf("%w: %s", ErrInvalidState, err)
+	}
+
+	return state, state.Validate()
+}
+
+// Validate rejects states that cannot be reconciled unambiguously: missing
+// names or emails and duplicates.
+func (state State) Validate() error {
+	organizationNames := make(map[string]bool)
+
+	for _, organization := range state.Organizations {
+		if strings.TrimSpace(organization.Name) == "" {
+			return fmt.Errorf("%w: organization without name", ErrInvalidState)
+		}
+		if organizationNames[organization.Name] {
+			return fmt.Errorf("%w: organization %q listed twice", ErrInvalidState, organization.Name)
+		}
+		organizationNames[organization.Name] = true
+
+		if err := uniqueEmails(organization.Admins, "admin of organization "+organization.Name); err != nil {
+			return err
+		}
+
+		appNames := make(map[string]bool)
+		for _, app := range organization.Apps {
+			if strings.TrimSpace(app.Name) == "" {
+				return fmt.Errorf("%w: app without name in organization %q", ErrInvalidState, organization.Name)
+			}
+			if appNames[app.Name] {
+				return fmt.Errorf("%w: app %q listed twice in organization %q", ErrInvalidState, app.Name, organization.Name)
+			}
+			appNames[app.Name] = true
+
+			emails := make([]string, 0, len(app.Users))
+			for _, user := range app.Users {
+				emails = append(emails, user.Email)
+			}
+			if err := uniqueEmails(emails, "user of app "+app.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func uniqueEmails(emails []string, role string) error {
+	seen := make(map[string]bool)
+	for _, email := range emails {
+		key := normalizeEmail(email)
+		if key == "" {
+			return fmt.Errorf("%w: %s without email", ErrInvalidState, role)
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: %s %q listed twice", ErrInvalidState, role, email)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,72 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version is the document format written by Export. Import refuses other
+// versions rather than guess at their meaning.
+const Version = 1
+
+var ErrUnsupportedVersion = errors.New("unsupported export document version")
+
+// Document is a tenant's Letmein configuration. IDs are those of the source
+// instance; Import maps them to the IDs created on the target. App tokens are
+// secrets and are never exported.
+type Document struct {
+	Version       int            `json:"version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Source        string         `json:"source"`
+	Organizations []Organization `json:"organizations"`
+}
+
+type Organization struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	AdminUsers  []AdminUser `json:"admin_users"`
+	Apps        []App       `json:"apps"`
+}
+
+type AdminUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type App struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Users       []AppUser `json:"users"`
+}
+
+type AppUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func ReadDocument(r io.Reader) (*Document, error) {
+	var document Document
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("error parsing export document: %w", err)
+	}
+
+	if document.Version != Version {
+		return nil, fmt.Errorf("%w: %d, expected %d", ErrUnsupportedVersion, document.Version, Version)
+	}
+
+	return &document, nil
+}
+
+func (document *Document) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(document)
+}
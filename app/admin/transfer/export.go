@@ -0,0 +1,155 @@
+// Package transfer copies a tenant's organizations, admin users, apps and
+// app users from one Letmein instance to another. Export walks every list
+// endpoint into a versioned Document; Import recreates it, recording how
+// source IDs map to target IDs so an interrupted import can be resumed.
+package transfer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/lib/paging"
+)
+
+const DefaultPerPage = 100
+
+type Exporter struct {
+	Organizations organizations.OrganizationDao
+	AdminUsers    organizations.AdminUserDao
+	Apps          apps.AppDao
+
+	// OrganizationIDs limits the export to these organizations. When empty
+	// every organization is exported.
+	OrganizationIDs []string
+	// Source is recorded in the document, usually the source BaseURL.
+	Source  string
+	PerPage int
+}
+
+func (exporter *Exporter) Export() (*Document, error) {
+	document := &Document{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Source:     exporter.Source,
+	}
+
+	list, err := exporter.organizations()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, organization := range list {
+		exported, err := exporter.exportOrganization(organization)
+		if err != nil {
+			return nil, err
+		}
+		document.Organizations = append(document.Organizations, exported)
+	}
+
+	return document, nil
+}
+
+func (exporter *Exporter) organizations() ([]organizations.Organization, error) {
+	if len(exporter.OrganizationIDs) > 0 {
+		var list []organizations.Organization
+		for _, id := range exporter.OrganizationIDs {
+			organization, err := exporter.Organizations.Find(id)
+			if err != nil {
+				return nil, fmt.Errorf("error finding organization %s: %w", id, err)
+			}
+			list = append(list, *organization)
+		}
+
+		return list, nil
+	}
+
+	list, err := paging.All(exporter.perPage(), func(page, perPage int) ([]organizations.Organization, *entities.Pagination, error) {
+		organizationList, err := exporter.Organizations.List(page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return organizationList.Data, &organizationList.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing organizations: %w", err)
+	}
+
+	return list, nil
+}
+
+func (exporter *Exporter) exportOrganization(organization organizations.Organization) (Organization, error) {
+	exported := Organization{
+		ID:          organization.ID,
+		Name:        organization.Name,
+		Description: organization.Description,
+		AdminUsers:  []AdminUser{},
+		Apps:        []App{},
+	}
+
+	adminUsers, err := paging.All(exporter.perPage(), func(page, perPage int) ([]organizations.AdminUser, *entities.Pagination, error) {
+		list, err := exporter.AdminUsers.ListAdminUsers(organization.ID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Data, &list.Pagination, nil
+	})
+	if err != nil {
+		return exported, fmt.Errorf("error listing admin users of organization %s: %w", organization.ID, err)
+	}
+	for _, adminUser := range adminUsers {
+		exported.AdminUsers = append(exported.AdminUsers, AdminUser{ID: adminUser.ID, Name: adminUser.User.Name, Email: adminUser.User.Email})
+	}
+
+	appList, err := paging.All(exporter.perPage(), func(page, perPage int) ([]apps.App, *entities.Pagination, error) {
+		list, err := exporter.Apps.List(organization.ID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Apps, &list.Pagination, nil
+	})
+	if err != nil {
+		return exported, fmt.Errorf("error listing apps of organization %s: %w", organization.ID, err)
+	}
+	for _, app := range appList {
+		exportedApp, err := exporter.exportApp(organization.ID, app)
+		if err != nil {
+			return exported, err
+		}
+		exported.Apps = append(exported.Apps, exportedApp)
+	}
+
+	return exported, nil
+}
+
+// exportApp passes no pagination, since the users endpoint does not return
+// it.
+func (exporter *Exporter) exportApp(organizationID string, app apps.App) (App, error) {
+	exported := App{ID: app.ID, Name: app.Name, Description: app.Description, Users: []AppUser{}}
+
+	appUsers, err := paging.All(exporter.perPage(), func(page, perPage int) ([]apps.AppUser, *entities.Pagination, error) {
+		list, err := exporter.Apps.Users(organizationID, app.ID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return list.Users, nil, nil
+	})
+	if err != nil {
+		return exported, fmt.Errorf("error listing users of app %s: %w", app.ID, err)
+	}
+	for _, appUser := range appUsers {
+		exported.Users = append(exported.Users, AppUser{ID: appUser.ID, Name: appUser.User.Name, Email: appUser.User.Email})
+	}
+
+	return exported, nil
+}
+
+func (exporter *Exporter) perPage() int {
+	if exporter.PerPage <= 0 {
+		return DefaultPerPage
+	}
+
+	return exporter.PerPage
+}
@@ -0,0 +1,358 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/lib/paging"
+)
+
+// IDMap records, per kind, which target ID each source ID was recreated as.
+// Import saves it after every created object, so running Import again with
+// the same map skips what already exists and continues where it stopped.
+// Pending names the object being created when the map was last saved; its
+// create may have reached the target even though its ID was never recorded.
+type IDMap struct {
+	Source        string            `json:"source"`
+	Target        string            `json:"target"`
+	Organizations map[string]string `json:"organizations"`
+	AdminUsers    map[string]string `json:"admin_users"`
+	Apps          map[string]string `json:"apps"`
+	AppUsers      map[string]string `json:"app_users"`
+	Pending       string            `json:"pending,omitempty"`
+
+	path string
+}
+
+func NewIDMap() *IDMap {
+	return &IDMap{
+		Organizations: make(map[string]string),
+		AdminUsers:    make(map[string]string),
+		Apps:          make(map[string]string),
+		AppUsers:      make(map[string]string),
+	}
+}
+
+// LoadIDMap reads the map saved at path by an earlier import, or returns an
+// empty one that will be saved there.
+func LoadIDMap(path string) (*IDMap, error) {
+	idMap := NewIDMap()
+	idMap.path = path
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idMap, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading import state %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, idMap); err != nil {
+		return nil, fmt.Errorf("error parsing import state %s: %w", path, err)
+	}
+	for _, ids := range []*map[string]string{&idMap.Organizations, &idMap.AdminUsers, &idMap.Apps, &idMap.AppUsers} {
+		if *ids == nil {
+			*ids = make(map[string]string)
+		}
+	}
+
+	return idMap, nil
+}
+
+// save writes the map through a temporary file renamed into place, so an
+// interrupted save never leaves a truncated state behind. A map without a
+// path is kept in memory only.
+func (idMap *IDMap) save() error {
+	if idMap.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(idMap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(idMap.path), filepath.Base(idMap.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error saving import state: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(append(content, '\n')); err != nil {
+		temp.Close()
+		return fmt.Errorf("error saving import state: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error saving import state: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), idMap.path); err != nil {
+		return fmt.Errorf("error saving import state: %w", err)
+	}
+
+	return nil
+}
+
+type Importer struct {
+	Organizations organizations.OrganizationDao
+	AdminUsers    organizations.AdminUserDao
+	Apps          apps.AppDao
+
+	// Target is recorded in the map, usually the target BaseURL, so a map
+	// is never resumed against another server.
+	Target  string
+	PerPage int
+}
+
+// Summary counts what one Import call created and what it found already
+// created by an earlier run.
+type Summary struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+}
+
+var (
+	ErrSourceMismatch = errors.New("import state belongs to another export")
+	ErrTargetMismatch = errors.New("import state belongs to another target")
+)
+
+// Import recreates document on the target and fills idMap as it goes. It
+// stops at the first failure; fix the cause and call Import again with the
+// same map to resume.
+//
+// When the previous run stopped while creating an object, resume first
+// looks for it on the target, by name for organizations and apps and by
+// email for users, and only creates it if it is not there.
+func (importer *Importer) Import(document *Document, idMap *IDMap) (Summary, error) {
+	var summary Summary
+
+	if document.Version != Version {
+		return summary, fmt.Errorf("%w: %d", ErrUnsupportedVersion, document.Version)
+	}
+
+	if idMap.Source == "" {
+		idMap.Source = document.Source
+	} else if idMap.Source != document.Source {
+		return summary, fmt.Errorf("%w: state is for %q, document is from %q", ErrSourceMismatch, idMap.Source, document.Source)
+	}
+
+	if idMap.Target == "" {
+		idMap.Target = importer.Target
+	} else if idMap.Target != importer.Target {
+		return summary, fmt.Errorf("%w: state is for %q, importing into %q", ErrTargetMismatch, idMap.Target, importer.Target)
+	}
+
+	for _, organization := range document.Organizations {
+		if err := importer.importOrganization(organization, idMap, &summary); err != nil {
+			return summary, err
+		}
+	}
+
+	return summary, nil
+}
+
+func (importer *Importer) importOrganization(organization Organization, idMap *IDMap, summary *Summary) error {
+	organizationID, err := step(idMap, "organization", idMap.Organizations, organization.ID, summary, func() (string, error) {
+		return importer.findOrganization(organization.Name)
+	}, func() (string, error) {
+		created, err := importer.Organizations.Create(organizations.Organization{Name: organization.Name, Description: organization.Description})
+		if err != nil {
+			return "", fmt.Errorf("error creating organization %q: %w", organization.Name, err)
+		}
+		return created.ID, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, adminUser := range organization.AdminUsers {
+		_, err := step(idMap, "admin_user", idMap.AdminUsers, adminUser.ID, summary, func() (string, error) {
+			return importer.findAdminUser(organizationID, adminUser.Email)
+		}, func() (string, error) {
+			added, err := importer.AdminUsers.AddAdminUser(organizationID, adminUser.Email)
+			if err != nil {
+				return "", fmt.Errorf("error adding admin user %s to organization %q: %w", adminUser.Email, organization.Name, err)
+			}
+			return added.Data.ID, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, app := range organization.Apps {
+		appID, err := step(idMap, "app", idMap.Apps, app.ID, summary, func() (string, error) {
+			return importer.findApp(organizationID, app.Name)
+		}, func() (string, error) {
+			created, err := importer.Apps.Create(apps.App{OrganizationID: organizationID, Name: app.Name, Description: app.Description})
+			if err != nil {
+				return "", fmt.Errorf("error creating app %q in organization %q: %w", app.Name, organization.Name, err)
+			}
+			return created.ID, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, appUser := range app.Users {
+			_, err := step(idMap, "app_user", idMap.AppUsers, appUser.ID, summary, func() (string, error) {
+				return importer.findAppUser(organizationID, appID, appUser.Email)
+			}, func() (string, error) {
+				added, err := importer.Apps.AddUser(organizationID, appID, apps.User{Name: appUser.Name, Email: appUser.Email})
+				if err != nil {
+					return "", fmt.Errorf("error adding user %s to app %q: %w", appUser.Email, app.Name, err)
+				}
+				return added.ID, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// step returns the target ID already mapped for sourceID. Otherwise it
+// marks sourceID as pending and saves the map, then runs create, records the
+// new ID and saves the map again. A step still pending from an earlier run
+// runs find first, and find's ID is recorded instead when it returns one.
+func step(idMap *IDMap, kind string, ids map[string]string, sourceID string, summary *Summary, find, create func() (string, error)) (string, error) {
+	if sourceID == "" {
+		return "", errors.New("document entry without id cannot be tracked for resume")
+	}
+
+	if targetID, ok := ids[sourceID]; ok {
+		summary.Skipped++
+		return targetID, nil
+	}
+
+	pending := kind + ":" + sourceID
+	if idMap.Pending == pending {
+		targetID, err := find()
+		if err != nil {
+			return "", err
+		}
+		if targetID != "" {
+			ids[sourceID] = targetID
+			idMap.Pending = ""
+			summary.Skipped++
+			return targetID, idMap.save()
+		}
+	}
+
+	idMap.Pending = pending
+	if err := idMap.save(); err != nil {
+		return "", err
+	}
+
+	targetID, err := create()
+	if err != nil {
+		return "", err
+	}
+
+	ids[sourceID] = targetID
+	idMap.Pending = ""
+	summary.Created++
+
+	return targetID, idMap.save()
+}
+
+func (importer *Importer) findOrganization(name string) (string, error) {
+	list, err := paging.All(importer.perPage(), func(page, perPage int) ([]organizations.Organization, *entities.Pagination, error) {
+		organizationList, err := importer.Organizations.List(page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return organizationList.Data, &organizationList.Pagination, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error looking for organization %q: %w", name, err)
+	}
+
+	for _, organization := range list {
+		if organization.Name == name {
+			return organization.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (importer *Importer) findAdminUser(organizationID, email string) (string, error) {
+	list, err := paging.All(importer.perPage(), func(page, perPage int) ([]organizations.AdminUser, *entities.Pagination, error) {
+		adminUsers, err := importer.AdminUsers.ListAdminUsers(organizationID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return adminUsers.Data, &adminUsers.Pagination, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error looking for admin user %s: %w", email, err)
+	}
+
+	for _, adminUser := range list {
+		if strings.EqualFold(adminUser.User.Email, email) {
+			return adminUser.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (importer *Importer) findApp(organizationID, name string) (string, error) {
+	list, err := paging.All(importer.perPage(), func(page, perPage int) ([]apps.App, *entities.Pagination, error) {
+		appList, err := importer.Apps.List(organizationID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return appList.Apps, &appList.Pagination, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error looking for app %q: %w", name, err)
+	}
+
+	for _, app := range list {
+		if app.Name == name {
+			return app.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (importer *Importer) findAppUser(organizationID, appID, email string) (string, error) {
+	list, err := paging.All(importer.perPage(), func(page, perPage int) ([]apps.AppUser, *entities.Pagination, error) {
+		appUsers, err := importer.Apps.Users(organizationID, appID, page, perPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		return appUsers.Users, nil, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error looking for user %s: %w", email, err)
+	}
+
+	for _, appUser := range list {
+		if strings.EqualFold(appUser.User.Email, email) {
+			return appUser.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (importer *Importer) perPage() int {
+	if importer.PerPage <= 0 {
+		return DefaultPerPage
+	}
+
+	return importer.PerPage
+}
@@ -0,0 +1,290 @@
+package transfer_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/app/admin/transfer"
+	"github.com/adilsonchacon/goeli/entities"
+)
+
+// fakeInstance is an in-memory Letmein instance. Lists return one item per
+// page so every pagination loop is exercised.
+type fakeInstance struct {
+	prefix        string
+	nextID        int
+	organizations []organizations.Organization
+	admins        map[string][]organizations.AdminUser
+	apps          map[string][]apps.App
+	appUsers      map[string][]apps.AppUser
+	failOnEmail   string
+	// timeoutOnEmail adds the admin user but still fails, like a create
+	// that commits before the response is lost.
+	timeoutOnEmail string
+}
+
+func newFakeInstance(prefix string) *fakeInstance {
+	return &fakeInstance{
+		prefix:   prefix,
+		admins:   make(map[string][]organizations.AdminUser),
+		apps:     make(map[string][]apps.App),
+		appUsers: make(map[string][]apps.AppUser),
+	}
+}
+
+func (fake *fakeInstance) id() string {
+	fake.nextID++
+	return fmt.Sprintf("%s-%d", fake.prefix, fake.nextID)
+}
+
+func pageOf[T any](items []T, page int) ([]T, entities.Pagination) {
+	pagination := entities.Pagination{Page: page}
+	if page < len(items) {
+		next := page + 1
+		pagination.Next = &next
+	}
+	if page > len(items) {
+		return nil, pagination
+	}
+
+	return items[page-1 : page], pagination
+}
+
+func (fake *fakeInstance) List(page, perPage int) (*organizations.Organizations, error) {
+	data, pagination := pageOf(fake.organizations, page)
+	return &organizations.Organizations{Data: data, Pagination: pagination}, nil
+}
+
+func (fake *fakeInstance) Find(id string) (*organizations.Organization, error) {
+	for _, organization := range fake.organizations {
+		if organization.ID == id {
+			return &organization, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (fake *fakeInstance) Create(organization organizations.Organization) (*organizations.Organization, error) {
+	organization.ID = fake.id()
+	fake.organizations = append(fake.organizations, organization)
+	return &organization, nil
+}
+
+func (fake *fakeInstance) Update(organization organizations.Organization) (*organizations.Organization, error) {
+	return nil, errors.New("not used")
+}
+
+func (fake *fakeInstance) Delete(id string) error {
+	return errors.New("not used")
+}
+
+func (fake *fakeInstance) ListAdminUsers(orgID string, page, perPage int) (*organizations.AdminUsers, error) {
+	data, pagination := pageOf(fake.admins[orgID], page)
+	return &organizations.AdminUsers{Data: data, Pagination: pagination}, nil
+}
+
+func (fake *fakeInstance) AddAdminUser(orgID, email string) (*organizations.AdminUserData, error) {
+	if email == fake.failOnEmail {
+		return nil, errors.New("user does not exist")
+	}
+
+	adminUser := organizations.AdminUser{ID: fake.id(), User: organizations.User{Email: email}}
+	fake.admins[orgID] = append(fake.admins[orgID], adminUser)
+	if email == fake.timeoutOnEmail {
+		return nil, errors.New("timeout awaiting response headers")
+	}
+	return &organizations.AdminUserData{Data: adminUser}, nil
+}
+
+func (fake *fakeInstance) RemoveAdminUser(orgID, adminUserID string) error {
+	return errors.New("not used")
+}
+
+type appDao struct {
+	apps.AppDao
+	fake *fakeInstance
+}
+
+func (dao appDao) Create(app apps.App) (apps.App, error) {
+	app.ID = dao.fake.id()
+	dao.fake.apps[app.OrganizationID] = append(dao.fake.apps[app.OrganizationID], app)
+	return app, nil
+}
+
+func (dao appDao) List(orgID string, page, perPage int) (apps.Apps, error) {
+	data, pagination := pageOf(dao.fake.apps[orgID], page)
+	return apps.Apps{Apps: data, Pagination: pagination}, nil
+}
+
+func (dao appDao) Users(orgID, appID string, page, perPage int) (apps.AppUsers, error) {
+	data, _ := pageOf(dao.fake.appUsers[appID], page)
+	return apps.AppUsers{Users: data}, nil
+}
+
+func (dao appDao) AddUser(orgID, appID string, user apps.User) (apps.AppUser, error) {
+	appUser := apps.AppUser{ID: dao.fake.id(), User: user}
+	dao.fake.appUsers[appID] = append(dao.fake.appUsers[appID], appUser)
+	return appUser, nil
+}
+
+func newSource() *fakeInstance {
+	source := newFakeInstance("src")
+	for _, name := range []string{"Acme", "Globex"} {
+		organization, _ := source.Create(organizations.Organization{Name: name, Description: name + " description"})
+		source.AddAdminUser(organization.ID, "admin@"+name+".com")
+		source.AddAdminUser(organization.ID, "owner@"+name+".com")
+		app, _ := appDao{fake: source}.Create(apps.App{OrganizationID: organization.ID, Name: name + " Store"})
+		appDao{fake: source}.AddUser(organization.ID, app.ID, apps.User{Name: "Carol", Email: "carol@" + name + ".com"})
+	}
+
+	return source
+}
+
+func exportFrom(t *testing.T, source *fakeInstance) *transfer.Document {
+	t.Helper()
+
+	exporter := &transfer.Exporter{Organizations: source, AdminUsers: source, Apps: appDao{fake: source}, Source: "https://staging.example.com", PerPage: 1}
+	document, err := exporter.Export()
+	if err != nil {
+		t.Fatalf("Export expected no errors, got %s", err)
+	}
+
+	// Round trip through JSON like a real transfer would.
+	var buffer bytes.Buffer
+	document.Write(&buffer)
+	read, err := transfer.ReadDocument(&buffer)
+	if err != nil {
+		t.Fatalf("ReadDocument expected no errors, got %s", err)
+	}
+
+	return read
+}
+
+func TestExportWalksEveryPage(t *testing.T) {
+	document := exportFrom(t, newSource())
+
+	if document.Version == transfer.Version && len(document.Organizations) == 2 {
+		t.Log("Export includes every organization page")
+	} else {
+		t.Fatalf("expected 2 organizations in version %d, got %+v", transfer.Version, document)
+	}
+
+	acme := document.Organizations[0]
+	if len(acme.AdminUsers) == 2 && len(acme.Apps) == 1 && len(acme.Apps[0].Users) == 1 && acme.Apps[0].Users[0].Name == "Carol" {
+		t.Log("Export includes admin users, apps and app users")
+	} else {
+		t.Errorf("unexpected organization export %+v", acme)
+	}
+}
+
+func TestImportMapsIDsAndResumes(t *testing.T) {
+	document := exportFrom(t, newSource())
+	target := newFakeInstance("dst")
+	target.failOnEmail = "admin@Globex.com"
+	importer := &transfer.Importer{Organizations: target, AdminUsers: target, Apps: appDao{fake: target}, Target: "https://production.example.com", PerPage: 1}
+	statePath := filepath.Join(t.TempDir(), "import-state.json")
+
+	idMap, _ := transfer.LoadIDMap(statePath)
+	summary, err := importer.Import(document, idMap)
+	if err != nil && summary.Created == 6 {
+		t.Log("Import stops at the first failure")
+	} else {
+		t.Fatalf("Import expected to fail after 6 objects, got %v and %+v", err, summary)
+	}
+
+	target.failOnEmail = ""
+	idMap, err = transfer.LoadIDMap(statePath)
+	if err != nil {
+		t.Fatalf("LoadIDMap expected no errors, got %s", err)
+	}
+
+	summary, err = importer.Import(document, idMap)
+	if err == nil && summary.Skipped == 6 && summary.Created == 4 {
+		t.Log("resumed Import skips what was already created")
+	} else {
+		t.Fatalf("resumed Import expected 6 skipped and 4 created, got %v and %+v", err, summary)
+	}
+
+	if len(target.organizations) == 2 && len(target.admins[target.organizations[1].ID]) == 2 {
+		t.Log("resume does not create duplicates")
+	} else {
+		t.Errorf("expected 2 organizations without duplicates, got %+v", target.organizations)
+	}
+
+	if idMap.Organizations["src-1"] == target.organizations[0].ID && idMap.Apps[document.Organizations[0].Apps[0].ID] != "" {
+		t.Log("source IDs are mapped to target IDs")
+	} else {
+		t.Errorf("unexpected ID map %+v", idMap)
+	}
+}
+
+func TestImportResumeFindsObjectCreatedBeforeFailure(t *testing.T) {
+	document := exportFrom(t, newSource())
+	target := newFakeInstance("dst")
+	target.timeoutOnEmail = "owner@Acme.com"
+	importer := &transfer.Importer{Organizations: target, AdminUsers: target, Apps: appDao{fake: target}, Target: "https://production.example.com", PerPage: 1}
+	statePath := filepath.Join(t.TempDir(), "import-state.json")
+
+	idMap, _ := transfer.LoadIDMap(statePath)
+	if _, err := importer.Import(document, idMap); err != nil && idMap.Pending != "" {
+		t.Log("a failed create stays pending in the map")
+	} else {
+		t.Fatalf("Import expected to fail with a pending step, got %v and %q", err, idMap.Pending)
+	}
+
+	target.timeoutOnEmail = ""
+	idMap, _ = transfer.LoadIDMap(statePath)
+	summary, err := importer.Import(document, idMap)
+	if err == nil && summary.Skipped == 3 && summary.Created == 7 {
+		t.Log("resumed Import finds the pending admin user instead of adding it again")
+	} else {
+		t.Fatalf("resumed Import expected 3 skipped and 7 created, got %v and %+v", err, summary)
+	}
+
+	acmeID := target.organizations[0].ID
+	if len(target.admins[acmeID]) == 2 && idMap.AdminUsers[document.Organizations[0].AdminUsers[1].ID] == target.admins[acmeID][1].ID {
+		t.Log("the admin user created before the failure is mapped, not duplicated")
+	} else {
+		t.Errorf("expected 2 admin users in Acme, got %+v", target.admins[acmeID])
+	}
+}
+
+func TestImportRejectsOtherTargets(t *testing.T) {
+	target := newFakeInstance("dst")
+	importer := &transfer.Importer{Organizations: target, AdminUsers: target, Apps: appDao{fake: target}, Target: "https://production.example.com", PerPage: 1}
+	idMap := transfer.NewIDMap()
+	idMap.Target = "https://other.example.com"
+
+	_, err := importer.Import(&transfer.Document{Version: transfer.Version}, idMap)
+	if errors.Is(err, transfer.ErrTargetMismatch) {
+		t.Log("Import refuses state saved for another target")
+	} else {
+		t.Errorf("Import expected ErrTargetMismatch, got %v", err)
+	}
+}
+
+func TestImportRejectsOtherVersionsAndSources(t *testing.T) {
+	_, err := transfer.ReadDocument(bytes.NewBufferString(`{"version": 2, "organizations": []}`))
+	if errors.Is(err, transfer.ErrUnsupportedVersion) {
+		t.Log("ReadDocument rejects unknown versions")
+	} else {
+		t.Errorf("ReadDocument expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	target := newFakeInstance("dst")
+	importer := &transfer.Importer{Organizations: target, AdminUsers: target, Apps: appDao{fake: target}, Target: "https://production.example.com", PerPage: 1}
+	idMap := transfer.NewIDMap()
+	idMap.Source = "https://other.example.com"
+
+	_, err = importer.Import(&transfer.Document{Version: transfer.Version, Source: "https://staging.example.com"}, idMap)
+	if errors.Is(err, transfer.ErrSourceMismatch) {
+		t.Log("Import refuses state saved for another export")
+	} else {
+		t.Errorf("Import expected ErrSourceMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+)
+
+func nameFlags(name, description *string) func(flags *flag.FlagSet) {
+	return func(flags *flag.FlagSet) {
+		flags.StringVar(name, "name", "", "name")
+		flags.StringVar(description, "description", "", "description")
+	}
+}
+
+func organizationTable(list []organizations.Organization) table {
+	t := table{header: []string{"ID", "NAME", "DESCRIPTION"}}
+	for _, organization := range list {
+		t.rows = append(t.rows, []string{organization.ID, organization.Name, organization.Description})
+	}
+	return t
+}
+
+func adminUserTable(list []organizations.AdminUser) table {
+	t := table{header: []string{"ID", "NAME", "EMAIL"}}
+	for _, adminUser := range list {
+		t.rows = append(t.rows, []string{adminUser.ID, adminUser.User.Name, adminUser.User.Email})
+	}
+	return t
+}
+
+func appTable(list []apps.App) table {
+	t := table{header: []string{"ID", "ORGANIZATION ID", "NAME", "DESCRIPTION"}}
+	for _, app := range list {
+		t.rows = append(t.rows, []string{app.ID, app.OrganizationID, app.Name, app.Description})
+	}
+	return t
+}
+
+func appUserTable(list []apps.AppUser) table {
+	t := table{header: []string{"ID", "NAME", "EMAIL"}}
+	for _, appUser := range list {
+		t.rows = append(t.rows, []string{appUser.ID, appUser.User.Name, appUser.User.Email})
+	}
+	return t
+}
+
+func appTokenTable(list []apps.AppToken) table {
+	t := table{header: []string{"ID", "APP ID", "TOKEN", "CREATED AT", "REVOKED AT", "REVOKED BY"}}
+	for _, appToken := range list {
+		t.rows = append(t.rows, []string{appToken.ID, appToken.AppID, optional(appToken.Token), appToken.CreatedAt, optional(appToken.RevokedAt), optional(appToken.RevokedBy)})
+	}
+	return t
+}
+
+func (c *cli) orgList(args []string) error {
+	var page, perPage int
+	if _, err := parseArgs(args, nil, pageFlags(&page, &perPage)); err != nil {
+		return err
+	}
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	list, err := repo.List(page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(list, func() table { return organizationTable(list.Data) })
+}
+
+func (c *cli) orgGet(args []string) error {
+	values, err := parseArgs(args, []string{"org-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	organization, err := repo.Find(values[0])
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(organization, func() table { return organizationTable([]organizations.Organization{*organization}) })
+}
+
+func (c *cli) orgCreate(args []string) error {
+	var organization organizations.Organization
+	if _, err := parseArgs(args, nil, nameFlags(&organization.Name, &organization.Description)); err != nil {
+		return err
+	}
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	created, err := repo.Create(organization)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(created, func() table { return organizationTable([]organizations.Organization{*created}) })
+}
+
+func (c *cli) orgUpdate(args []string) error {
+	var organization organizations.Organization
+	values, err := parseArgs(args, []string{"org-id"}, nameFlags(&organization.Name, &organization.Description))
+	if err != nil {
+		return err
+	}
+	organization.ID = values[0]
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	updated, err := repo.Update(organization)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(updated, func() table { return organizationTable([]organizations.Organization{*updated}) })
+}
+
+func (c *cli) orgDelete(args []string) error {
+	values, err := parseArgs(args, []string{"org-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	if err := repo.Delete(values[0]); err != nil {
+		return err
+	}
+
+	return c.printer.message("organization %s deleted", values[0])
+}
+
+func (c *cli) adminUsersList(args []string) error {
+	var page, perPage int
+	values, err := parseArgs(args, []string{"org-id"}, pageFlags(&page, &perPage))
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	list, err := repo.ListAdminUsers(values[0], page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(list, func() table { return adminUserTable(list.Data) })
+}
+
+func (c *cli) adminUsersAdd(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "email"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	added, err := repo.AddAdminUser(values[0], values[1])
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(added.Data, func() table { return adminUserTable([]organizations.AdminUser{added.Data}) })
+}
+
+func (c *cli) adminUsersRemove(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "admin-user-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.organizationRepo()
+	if err != nil {
+		return err
+	}
+
+	if err := repo.RemoveAdminUser(values[0], values[1]); err != nil {
+		return err
+	}
+
+	return c.printer.message("admin user %s removed", values[1])
+}
+
+func (c *cli) appsList(args []string) error {
+	var page, perPage int
+	values, err := parseArgs(args, []string{"org-id"}, pageFlags(&page, &perPage))
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	list, err := repo.List(values[0], page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(list, func() table { return appTable(list.Apps) })
+}
+
+func (c *cli) appsGet(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "app-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	app, err := repo.Find(values[0], values[1])
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(app, func() table { return appTable([]apps.App{app}) })
+}
+
+func (c *cli) appsCreate(args []string) error {
+	var app apps.App
+	values, err := parseArgs(args, []string{"org-id"}, nameFlags(&app.Name, &app.Description))
+	if err != nil {
+		return err
+	}
+	app.OrganizationID = values[0]
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	created, err := repo.Create(app)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(created, func() table { return appTable([]apps.App{created}) })
+}
+
+func (c *cli) appsUpdate(args []string) error {
+	var app apps.App
+	values, err := parseArgs(args, []string{"org-id", "app-id"}, nameFlags(&app.Name, &app.Description))
+	if err != nil {
+		return err
+	}
+	app.OrganizationID, app.ID = values[0], values[1]
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	updated, err := repo.Update(app)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(updated, func() table { return appTable([]apps.App{updated}) })
+}
+
+func (c *cli) appsDelete(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "app-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	if err := repo.Delete(values[0], values[1]); err != nil {
+		return err
+	}
+
+	return c.printer.message("app %s deleted", values[1])
+}
+
+func (c *cli) appsUsers(args []string) error {
+	var page, perPage int
+	values, err := parseArgs(args, []string{"org-id", "app-id"}, pageFlags(&page, &perPage))
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	list, err := repo.Users(values[0], values[1], page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(list, func() table { return appUserTable(list.Users) })
+}
+
+func (c *cli) appsAddUser(args []string) error {
+	var user apps.User
+	values, err := parseArgs(args, []string{"org-id", "app-id", "email"}, func(flags *flag.FlagSet) {
+		flags.StringVar(&user.Name, "name", "", "user name")
+	})
+	if err != nil {
+		return err
+	}
+	user.Email = values[2]
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	added, err := repo.AddUser(values[0], values[1], user)
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(added, func() table { return appUserTable([]apps.AppUser{added}) })
+}
+
+func (c *cli) appsRemoveUser(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "app-id", "app-user-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	if err := repo.RemoveUser(values[0], values[1], values[2]); err != nil {
+		return err
+	}
+
+	return c.printer.message("app user %s removed", values[2])
+}
+
+func (c *cli) appTokensList(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "app-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	list, err := repo.ListTokens(values[0], values[1])
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(list, func() table { return appTokenTable(list.AppTokens) })
+}
+
+func (c *cli) appTokensGet(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "app-id", "token-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	appToken, err := repo.FindToken(values[0], values[1], values[2])
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(appToken, func() table { return appTokenTable([]apps.AppToken{appToken}) })
+}
+
+func (c *cli) appTokensCreate(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "app-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	appToken, err := repo.CreateToken(values[0], values[1])
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(appToken, func() table { return appTokenTable([]apps.AppToken{appToken}) })
+}
+
+func (c *cli) appTokensRevoke(args []string) error {
+	values, err := parseArgs(args, []string{"org-id", "app-id", "token-id"}, nil)
+	if err != nil {
+		return err
+	}
+
+	repo, err := c.appRepo()
+	if err != nil {
+		return err
+	}
+
+	if err := repo.RevokeToken(values[0], values[1], values[2]); err != nil {
+		return err
+	}
+
+	return c.printer.message("app token %s revoked", values[2])
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/lib/audit"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+type cli struct {
+	profiles     *Profiles
+	tokens       tokenstore.Store
+	audit        *audit.FileSink
+	dryRun       bool
+	adminConfigs []*admin.Config
+	profileName  string
+	printer      printer
+	stdin        io.Reader
+	stderr       io.Writer
+}
+
+type command func(c *cli, args []string) error
+
+var commands = map[string]command{
+	"configure": (*cli).configure,
+	"profiles":  (*cli).listProfiles,
+	"sign-in":   (*cli).signIn,
+	"whoami":    (*cli).whoami,
+	"sign-out":  (*cli).signOut,
+	"refresh":   (*cli).refresh,
+	"org": subcommands(map[string]command{
+		"list":   (*cli).orgList,
+		"get":    (*cli).orgGet,
+		"create": (*cli).orgCreate,
+		"update": (*cli).orgUpdate,
+		"delete": (*cli).orgDelete,
+	}),
+	"admin-users": subcommands(map[string]command{
+		"list":   (*cli).adminUsersList,
+		"add":    (*cli).adminUsersAdd,
+		"remove": (*cli).adminUsersRemove,
+	}),
+	"apps": subcommands(map[string]command{
+		"list":        (*cli).appsList,
+		"get":         (*cli).appsGet,
+		"create":      (*cli).appsCreate,
+		"update":      (*cli).appsUpdate,
+		"delete":      (*cli).appsDelete,
+		"users":       (*cli).appsUsers,
+		"add-user":    (*cli).appsAddUser,
+		"remove-user": (*cli).appsRemoveUser,
+	}),
+	"app-tokens": subcommands(map[string]command{
+		"list":   (*cli).appTokensList,
+		"get":    (*cli).appTokensGet,
+		"create": (*cli).appTokensCreate,
+		"revoke": (*cli).appTokensRevoke,
+	}),
+	"export": (*cli).export,
+	"import": (*cli).importDocument,
+	"reconcile": subcommands(map[string]command{
+		"plan":  (*cli).reconcileShowPlan,
+		"apply": (*cli).reconcileApply,
+	}),
+}
+
+func (c *cli) dispatch(name string, args []string) error {
+	run, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", errUsage, name)
+	}
+
+	return run(c, args)
+}
+
+func subcommands(table map[string]command) command {
+	return func(c *cli, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("%w: missing subcommand", errUsage)
+		}
+
+		run, ok := table[args[0]]
+		if !ok {
+			return fmt.Errorf("%w: unknown subcommand %q", errUsage, args[0])
+		}
+
+		return run(c, args[1:])
+	}
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments, and checks the number of positionals.
+func parseArgs(args []string, positional []string, define func(flags *flag.FlagSet)) ([]string, error) {
+	flags := flag.NewFlagSet(strings.Join(positional, " "), flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	if define != nil {
+		define(flags)
+	}
+
+	var values []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUsage, err)
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		values = append(values, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if len(values) != len(positional) {
+		return nil, fmt.Errorf("%w: expected arguments <%s>", errUsage, strings.Join(positional, "> <"))
+	}
+
+	return values, nil
+}
+
+func pageFlags(page, perPage *int) func(flags *flag.FlagSet) {
+	return func(flags *flag.FlagSet) {
+		flags.IntVar(page, "page", 1, "page number")
+		flags.IntVar(perPage, "per-page", 20, "items per page")
+	}
+}
+
+func (c *cli) profile() (*Profile, error) {
+	return c.profiles.Get(c.profileName)
+}
+
+func (c *cli) authConfig() (*goeli.Config, *Profile, error) {
+	profile, err := c.profile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := goeli.NewServiceConfig(profile.ServiceType, profile.BaseURL, profile.AppToken)
+	config.TokenStore = c.tokens
+	config.TokenKey = c.profileName
+
+	return config, profile, nil
+}
+
+func (c *cli) signedInConfig() (*goeli.Config, error) {
+	config, _, err := c.authConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.requireSignIn(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c *cli) adminConfig() (*admin.Config, error) {
+	profile, err := c.profile()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.requireSignIn(); err != nil {
+		return nil, err
+	}
+
+	config := admin.NewConfig(profile.BaseURL, "")
+	config.TokenStore = c.tokens
+	config.TokenKey = c.profileName
+
+	if c.dryRun {
+		config.EnableDryRun()
+		c.adminConfigs = append(c.adminConfigs, config)
+	}
+
+	if c.audit != nil {
+		auth, _, err := c.authConfig()
+		if err != nil {
+			return nil, err
+		}
+		// The change has already been made when a record fails, so the
+		// command keeps its result and only warns.
+		config.Use(audit.Middleware(c.audit, audit.CurrentUser(auth), func(err error) {
+			fmt.Fprintf(c.stderr, "goeli: warning: %s\n", err)
+		}))
+	}
+
+	return config, nil
+}
+
+// reportPlannedRequests lists on stderr the changes a -dry-run command
+// would have sent. Commands that never built an admin config, such as
+// whoami or profiles, report nothing.
+func (c *cli) reportPlannedRequests() {
+	if len(c.adminConfigs) == 0 {
+		return
+	}
+
+	count := 0
+	for _, config := range c.adminConfigs {
+		for _, planned := range config.PlannedRequests() {
+			count++
+			fmt.Fprintf(c.stderr, "dry run: %s %s %s\n", planned.Method, planned.Path, planned.Body)
+		}
+	}
+
+	fmt.Fprintf(c.stderr, "dry run: %d changes not sent\n", count)
+}
+
+func (c *cli) requireSignIn() error {
+	_, err := c.tokens.Get(c.profileName)
+	if errors.Is(err, tokenstore.ErrNotFound) {
+		return fmt.Errorf("%w: profile %q is not signed in, run \"goeli sign-in\"", errUsage, c.profileName)
+	}
+
+	return err
+}
+
+func (c *cli) organizationRepo() (organizations.OrganizationRepo, error) {
+	adminConfig, err := c.adminConfig()
+	if err != nil {
+		return organizations.OrganizationRepo{}, err
+	}
+
+	return organizations.NewRepo(adminConfig), nil
+}
+
+func (c *cli) appRepo() (apps.AppRepo, error) {
+	adminConfig, err := c.adminConfig()
+	if err != nil {
+		return apps.AppRepo{}, err
+	}
+
+	return apps.NewRepo(adminConfig), nil
+}
+
+func (c *cli) configure(args []string) error {
+	profile := &Profile{}
+	if existing, ok := c.profiles.Profiles[c.profileName]; ok {
+		copied := *existing
+		profile = &copied
+	}
+
+	_, err := parseArgs(args, nil, func(flags *flag.FlagSet) {
+		flags.StringVar(&profile.BaseURL, "base-url", profile.BaseURL, "Letmein base URL")
+		flags.StringVar(&profile.AppToken, "app-token", profile.AppToken, "app token")
+		flags.StringVar(&profile.ServiceType, "service-type", profile.ServiceType, "regular or admin")
+	})
+	if err != nil {
+		return err
+	}
+
+	if profile.BaseURL == "" {
+		return fmt.Errorf("%w: -base-url is required", errUsage)
+	}
+
+	c.profiles.Profiles[c.profileName] = profile
+	if err := c.profiles.Save(); err != nil {
+		return err
+	}
+
+	return c.printer.message("profile %q saved", c.profileName)
+}
+
+func (c *cli) listProfiles(args []string) error {
+	if _, err := parseArgs(args, nil, nil); err != nil {
+		return err
+	}
+
+	names := c.profiles.Names()
+	return c.printer.print(names, func() table {
+		t := table{header: []string{"PROFILE", "BASE URL", "SERVICE TYPE", "SIGNED IN"}}
+		for _, name := range names {
+			profile := c.profiles.Profiles[name]
+			_, err := c.tokens.Get(name)
+			t.rows = append(t.rows, []string{name, profile.BaseURL, profile.ServiceType, strconv.FormatBool(err == nil)})
+		}
+		return t
+	})
+}
+
+func (c *cli) signIn(args []string) error {
+	var email string
+	if _, err := parseArgs(args, nil, func(flags *flag.FlagSet) {
+		flags.StringVar(&email, "email", "", "email to sign in with")
+	}); err != nil {
+		return err
+	}
+	if email == "" {
+		return fmt.Errorf("%w: -email is required", errUsage)
+	}
+
+	config, _, err := c.authConfig()
+	if err != nil {
+		return err
+	}
+
+	password, err := c.readPassword()
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := config.SignIn(email, password); err != nil {
+		return err
+	}
+
+	return c.printer.message("signed in as %s", email)
+}
+
+func (c *cli) readPassword() (string, error) {
+	if password := os.Getenv("GOELI_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("error reading password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("%w: password is required on stdin or in GOELI_PASSWORD", errUsage)
+	}
+
+	return password, nil
+}
+
+func (c *cli) whoami(args []string) error {
+	if _, err := parseArgs(args, nil, nil); err != nil {
+		return err
+	}
+
+	config, err := c.signedInConfig()
+	if err != nil {
+		return err
+	}
+
+	user, _, err := config.CurrentUser("")
+	if err != nil {
+		return err
+	}
+
+	return c.printer.print(user, func() table {
+		return userTable(user)
+	})
+}
+
+func (c *cli) signOut(args []string) error {
+	if _, err := parseArgs(args, nil, nil); err != nil {
+		return err
+	}
+
+	config, err := c.signedInConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.SignOut(""); err != nil {
+		return err
+	}
+
+	return c.printer.message("signed out")
+}
+
+func (c *cli) refresh(args []string) error {
+	if _, err := parseArgs(args, nil, nil); err != nil {
+		return err
+	}
+
+	config, err := c.signedInConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := config.Refresh(""); err != nil {
+		return err
+	}
+
+	return c.printer.message("session refreshed")
+}
+
+func userTable(user *entities.User) table {
+	return table{
+		header: []string{"ID", "NAME", "EMAIL", "ACTIVE", "LANGUAGE", "TIMEZONE"},
+		rows:   [][]string{{user.ID, user.Name, user.Email, strconv.FormatBool(user.Active), user.Language, user.Timezone}},
+	}
+}
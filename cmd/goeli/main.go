@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/adilsonchacon/goeli/lib/audit"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+const usage = `usage: goeli [-config path] [-profile name] [-output table|json] [-dry-run] [-audit-log path] <command> [arguments]
+
+commands:
+  configure -base-url url [-app-token token] [-service-type regular|admin]
+  profiles
+  sign-in -email email              password is read from GOELI_PASSWORD or stdin
+  whoami
+  sign-out
+  refresh
+  org list [-page n] [-per-page n]
+  org get <org-id>
+  org create -name name [-description text]
+  org update <org-id> -name name [-description text]
+  org delete <org-id>
+  admin-users list <org-id> [-page n] [-per-page n]
+  admin-users add <org-id> <email>
+  admin-users remove <org-id> <admin-user-id>
+  apps list <org-id> [-page n] [-per-page n]
+  apps get <org-id> <app-id>
+  apps create <org-id> -name name [-description text]
+  apps update <org-id> <app-id> -name name [-description text]
+  apps delete <org-id> <app-id>
+  apps users <org-id> <app-id> [-page n] [-per-page n]
+  apps add-user <org-id> <app-id> <email> [-name name]
+  apps remove-user <org-id> <app-id> <app-user-id>
+  app-tokens list <org-id> <app-id>
+  app-tokens get <org-id> <app-id> <token-id>
+  app-tokens create <org-id> <app-id>
+  app-tokens revoke <org-id> <app-id> <token-id>
+  reconcile plan <state-file> [-prune]
+  reconcile apply <state-file> [-prune] [-yes]
+  export [-org org-id]... [-file path]
+  import <file> [-state path]           run again after a failure to resume
+`
+
+const (
+	exitOK                  = 0
+	exitGeneral             = 1
+	exitUsage               = 2
+	exitBadRequest          = 10
+	exitUnauthorized        = 11
+	exitForbidden           = 12
+	exitNotFound            = 13
+	exitConflict            = 14
+	exitUnprocessableEntity = 15
+	exitTooManyRequests     = 16
+	exitServerError         = 17
+	exitServiceUnavailable  = 18
+	exitNetwork             = 19
+	exitUnexpectedResponse  = 20
+)
+
+var errUsage = errors.New("usage error")
+
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{errUsage, exitUsage},
+	{restapi.ErrEmptyPathParam, exitUsage},
+	{restapi.ErrInvalidPathParam, exitUsage},
+	{letmeinerr.ErrBadRequest, exitBadRequest},
+	{letmeinerr.ErrUnauthorized, exitUnauthorized},
+	{letmeinerr.ErrForbidden, exitForbidden},
+	{letmeinerr.ErrNotFound, exitNotFound},
+	{letmeinerr.ErrConflict, exitConflict},
+	{letmeinerr.ErrUnprocessableEntity, exitUnprocessableEntity},
+	{letmeinerr.ErrTooManyRequests, exitTooManyRequests},
+	{letmeinerr.ErrServerError, exitServerError},
+	{letmeinerr.ErrServiceUnavailable, exitServiceUnavailable},
+	{letmeinerr.ErrNetwork, exitNetwork},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	global := flag.NewFlagSet("goeli", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprint(stderr, usage) }
+	configPath := global.String("config", defaultConfigPath(), "path to the profiles file")
+	profileName := global.String("profile", envOr("GOELI_PROFILE", "default"), "profile to use")
+	output := global.String("output", "table", "output format: table or json")
+	dryRun := global.Bool("dry-run", false, "send reads but only print the admin changes that would be sent")
+	auditPath := global.String("audit-log", os.Getenv("GOELI_AUDIT_LOG"), "append a JSON Lines audit record for every admin change to this file")
+
+	if err := global.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if global.NArg() == 0 || (*output != "table" && *output != "json") {
+		global.Usage()
+		return exitUsage
+	}
+
+	profiles, err := loadProfiles(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "goeli: %s\n", err)
+		return exitGeneral
+	}
+
+	var auditSink *audit.FileSink
+	if *auditPath != "" {
+		auditSink, err = audit.NewFileSink(*auditPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "goeli: %s\n", err)
+			return exitGeneral
+		}
+		defer auditSink.Close()
+	}
+
+	c := &cli{
+		audit:       auditSink,
+		dryRun:      *dryRun,
+		profiles:    profiles,
+		tokens:      tokenstore.NewFile(tokenStorePath(*configPath)),
+		profileName: *profileName,
+		printer:     printer{format: *output, out: stdout},
+		stdin:       stdin,
+		stderr:      stderr,
+	}
+
+	err = c.dispatch(global.Arg(0), global.Args()[1:])
+	if c.dryRun {
+		c.reportPlannedRequests()
+	}
+	if err != nil {
+		reportError(stderr, err)
+		return exitCode(err)
+	}
+
+	return exitOK
+}
+
+func exitCode(err error) int {
+	for _, mapping := range exitCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.code
+		}
+	}
+
+	var unexpected *letmeinerr.UnexpectedResponseError
+	if errors.As(err, &unexpected) {
+		return exitUnexpectedResponse
+	}
+
+	return exitGeneral
+}
+
+func reportError(stderr io.Writer, err error) {
+	fmt.Fprintf(stderr, "goeli: %s\n", err)
+
+	var letmeinError *letmeinerr.LetmeinError
+	if !errors.As(err, &letmeinError) {
+		return
+	}
+
+	// Auth client errors already read as their detail.
+	if detail := letmeinError.Detail(); detail != "" && !strings.HasSuffix(err.Error(), detail) {
+		fmt.Fprintf(stderr, "  %s\n", detail)
+	}
+	for _, fieldError := range letmeinError.FieldErrors() {
+		for _, message := range fieldError.Message {
+			fmt.Fprintf(stderr, "  %s: %s\n", fieldError.Field, message)
+		}
+	}
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return fallback
+}
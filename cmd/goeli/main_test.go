@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/sessions"):
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": {"token": "a-valid-token"}}`))
+		case r.URL.Path == "/rest/admin/organizations" && r.Header.Get("Authorization") == "Bearer a-valid-token":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": [{"id": "1", "name": "Acme", "description": "Acme Inc."}], "pagination": {"page": 1, "perPage": 10, "total": 1}}`))
+		case r.URL.Path == "/rest/admin/organizations/behind-proxy":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<html><body>Service Unavailable</body></html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": {"detail": "not found"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func runCLI(configPath, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"-config", configPath}, args...), strings.NewReader(stdin), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestSignInStoresTokenAndListsOrganizations(t *testing.T) {
+	server := newTestServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	if code, _, stderr := runCLI(configPath, "", "configure", "-base-url", server.URL, "-app-token", "some-app-token"); code == exitOK {
+		t.Log("configure saves the profile")
+	} else {
+		t.Fatalf("configure expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if code, _, stderr := runCLI(configPath, "Secret.123!\n", "sign-in", "-email", "test@test.com"); code == exitOK {
+		t.Log("sign-in reads the password from stdin")
+	} else {
+		t.Fatalf("sign-in expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	token, err := tokenstore.NewFile(tokenStorePath(configPath)).Get("default")
+	if err == nil && token == "a-valid-token" {
+		t.Log("sign-in stores the session token under the profile name")
+	} else {
+		t.Errorf("sign-in expected the session token to be stored, got %q, %v", token, err)
+	}
+
+	content, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(content), "a-valid-token") {
+		t.Log("the profiles file does not hold the session token")
+	} else {
+		t.Error("the profiles file should not hold the session token")
+	}
+
+	code, stdout, stderr := runCLI(configPath, "", "-output", "json", "org", "list")
+	if code != exitOK {
+		t.Fatalf("org list expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	var list struct {
+		Data []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &list); err == nil && len(list.Data) == 1 && list.Data[0].Name == "Acme" {
+		t.Log("org list prints JSON with -output json")
+	} else {
+		t.Errorf("org list returned unexpected output %q", stdout)
+	}
+
+	code, stdout, _ = runCLI(configPath, "", "org", "list")
+	if code == exitOK && strings.Contains(stdout, "NAME") && strings.Contains(stdout, "Acme") {
+		t.Log("org list prints a table by default")
+	} else {
+		t.Errorf("org list returned unexpected table %q", stdout)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	server := newTestServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	runCLI(configPath, "", "configure", "-base-url", server.URL)
+	runCLI(configPath, "Secret.123!\n", "sign-in", "-email", "test@test.com")
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"not found", []string{"org", "get", "missing"}, exitNotFound},
+		{"proxy error page", []string{"org", "get", "behind-proxy"}, exitServiceUnavailable},
+		{"unknown command", []string{"frobnicate"}, exitUsage},
+		{"missing argument", []string{"org", "get"}, exitUsage},
+		{"unsafe id", []string{"org", "get", ".."}, exitUsage},
+		{"unknown profile", []string{"-profile", "staging", "org", "list"}, exitUsage},
+	}
+
+	for _, c := range cases {
+		code, _, stderr := runCLI(configPath, "", c.args...)
+		if code == c.expected {
+			t.Logf("%s exits with %d", c.name, c.expected)
+		} else {
+			t.Errorf("%s expected exit code %d, got %d: %s", c.name, c.expected, code, stderr)
+		}
+	}
+}
+
+func TestReconcileApplyAsksForConfirmation(t *testing.T) {
+	server := newTestServer(t)
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	statePath := filepath.Join(dir, "state.json")
+	os.WriteFile(statePath, []byte(`{"organizations": [{"name": "Globex"}]}`), 0o600)
+
+	runCLI(configPath, "", "configure", "-base-url", server.URL)
+	runCLI(configPath, "Secret.123!\n", "sign-in", "-email", "test@test.com")
+
+	code, stdout, _ := runCLI(configPath, "", "reconcile", "plan", statePath)
+	if code == exitOK && strings.Contains(stdout, `+ create organization "Globex"`) {
+		t.Log("reconcile plan prints the changes")
+	} else {
+		t.Errorf("reconcile plan expected the create change, got %d %q", code, stdout)
+	}
+
+	code, _, stderr := runCLI(configPath, "no\n", "reconcile", "apply", statePath)
+	if code == exitGeneral && strings.Contains(stderr, "Type \"yes\"") && strings.Contains(stderr, "apply canceled") {
+		t.Log("reconcile apply stops unless the user types yes")
+	} else {
+		t.Errorf("reconcile apply expected to be canceled, got %d %q", code, stderr)
+	}
+}
+
+func TestDryRunSendsNoChanges(t *testing.T) {
+	server := newTestServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	runCLI(configPath, "", "configure", "-base-url", server.URL)
+	runCLI(configPath, "Secret.123!\n", "sign-in", "-email", "test@test.com")
+
+	code, stdout, stderr := runCLI(configPath, "", "-dry-run", "org", "create", "-name", "Globex")
+	if code == exitOK && strings.Contains(stdout, "dry-run-1") {
+		t.Log("org create in dry run prints the synthesized organization")
+	} else {
+		t.Errorf("org create in dry run expected exit code 0, got %d %q %q", code, stdout, stderr)
+	}
+
+	if strings.Contains(stderr, `dry run: POST /rest/admin/organizations {"description":"","name":"Globex"}`) {
+		t.Log("dry run lists the request it did not send")
+	} else {
+		t.Errorf("dry run expected the planned request on stderr, got %q", stderr)
+	}
+
+	_, _, stderr = runCLI(configPath, "", "-dry-run", "profiles")
+	if !strings.Contains(stderr, "dry run") {
+		t.Log("commands without admin calls print no dry run summary")
+	} else {
+		t.Errorf("profiles in dry run expected no dry run summary, got %q", stderr)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+// print writes value as indented JSON, or as the table built by toTable.
+func (p printer) print(value any, toTable func() table) error {
+	if p.format == "json" {
+		encoder := json.NewEncoder(p.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	t := toTable()
+	writer := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+func (p printer) message(format string, args ...any) error {
+	if p.format == "json" {
+		return p.print(map[string]string{"message": fmt.Sprintf(format, args...)}, nil)
+	}
+
+	_, err := fmt.Fprintf(p.out, format+"\n", args...)
+	return err
+}
+
+func optional(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Profile holds the connection settings of one profile. Session tokens are
+// kept apart in the token store, keyed by profile name.
+type Profile struct {
+	BaseURL     string `json:"base_url"`
+	AppToken    string `json:"app_token,omitempty"`
+	ServiceType string `json:"service_type,omitempty"`
+}
+
+type Profiles struct {
+	path     string
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv("GOELI_CONFIG"); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".goeli.json"
+	}
+
+	return filepath.Join(dir, "goeli", "config.json")
+}
+
+// tokenStorePath keeps session tokens next to the profiles file.
+func tokenStorePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "tokens.json")
+}
+
+func loadProfiles(path string) (*Profiles, error) {
+	profiles := &Profiles{path: path, Profiles: make(map[string]*Profile)}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, profiles); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %w", path, err)
+	}
+	if profiles.Profiles == nil {
+		profiles.Profiles = make(map[string]*Profile)
+	}
+
+	return profiles, nil
+}
+
+func (profiles *Profiles) Get(name string) (*Profile, error) {
+	profile, ok := profiles.Profiles[name]
+	if !ok || profile.BaseURL == "" {
+		return nil, fmt.Errorf("%w: profile %q is not configured, run \"goeli configure -profile %s -base-url ...\"", errUsage, name, name)
+	}
+
+	return profile, nil
+}
+
+func (profiles *Profiles) Names() []string {
+	names := make([]string, 0, len(profiles.Profiles))
+	for name := range profiles.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Save writes the config with 0600 permissions, since profiles hold app
+// tokens.
+func (profiles *Profiles) Save() error {
+	if err := os.MkdirAll(filepath.Dir(profiles.path), 0o700); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(profiles.path, append(content, '\n'), 0o600); err != nil {
+		return fmt.Errorf("error writing config %s: %w", profiles.path, err)
+	}
+
+	return nil
+}
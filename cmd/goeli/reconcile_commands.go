@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/app/admin/reconcile"
+)
+
+var errCanceled = errors.New("apply canceled")
+
+func (c *cli) reconciler(prune bool) (*reconcile.Reconciler, error) {
+	adminConfig, err := c.adminConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	appRepo := apps.NewRepo(adminConfig)
+
+	return reconcile.New(&organizationRepo, &organizationRepo, &appRepo, reconcile.Options{PruneOrganizations: prune}), nil
+}
+
+func (c *cli) reconcilePlan(args []string, prune *bool, yes *bool) (*reconcile.Reconciler, *reconcile.Plan, error) {
+	values, err := parseArgs(args, []string{"state-file"}, func(flags *flag.FlagSet) {
+		flags.BoolVar(prune, "prune", false, "delete organizations missing from the state file")
+		if yes != nil {
+			flags.BoolVar(yes, "yes", false, "apply without asking for confirmation")
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(values[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errUsage, err)
+	}
+	defer file.Close()
+
+	state, err := reconcile.ReadState(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s: %s", errUsage, values[0], err)
+	}
+
+	reconciler, err := c.reconciler(*prune)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := reconciler.Plan(state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reconciler, plan, nil
+}
+
+func (c *cli) reconcileShowPlan(args []string) error {
+	var prune bool
+	_, plan, err := c.reconcilePlan(args, &prune, nil)
+	if err != nil {
+		return err
+	}
+
+	if c.printer.format == "json" {
+		return plan.WriteJSON(c.printer.out)
+	}
+
+	return plan.WriteText(c.printer.out)
+}
+
+// reconcileApply shows the plan on stderr and applies it once the user
+// types "yes", unless -yes is given.
+func (c *cli) reconcileApply(args []string) error {
+	var prune, yes bool
+	reconciler, plan, err := c.reconcilePlan(args, &prune, &yes)
+	if err != nil {
+		return err
+	}
+
+	if plan.Empty() {
+		return c.printer.message("no changes")
+	}
+
+	if !yes {
+		if err := plan.WriteText(c.stderr); err != nil {
+			return err
+		}
+
+		confirmed, err := c.confirm(fmt.Sprintf("Apply %d changes? Type \"yes\" to continue: ", len(plan.Changes)))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errCanceled
+		}
+	}
+
+	report, applyErr := reconciler.Apply(plan)
+
+	if c.printer.format == "json" {
+		err = report.WriteJSON(c.printer.out)
+	} else {
+		err = report.WriteText(c.printer.out)
+	}
+	if applyErr != nil {
+		return applyErr
+	}
+
+	return err
+}
+
+func (c *cli) confirm(prompt string) (bool, error) {
+	fmt.Fprint(c.stderr, prompt)
+
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("error reading confirmation: %w", err)
+	}
+
+	return strings.TrimSpace(line) == "yes", nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/app/admin/transfer"
+)
+
+func (c *cli) export(args []string) error {
+	var organizationIDs []string
+	var path string
+	_, err := parseArgs(args, nil, func(flags *flag.FlagSet) {
+		flags.Func("org", "organization id to export, repeatable", func(id string) error {
+			organizationIDs = append(organizationIDs, id)
+			return nil
+		})
+		flags.StringVar(&path, "file", "", "write the document to this file instead of stdout")
+	})
+	if err != nil {
+		return err
+	}
+
+	adminConfig, err := c.adminConfig()
+	if err != nil {
+		return err
+	}
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	appRepo := apps.NewRepo(adminConfig)
+	exporter := &transfer.Exporter{
+		Organizations:   &organizationRepo,
+		AdminUsers:      &organizationRepo,
+		Apps:            &appRepo,
+		OrganizationIDs: organizationIDs,
+		Source:          adminConfig.BaseURL,
+	}
+
+	document, err := exporter.Export()
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		return document.Write(c.printer.out)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	if err := document.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return c.printer.message("exported %d organizations to %s", len(document.Organizations), path)
+}
+
+// importDocument recreates an export on the current profile. The ID map is
+// kept in -state, by default next to the document, so running the same
+// command again resumes an interrupted import.
+func (c *cli) importDocument(args []string) error {
+	var statePath string
+	values, err := parseArgs(args, []string{"file"}, func(flags *flag.FlagSet) {
+		flags.StringVar(&statePath, "state", "", "import state file, defaults to <file>.state.json")
+	})
+	if err != nil {
+		return err
+	}
+	if statePath == "" {
+		statePath = values[0] + ".state.json"
+	}
+
+	document, err := c.readDocument(values[0])
+	if err != nil {
+		return err
+	}
+
+	idMap, err := transfer.LoadIDMap(statePath)
+	if err != nil {
+		return err
+	}
+
+	adminConfig, err := c.adminConfig()
+	if err != nil {
+		return err
+	}
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	appRepo := apps.NewRepo(adminConfig)
+	importer := &transfer.Importer{Organizations: &organizationRepo, AdminUsers: &organizationRepo, Apps: &appRepo, Target: adminConfig.BaseURL}
+
+	summary, err := importer.Import(document, idMap)
+	if err != nil {
+		return fmt.Errorf("%w (created %d, skipped %d; run the same command again to resume)", err, summary.Created, summary.Skipped)
+	}
+
+	return c.printer.print(summary, func() table {
+		return table{
+			header: []string{"CREATED", "SKIPPED", "STATE"},
+			rows:   [][]string{{fmt.Sprint(summary.Created), fmt.Sprint(summary.Skipped), statePath}},
+		}
+	})
+}
+
+func (c *cli) readDocument(path string) (*transfer.Document, error) {
+	reader := c.stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errUsage, err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	document, err := transfer.ReadDocument(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", errUsage, path, err)
+	}
+
+	return document, nil
+}
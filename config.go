@@ -1,11 +1,32 @@
 package goeli
 
-import "strings"
+import (
+	"context"
+	"net/http"
+	"strings"
 
+	"github.com/adilsonchacon/goeli/lib/restapi"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+// Config talks to the Letmein auth endpoints. When TokenStore is set, SignIn
+// stores the new session token under TokenKey, or under the email when
+// TokenKey is empty. With a TokenKey, Refresh stores the refreshed token,
+// SignOut deletes it, and calls given an empty sessionToken read it from the
+// store. Hooks registered with the On methods run synchronously unless
+// AsyncHooks is set.
 type Config struct {
 	ServiceType string
 	BaseURL     string
 	AppToken    string
+	Middlewares []restapi.Middleware
+	MaxBodySize int64
+	Transport   http.RoundTripper
+	TokenStore  tokenstore.Store
+	TokenKey    string
+	AsyncHooks  bool
+	hooks       hooks
+	ctx         context.Context
 }
 
 func NewServiceConfig(serviceType, baseURL, appToken string) *Config {
@@ -16,6 +37,27 @@ func NewServiceConfig(serviceType, baseURL, appToken string) *Config {
 	}
 }
 
+func (config *Config) Use(middlewares ...restapi.Middleware) {
+	config.Middlewares = append(config.Middlewares, middlewares...)
+}
+
+// WithContext returns a copy of config whose requests carry ctx, so
+// cancellation and trace context reach every call made through it.
+func (config *Config) WithContext(ctx context.Context) *Config {
+	copied := *config
+	copied.ctx = ctx
+
+	return &copied
+}
+
+func (config *Config) Context() context.Context {
+	if config.ctx == nil {
+		return context.Background()
+	}
+
+	return config.ctx
+}
+
 func normalizeServiceType(serviceType string) string {
 	if strings.ToLower(serviceType) == "admin" {
 		return "admin"
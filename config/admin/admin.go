@@ -1,8 +1,29 @@
 package admin
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/adilsonchacon/goeli/lib/restapi"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+// Config holds what every admin request needs. When SessionToken is empty
+// and TokenStore is set, each request reads the token stored under TokenKey,
+// so a token refreshed by another process is picked up.
 type Config struct {
-	BaseURL      string
-	SessionToken string
+	BaseURL         string
+	SessionToken    string
+	TokenStore      tokenstore.Store
+	TokenKey        string
+	Middlewares     []restapi.Middleware
+	MaxBodySize     int64
+	Transport       http.RoundTripper
+	ctx             context.Context
+	idempotencyKey  string
+	idempotencySeed string
+	dryRun          *dryRun
 }
 
 func NewConfig(baseURL, sessionToken string) *Config {
@@ -11,3 +32,111 @@ func NewConfig(baseURL, sessionToken string) *Config {
 		SessionToken: sessionToken,
 	}
 }
+
+func (config *Config) Use(middlewares ...restapi.Middleware) {
+	config.Middlewares = append(config.Middlewares, middlewares...)
+}
+
+// WithContext returns a copy of config whose requests carry ctx, so
+// cancellation and trace context reach every call made through it.
+func (config *Config) WithContext(ctx context.Context) *Config {
+	copied := *config
+	copied.ctx = ctx
+
+	return &copied
+}
+
+func (config *Config) Context() context.Context {
+	if config.ctx == nil {
+		return context.Background()
+	}
+
+	return config.ctx
+}
+
+// WithIdempotencyKey returns a copy of config whose create operations send
+// key as their Idempotency-Key instead of a generated one. Every create made
+// through the copy sends the same key, so use a fresh copy for each logical
+// operation, or WithIdempotencySeed for several.
+func (config *Config) WithIdempotencyKey(key string) *Config {
+	copied := *config
+	copied.idempotencyKey = key
+	copied.idempotencySeed = ""
+
+	return &copied
+}
+
+// WithIdempotencySeed returns a copy of config whose create operations
+// derive their Idempotency-Key from seed, the operation and the body, so
+// repeating a call with the same seed is deduplicated while different calls
+// made through the copy are not.
+func (config *Config) WithIdempotencySeed(seed string) *Config {
+	copied := *config
+	copied.idempotencyKey = ""
+	copied.idempotencySeed = seed
+
+	return &copied
+}
+
+func (config *Config) IdempotencyKey() string {
+	return config.idempotencyKey
+}
+
+// Idempotent marks req as a create that carries an Idempotency-Key: the key
+// of WithIdempotencyKey, one derived from the seed of WithIdempotencySeed,
+// or a generated one.
+func (config *Config) Idempotent(req *restapi.RESTApi) {
+	if config.idempotencySeed != "" {
+		req.DeriveIdempotencyKey(config.idempotencySeed)
+		return
+	}
+
+	req.Idempotent(config.idempotencyKey)
+}
+
+func (config *Config) NewRequest(operation, url, httpMethod string) *restapi.RESTApi {
+	req := restapi.New(url, httpMethod)
+	req.Operation = operation
+	req.Context = config.Context()
+	req.MaxBodySize = config.MaxBodySize
+	req.Transport = config.Transport
+
+	// Dry run comes first so no other middleware sees, retries or audits a
+	// call that is never sent.
+	if config.dryRun != nil {
+		req.Use(config.dryRun.middleware)
+	}
+
+	sessionToken, err := config.sessionToken()
+	if err != nil {
+		req.Use(failWith(err))
+		return req
+	}
+
+	req.AddHeader("Authorization", fmt.Sprintf("Bearer %s", sessionToken))
+	req.Use(config.Middlewares...)
+
+	return req
+}
+
+func (config *Config) sessionToken() (string, error) {
+	if config.SessionToken != "" || config.TokenStore == nil {
+		return config.SessionToken, nil
+	}
+
+	sessionToken, err := config.TokenStore.Get(config.TokenKey)
+	if err != nil {
+		return "", fmt.Errorf("could not load session token %q: %w", config.TokenKey, err)
+	}
+
+	return sessionToken, nil
+}
+
+// failWith stops the request before it is sent and returns err instead.
+func failWith(err error) restapi.Middleware {
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/adilsonchacon/goeli/lib/redact"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+// PlannedRequest is a mutating call that dry-run mode recorded instead of
+// sending. Body is redacted JSON.
+type PlannedRequest struct {
+	Operation string          `json:"operation"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Body      json.RawMessage `json:"body,omitempty"`
+}
+
+type dryRun struct {
+	mutex    sync.Mutex
+	requests []PlannedRequest
+}
+
+// EnableDryRun makes every request built from config, and from copies made
+// by WithContext, WithIdempotencyKey or WithIdempotencySeed afterwards,
+// record mutating calls instead of sending them. GET requests are still
+// sent.
+//
+// Recorded calls answer with synthesized results: POST with 201 and the
+// request body plus an ID like "dry-run-1", PUT and PATCH with the request
+// body and the status Letmein uses for that update, DELETE with 204. Reads
+// of objects created in dry-run mode find nothing on the server.
+func (config *Config) EnableDryRun() {
+	if config.dryRun == nil {
+		config.dryRun = &dryRun{}
+	}
+}
+
+func (config *Config) DryRun() bool {
+	return config.dryRun != nil
+}
+
+// PlannedRequests returns the calls recorded so far in dry-run mode, in the
+// order they were made.
+func (config *Config) PlannedRequests() []PlannedRequest {
+	if config.dryRun == nil {
+		return nil
+	}
+
+	config.dryRun.mutex.Lock()
+	defer config.dryRun.mutex.Unlock()
+
+	return append([]PlannedRequest(nil), config.dryRun.requests...)
+}
+
+func (recorder *dryRun) middleware(next restapi.RoundTrip) restapi.RoundTrip {
+	return func(req *restapi.Request) (*restapi.Response, error) {
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			return next(req)
+		}
+
+		planned := PlannedRequest{Operation: req.Operation, Method: req.Method, Path: req.Path()}
+		if len(req.Body) > 0 {
+			planned.Body = redact.JSON(req.Body)
+		}
+
+		recorder.mutex.Lock()
+		recorder.requests = append(recorder.requests, planned)
+		id := fmt.Sprintf("dry-run-%d", len(recorder.requests))
+		recorder.mutex.Unlock()
+
+		return synthesize(req, id)
+	}
+}
+
+// synthesize builds the response Letmein would most likely give. The data
+// holds the request fields, the IDs from the path and, for user
+// collections, the same fields nested under "user".
+func synthesize(req *restapi.Request, id string) (*restapi.Response, error) {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+
+	if req.Method == http.MethodDelete {
+		return &restapi.Response{StatusCode: http.StatusNoContent, Header: header}, nil
+	}
+
+	fields := make(map[string]any)
+	if len(req.Body) > 0 {
+		if err := json.Unmarshal(req.Body, &fields); err != nil {
+			fields = make(map[string]any)
+		}
+	}
+
+	segments := strings.Split(strings.Trim(req.Path(), "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		switch segments[i] {
+		case "organizations":
+			fields["organization_id"] = segments[i+1]
+		case "apps":
+			fields["app_id"] = segments[i+1]
+		}
+	}
+
+	statusCode := http.StatusOK
+	collection := segments[len(segments)-1]
+	if req.Method == http.MethodPost {
+		statusCode = http.StatusCreated
+		fields["id"] = id
+	} else if len(segments) > 1 {
+		fields["id"] = collection
+		collection = segments[len(segments)-2]
+	}
+
+	// Letmein answers an organization update with 201, which is what
+	// OrganizationRepo.Update expects; every other update answers 200.
+	if req.Method != http.MethodPost && collection == "organizations" {
+		statusCode = http.StatusCreated
+	}
+
+	if collection == "users" || collection == "admin_users" {
+		fields["user"] = map[string]any{"name": fields["name"], "email": fields["email"]}
+	}
+
+	body, err := json.Marshal(map[string]any{"data": fields})
+	if err != nil {
+		return nil, err
+	}
+
+	return &restapi.Response{StatusCode: statusCode, Header: header, Body: body}, nil
+}
@@ -1,269 +1,196 @@
 package goeli
 
 import (
-	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
 	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
 )
 
-func (config *Config) SignIn(email, password string) (string, int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/sessions"
-	jsonBody := []byte(`{"email": "` + email + `", "password": "` + password + `"}`)
-	bodyReader := bytes.NewReader(jsonBody)
+func (config *Config) SignIn(email, password string) (sessionToken string, statusCode int, err error) {
+	defer func() {
+		runHooks(config, config.hooks.signIn, SignInEvent{Email: email, StatusCode: statusCode, Err: err})
+	}()
 
-	req, err := http.NewRequest(http.MethodPost, requestURL, bodyReader)
-	if err != nil {
-		return "", 0, fmt.Errorf("could not create request for SignIn: %s", err)
-	}
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("app-token", config.AppToken)
-
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
+	req := config.newRequest("SignIn", "/sessions", http.MethodPost)
+	req.AddHeader("app-token", config.AppToken)
+	req.AddBody("email", email)
+	req.AddBody("password", password)
 
-	res, err := client.Do(req)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return "", 0, fmt.Errorf("error requesting for SignIn: %s", err)
+		return "", 0, fmt.Errorf("error requesting for SignIn: %w", err)
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	sessionToken, statusCode, err = parseSignInResponse(statusCode, body)
 	if err != nil {
-		return "", 0, fmt.Errorf("body reader error for SignIn: %s", err)
+		return sessionToken, statusCode, err
 	}
 
-	return parseSignInResponse(res.StatusCode, body)
+	return sessionToken, statusCode, config.storeSessionToken(config.tokenKey(email), sessionToken)
 }
 
 func (config *Config) SignedIn(sessionToken string) (bool, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/sessions/signed_in"
-
-	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	sessionToken, err := config.sessionToken(sessionToken)
 	if err != nil {
-		return false, fmt.Errorf("could not create request for SignedIn: %s", err)
+		return false, err
 	}
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("authorization", "Bearer "+sessionToken)
 
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
+	req := config.newRequest("SignedIn", "/sessions/signed_in", http.MethodGet)
+	req.AddHeader("authorization", "Bearer "+sessionToken)
 
-	res, err := client.Do(req)
+	statusCode, _, err := req.DoRequest()
 	if err != nil {
-		return false, fmt.Errorf("error requesting for SignedIn: %s", err)
+		return false, fmt.Errorf("error requesting for SignedIn: %w", err)
 	}
-	defer res.Body.Close()
+	config.sessionRejected("SignedIn", statusCode)
 
-	return res.StatusCode == 200, nil
+	return statusCode == 200, nil
 }
 
 func (config *Config) CurrentUser(sessionToken string) (*entities.User, int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/sessions"
-
-	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	sessionToken, err := config.sessionToken(sessionToken)
 	if err != nil {
-		return nil, 0, fmt.Errorf("could not create request for CurrentUser: %s", err)
+		return nil, 0, err
 	}
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("authorization", "Bearer "+sessionToken)
 
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error requesting for CurrentUser: %s", err)
-	}
-	defer res.Body.Close()
+	req := config.newRequest("CurrentUser", "/sessions", http.MethodGet)
+	req.AddHeader("authorization", "Bearer "+sessionToken)
 
-	body, err := io.ReadAll(res.Body)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return nil, 0, fmt.Errorf("body reader error for SignIn: %s", err)
+		return nil, 0, fmt.Errorf("error requesting for CurrentUser: %w", err)
 	}
+	config.sessionRejected("CurrentUser", statusCode)
 
-	return parseCurrentUserResponse(res.StatusCode, body)
+	return parseCurrentUserResponse(statusCode, body)
 }
 
-func (config *Config) SignOut(sessionToken string) (int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/sessions"
+func (config *Config) SignOut(sessionToken string) (statusCode int, err error) {
+	defer func() {
+		runHooks(config, config.hooks.signOut, SignOutEvent{StatusCode: statusCode, Err: err})
+	}()
 
-	req, err := http.NewRequest(http.MethodDelete, requestURL, nil)
+	sessionToken, err = config.sessionToken(sessionToken)
 	if err != nil {
-		return 0, fmt.Errorf("could not create request for SignOut: %s", err)
+		return 0, err
 	}
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("authorization", "Bearer "+sessionToken)
 
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
+	req := config.newRequest("SignOut", "/sessions", http.MethodDelete)
+	req.AddHeader("authorization", "Bearer "+sessionToken)
 
-	res, err := client.Do(req)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return 0, fmt.Errorf("error requesting for SignOut: %s", err)
+		return 0, fmt.Errorf("error requesting for SignOut: %w", err)
 	}
-	defer res.Body.Close()
+	config.sessionRejected("SignOut", statusCode)
 
-	body, err := io.ReadAll(res.Body)
+	statusCode, err = parseSignOutResponse(statusCode, body)
 	if err != nil {
-		return 0, fmt.Errorf("body reader error for SignOut: %s", err)
+		return statusCode, err
 	}
 
-	return parseSignOutResponse(res.StatusCode, body)
+	return statusCode, config.deleteSessionToken()
 }
 
-func (config *Config) Refresh(sessionToken string) (string, int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/sessions"
+func (config *Config) Refresh(sessionToken string) (refreshedToken string, statusCode int, err error) {
+	defer func() {
+		runHooks(config, config.hooks.refresh, RefreshEvent{StatusCode: statusCode, Err: err})
+	}()
 
-	req, err := http.NewRequest(http.MethodPut, requestURL, nil)
+	sessionToken, err = config.sessionToken(sessionToken)
 	if err != nil {
-		return "", 0, fmt.Errorf("could not create request for Refresh: %s", err)
+		return "", 0, err
 	}
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("authorization", "Bearer "+sessionToken)
 
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
+	req := config.newRequest("Refresh", "/sessions", http.MethodPut)
+	req.AddHeader("authorization", "Bearer "+sessionToken)
 
-	res, err := client.Do(req)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return "", 0, fmt.Errorf("error requesting for Refresh: %s", err)
+		return "", 0, fmt.Errorf("error requesting for Refresh: %w", err)
 	}
-	defer res.Body.Close()
+	config.sessionRejected("Refresh", statusCode)
 
-	body, err := io.ReadAll(res.Body)
+	refreshedToken, statusCode, err = parseRefreshResponse(statusCode, body)
 	if err != nil {
-		return "", 0, fmt.Errorf("body reader error for Refresh: %s", err)
+		return refreshedToken, statusCode, err
 	}
 
-	return parseRefreshResponse(res.StatusCode, body)
+	return refreshedToken, statusCode, config.storeSessionToken(config.TokenKey, refreshedToken)
 }
 
 func (config *Config) Unlock(unlockToken string) (int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/accounts/unlock"
-	jsonBody := []byte(`{"token": "` + unlockToken + `"}`)
-	bodyReader := bytes.NewReader(jsonBody)
-
-	req, err := http.NewRequest(http.MethodPut, requestURL, bodyReader)
-	if err != nil {
-		return 0, fmt.Errorf("could not create request for Unlock: %s", err)
-	}
-	req.Header.Set("content-type", "application/json")
-
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
+	req := config.newRequest("Unlock", "/accounts/unlock", http.MethodPut)
+	req.AddBody("token", unlockToken)
 
-	res, err := client.Do(req)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return 0, fmt.Errorf("error requesting for Unlock: %s", err)
+		return 0, fmt.Errorf("error requesting for Unlock: %w", err)
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return 0, fmt.Errorf("body reader error for Unlock: %s", err)
-	}
-
-	return parseDefaultAccountResponse(res.StatusCode, body)
+	return parseDefaultAccountResponse(statusCode, body)
 }
 
 func (config *Config) Confirm(confirmationToken string) (int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/accounts/confirm"
-	jsonBody := []byte(`{"token": "` + confirmationToken + `"}`)
-	bodyReader := bytes.NewReader(jsonBody)
-
-	req, err := http.NewRequest(http.MethodPut, requestURL, bodyReader)
-	if err != nil {
-		return 0, fmt.Errorf("could not create request for Confirm: %s", err)
-	}
-	req.Header.Set("content-type", "application/json")
+	req := config.newRequest("Confirm", "/accounts/confirm", http.MethodPut)
+	req.AddBody("token", confirmationToken)
 
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	res, err := client.Do(req)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return 0, fmt.Errorf("error requesting for Confirm: %s", err)
+		return 0, fmt.Errorf("error requesting for Confirm: %w", err)
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return 0, fmt.Errorf("body reader error for Confirm: %s", err)
-	}
-
-	return parseDefaultAccountResponse(res.StatusCode, body)
+	return parseDefaultAccountResponse(statusCode, body)
 }
 
-func (config *Config) RequestPasswordRecovery(appToken, email string) (int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/accounts/password/recover"
-	jsonBody := []byte(`{"email": "` + email + `"}`)
-	bodyReader := bytes.NewReader(jsonBody)
+func (config *Config) RequestPasswordRecovery(appToken, email string) (statusCode int, err error) {
+	defer func() {
+		runHooks(config, config.hooks.passwordRecoveryRequested, PasswordRecoveryRequestedEvent{Email: email, StatusCode: statusCode, Err: err})
+	}()
 
-	req, err := http.NewRequest(http.MethodPost, requestURL, bodyReader)
-	if err != nil {
-		return 0, fmt.Errorf("could not create request for RequestPasswordRecovery: %s", err)
-	}
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("app-token", appToken)
+	req := config.newRequest("RequestPasswordRecovery", "/accounts/password/recover", http.MethodPost)
+	req.AddHeader("app-token", appToken)
+	req.AddBody("email", email)
 
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	res, err := client.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("error requesting for RequestPasswordRecovery: %s", err)
-	}
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return 0, fmt.Errorf("body reader error for RequestPasswordRecovery: %s", err)
+		return 0, fmt.Errorf("error requesting for RequestPasswordRecovery: %w", err)
 	}
 
-	return parseRequestPasswordRecoveryResponse(res.StatusCode, body)
+	return parseRequestPasswordRecoveryResponse(statusCode, body)
 }
 
 func (config *Config) RecoverPassword(token, password, passwordConfirmation string) (int, error) {
-	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + "/accounts/password/recover"
-	jsonBody := []byte(`{"token": "` + token + `", "password": "` + password + `", "password_confirmation": "` + passwordConfirmation + `"}`)
-	bodyReader := bytes.NewReader(jsonBody)
+	req := config.newRequest("RecoverPassword", "/accounts/password/recover", http.MethodPut)
+	req.AddBody("token", token)
+	req.AddBody("password", password)
+	req.AddBody("password_confirmation", passwordConfirmation)
 
-	req, err := http.NewRequest(http.MethodPut, requestURL, bodyReader)
+	statusCode, body, err := req.DoRequest()
 	if err != nil {
-		return 0, fmt.Errorf("could not create request for RecoverPassword: %s", err)
+		return 0, fmt.Errorf("error requesting for RecoverPassword: %w", err)
 	}
-	req.Header.Set("content-type", "application/json")
 
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
+	return parseRequestPasswordRecoveryResponse(statusCode, body)
+}
 
-	res, err := client.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("error requesting for RecoverPassword: %s", err)
-	}
-	defer res.Body.Close()
+func (config *Config) newRequest(operation, path, httpMethod string) *restapi.RESTApi {
+	requestURL := config.BaseURL + "/rest" + addAdminToUrlPath(config.ServiceType) + path
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return 0, fmt.Errorf("body reader error for RecoverPassword: %s", err)
-	}
+	req := restapi.New(requestURL, httpMethod)
+	req.Operation = operation
+	req.Context = config.Context()
+	req.Timeout = 30 * time.Second
+	req.MaxBodySize = config.MaxBodySize
+	req.Transport = config.Transport
+	req.Use(config.Middlewares...)
 
-	return parseRequestPasswordRecoveryResponse(res.StatusCode, body)
+	return req
 }
 
 func addAdminToUrlPath(serviceName string) string {
@@ -280,7 +207,7 @@ func parseSignInResponse(statusCode int, body []byte) (string, int, error) {
 	if statusCode == http.StatusOK {
 		message, err = parseTokenResponse(body)
 	} else {
-		message, err = parseErrorResponse(body)
+		message, err = parseErrorResponse(statusCode, body)
 	}
 
 	return message, statusCode, err
@@ -296,14 +223,30 @@ func parseTokenResponse(body []byte) (string, error) {
 	return token.Data.Token, nil
 }
 
-func parseErrorResponse(body []byte) (string, error) {
-	var letmeinError *entities.LetmeinError
-	err := json.Unmarshal(body, &letmeinError)
-	if err != nil {
-		return "", fmt.Errorf("json parser error for SignIn when status ERROR: %s", err)
+// parseErrorResponse classifies an error status like the admin repos do. The
+// error reads as the detail Letmein gave, when there is one.
+func parseErrorResponse(statusCode int, body []byte) (string, error) {
+	letmeinError := letmeinerr.New(statusCode, body)
+	if detail := letmeinError.Detail(); detail != "" {
+		return "", &detailError{detail: detail, letmeinError: letmeinError}
 	}
 
-	return "", errors.New(letmeinError.Errors.Detail)
+	return "", letmeinError
+}
+
+// detailError reads as the detail of a Letmein error response and unwraps to
+// the LetmeinError of its status.
+type detailError struct {
+	detail       string
+	letmeinError *letmeinerr.LetmeinError
+}
+
+func (e *detailError) Error() string {
+	return e.detail
+}
+
+func (e *detailError) Unwrap() error {
+	return e.letmeinError
 }
 
 func parseUserResponse(body []byte) (*entities.User, int, error) {
@@ -320,7 +263,7 @@ func parseCurrentUserResponse(statusCode int, body []byte) (*entities.User, int,
 	if statusCode == http.StatusOK {
 		return parseUserResponse(body)
 	} else {
-		_, err := parseErrorResponse(body)
+		_, err := parseErrorResponse(statusCode, body)
 		return nil, statusCode, err
 	}
 }
@@ -329,7 +272,7 @@ func parseSignOutResponse(statusCode int, body []byte) (int, error) {
 	if statusCode == http.StatusOK {
 		return statusCode, nil
 	} else {
-		_, err := parseErrorResponse(body)
+		_, err := parseErrorResponse(statusCode, body)
 		return statusCode, err
 	}
 }
@@ -341,7 +284,7 @@ func parseRefreshResponse(statusCode int, body []byte) (string, int, error) {
 	if statusCode == http.StatusOK {
 		message, err = parseTokenResponse(body)
 	} else {
-		message, err = parseErrorResponse(body)
+		message, err = parseErrorResponse(statusCode, body)
 	}
 
 	return message, statusCode, err
@@ -351,7 +294,7 @@ func parseDefaultAccountResponse(statusCode int, body []byte) (int, error) {
 	if statusCode == http.StatusAccepted {
 		return statusCode, nil
 	} else {
-		_, err := parseErrorResponse(body)
+		_, err := parseErrorResponse(statusCode, body)
 		return statusCode, err
 	}
 }
@@ -360,7 +303,7 @@ func parseRequestPasswordRecoveryResponse(statusCode int, body []byte) (int, err
 	if statusCode == http.StatusOK {
 		return statusCode, nil
 	} else {
-		_, err := parseErrorResponse(body)
+		_, err := parseErrorResponse(statusCode, body)
 		return statusCode, err
 	}
 }
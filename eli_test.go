@@ -1,11 +1,15 @@
 package goeli_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
 )
 
 func TestSignInSuccess(t *testing.T) {
@@ -16,6 +20,7 @@ func TestSignInSuccess(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -51,6 +56,7 @@ func TestSignInFails(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(400)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -82,6 +88,7 @@ func TestSignedInSuccess(t *testing.T) {
 	jsonResponse := `{}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -101,6 +108,7 @@ func TestSignedInFails(t *testing.T) {
 	jsonResponse := `{}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(400)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -129,6 +137,7 @@ func TestCurrentUserSuccess(t *testing.T) {
 	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -164,6 +173,7 @@ func TestCurrentUserFails(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(404)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -199,6 +209,7 @@ func TestSignOutSuccess(t *testing.T) {
 	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -228,6 +239,7 @@ func TestSignOutFails(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(404)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -257,6 +269,7 @@ func TestRefreshSuccess(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -292,6 +305,7 @@ func TestRefreshFails(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(400)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -327,6 +341,7 @@ func TestUnlockSuccess(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(202)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -356,6 +371,7 @@ func TestUnlockFails(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(404)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -385,6 +401,7 @@ func TestConfirmSuccess(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(202)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -414,6 +431,7 @@ func TestConfirmFails(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(404)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -443,6 +461,7 @@ func TestRequestPasswordRecoverySuccess(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -472,6 +491,7 @@ func TestRequestPasswordRecoveryFails(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(404)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -501,6 +521,7 @@ func TestRecoverPasswordSuccess(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -530,6 +551,7 @@ func TestRecoverPasswordyFailsWithInvalidToken(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(400)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -565,6 +587,7 @@ func TestRecoverPasswordyFailsWithInvalidPasswords(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(400)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -600,6 +623,7 @@ func TestRecoverPasswordyFailsWithInvalidPasswordConfirmation(t *testing.T) {
  	}`
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(400)
 		_, _ = w.Write([]byte(jsonResponse))
 	}))
@@ -626,3 +650,166 @@ func TestRecoverPasswordyFailsWithInvalidPasswordConfirmation(t *testing.T) {
 		t.Errorf("[FAILED] with invalid password confirmation RecoverPassword did not return status code 400, but %d", statusCode)
 	}
 }
+
+func TestMiddlewaresSeeAuthOperations(t *testing.T) {
+	jsonResponse := `{
+		"data": {
+			"token": "a-valid-token"
+		}
+ 	}`
+
+	var correlationID string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		correlationID = r.Header.Get("x-correlation-id")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(jsonResponse))
+	}))
+	defer server.Close()
+
+	var operations []string
+
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+	eli.Use(func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			operations = append(operations, req.Operation)
+			req.Header.Set("x-correlation-id", "abc-123")
+			return next(req)
+		}
+	})
+
+	_, _, err := eli.SignIn("test@test.com", "Secret.123!")
+	if err != nil {
+		t.Errorf("[FAILED] SignIn with middleware returned an error: %s", err)
+	}
+
+	_, _, _ = eli.Refresh("a-valid-token")
+
+	if len(operations) == 2 && operations[0] == "SignIn" && operations[1] == "Refresh" {
+		t.Log("[PASSED] middleware sees the operation name of each call")
+	} else {
+		t.Errorf("[FAILED] middleware expected operations [SignIn Refresh], got %v", operations)
+	}
+
+	if correlationID == "abc-123" {
+		t.Log("[PASSED] middleware can add request headers")
+	} else {
+		t.Errorf("[FAILED] middleware header did not reach the server, got %q", correlationID)
+	}
+}
+
+func TestTokenStoreKeepsSessionToken(t *testing.T) {
+	var authorizations []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+
+		switch r.Method {
+		case http.MethodPost:
+			_, _ = w.Write([]byte(`{"data": {"token": "a-valid-token"}}`))
+		case http.MethodPut:
+			_, _ = w.Write([]byte(`{"data": {"token": "a-refreshed-token"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"message": "signed out"}`))
+		}
+	}))
+	defer server.Close()
+
+	store := tokenstore.NewMemory()
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+	eli.TokenStore = store
+	eli.TokenKey = "default"
+
+	if _, _, err := eli.SignIn("test@test.com", "Secret.123!"); err != nil {
+		t.Fatalf("[FAILED] SignIn with a token store returned an error: %s", err)
+	}
+
+	if token, _ := store.Get("default"); token == "a-valid-token" {
+		t.Log("[PASSED] SignIn stores the session token under TokenKey")
+	} else {
+		t.Errorf("[FAILED] SignIn expected to store a-valid-token, got %q", token)
+	}
+
+	if _, _, err := eli.Refresh(""); err != nil {
+		t.Fatalf("[FAILED] Refresh with a token store returned an error: %s", err)
+	}
+
+	if authorizations[1] == "Bearer a-valid-token" {
+		t.Log("[PASSED] Refresh with an empty token uses the stored one")
+	} else {
+		t.Errorf("[FAILED] Refresh expected the stored token, sent %q", authorizations[1])
+	}
+
+	if token, _ := store.Get("default"); token == "a-refreshed-token" {
+		t.Log("[PASSED] Refresh stores the refreshed token")
+	} else {
+		t.Errorf("[FAILED] Refresh expected to store a-refreshed-token, got %q", token)
+	}
+
+	if _, err := eli.SignOut(""); err != nil {
+		t.Fatalf("[FAILED] SignOut with a token store returned an error: %s", err)
+	}
+
+	if _, err := store.Get("default"); errors.Is(err, tokenstore.ErrNotFound) {
+		t.Log("[PASSED] SignOut deletes the stored token")
+	} else {
+		t.Errorf("[FAILED] SignOut expected to delete the token, got %v", err)
+	}
+
+	if _, _, err := eli.CurrentUser(""); errors.Is(err, tokenstore.ErrNotFound) && len(authorizations) == 3 {
+		t.Log("[PASSED] without a stored token no request is sent")
+	} else {
+		t.Errorf("[FAILED] expected ErrNotFound without a request, got %v after %d requests", err, len(authorizations))
+	}
+}
+
+func TestSignInStoresTokenUnderEmail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"data": {"token": "a-valid-token"}}`))
+	}))
+	defer server.Close()
+
+	store := tokenstore.NewMemory()
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+	eli.TokenStore = store
+
+	_, _, _ = eli.SignIn("test@test.com", "Secret.123!")
+
+	if token, _ := store.Get("test@test.com"); token == "a-valid-token" {
+		t.Log("[PASSED] without TokenKey SignIn stores the token under the email")
+	} else {
+		t.Errorf("[FAILED] SignIn expected to store the token under the email, got %q", token)
+	}
+}
+
+func TestAuthErrorsAreClassified(t *testing.T) {
+	statusCode := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(`{"errors": {"detail": "invalid credentials"}}`))
+	}))
+	defer server.Close()
+
+	user := goeli.NewServiceConfig("", server.URL, "some-app-token")
+
+	_, _, err := user.SignIn("test@test.com", "wrong-password")
+	if errors.Is(err, letmeinerr.ErrUnauthorized) && letmeinerr.IsAuthFailure(err) && err.Error() == "invalid credentials" {
+		t.Log("[PASSED] a SignIn 401 is an auth failure that reads as its detail")
+	} else {
+		t.Errorf("[FAILED] a SignIn 401 expected ErrUnauthorized reading \"invalid credentials\", got %v", err)
+	}
+
+	statusCode = http.StatusServiceUnavailable
+	_, _, err = user.SignIn("test@test.com", "Secret.123!")
+	if errors.Is(err, letmeinerr.ErrServiceUnavailable) && letmeinerr.IsRetryable(err) {
+		t.Log("[PASSED] a SignIn 503 is retryable")
+	} else {
+		t.Errorf("[FAILED] a SignIn 503 expected a retryable ErrServiceUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package goeli
+
+import (
+	"context"
+	"net/http"
+)
+
+// Hooks hear about auth outcomes after the call returns. They run in
+// registration order on the calling goroutine, or each on its own goroutine
+// when Config.AsyncHooks is set; async hooks get the call's context without
+// its cancellation. Events never carry passwords or session tokens.
+type hooks struct {
+	signIn                    []func(context.Context, SignInEvent)
+	signOut                   []func(context.Context, SignOutEvent)
+	refresh                   []func(context.Context, RefreshEvent)
+	sessionRejected           []func(context.Context, SessionRejectedEvent)
+	passwordRecoveryRequested []func(context.Context, PasswordRecoveryRequestedEvent)
+}
+
+// SignInEvent reports a SignIn attempt. Err is nil on success.
+type SignInEvent struct {
+	Email      string
+	StatusCode int
+	Err        error
+}
+
+func (event SignInEvent) Succeeded() bool {
+	return event.Err == nil
+}
+
+type SignOutEvent struct {
+	StatusCode int
+	Err        error
+}
+
+type RefreshEvent struct {
+	StatusCode int
+	Err        error
+}
+
+// SessionRejectedEvent reports that Letmein answered 401 to a call made with
+// a session token, such as "CurrentUser" or "Refresh": the session expired
+// or was revoked.
+type SessionRejectedEvent struct {
+	Operation  string
+	StatusCode int
+}
+
+type PasswordRecoveryRequestedEvent struct {
+	Email      string
+	StatusCode int
+	Err        error
+}
+
+func (config *Config) OnSignIn(hook func(context.Context, SignInEvent)) {
+	config.hooks.signIn = append(config.hooks.signIn, hook)
+}
+
+func (config *Config) OnSignOut(hook func(context.Context, SignOutEvent)) {
+	config.hooks.signOut = append(config.hooks.signOut, hook)
+}
+
+func (config *Config) OnRefresh(hook func(context.Context, RefreshEvent)) {
+	config.hooks.refresh = append(config.hooks.refresh, hook)
+}
+
+func (config *Config) OnSessionRejected(hook func(context.Context, SessionRejectedEvent)) {
+	config.hooks.sessionRejected = append(config.hooks.sessionRejected, hook)
+}
+
+func (config *Config) OnPasswordRecoveryRequested(hook func(context.Context, PasswordRecoveryRequestedEvent)) {
+	config.hooks.passwordRecoveryRequested = append(config.hooks.passwordRecoveryRequested, hook)
+}
+
+func (config *Config) sessionRejected(operation string, statusCode int) {
+	if statusCode == http.StatusUnauthorized {
+		runHooks(config, config.hooks.sessionRejected, SessionRejectedEvent{Operation: operation, StatusCode: statusCode})
+	}
+}
+
+func runHooks[E any](config *Config, hooks []func(context.Context, E), event E) {
+	ctx := config.Context()
+	for _, hook := range hooks {
+		if config.AsyncHooks {
+			go hook(context.WithoutCancel(ctx), event)
+		} else {
+			hook(ctx, event)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package goeli_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/internal/letmeintest"
+)
+
+func newSessionServer(t *testing.T, statusCode int) *httptest.Server {
+	if statusCode != http.StatusOK {
+		return letmeintest.NewServer(t, letmeintest.JSON(statusCode, `{"errors":{"detail":"invalid session"}}`))
+	}
+
+	user := letmeintest.JSON(http.StatusOK, `{"data":{"id":"u1","email":"user@example.com"}}`)
+	session := letmeintest.JSON(http.StatusOK, `{"data":{"token":"a-valid-token"}}`)
+
+	return letmeintest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/sessions" && r.Method == http.MethodGet {
+			user(w, r)
+			return
+		}
+		session(w, r)
+	}))
+}
+
+func TestHooksReportAuthOutcomes(t *testing.T) {
+	server := newSessionServer(t, http.StatusOK)
+
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+
+	var events []string
+	eli.OnSignIn(func(ctx context.Context, event goeli.SignInEvent) {
+		events = append(events, fmt.Sprintf("sign in %s %d %t", event.Email, event.StatusCode, event.Succeeded()))
+	})
+	eli.OnRefresh(func(ctx context.Context, event goeli.RefreshEvent) {
+		events = append(events, fmt.Sprintf("refresh %d %v", event.StatusCode, event.Err))
+	})
+	eli.OnSignOut(func(ctx context.Context, event goeli.SignOutEvent) {
+		events = append(events, fmt.Sprintf("sign out %d %v", event.StatusCode, event.Err))
+	})
+	eli.OnPasswordRecoveryRequested(func(ctx context.Context, event goeli.PasswordRecoveryRequestedEvent) {
+		events = append(events, fmt.Sprintf("recovery %s %d", event.Email, event.StatusCode))
+	})
+	eli.OnSessionRejected(func(ctx context.Context, event goeli.SessionRejectedEvent) {
+		events = append(events, "rejected "+event.Operation)
+	})
+
+	eli.SignIn("user@example.com", "Secret.123!")
+	eli.Refresh("a-valid-token")
+	eli.SignOut("a-valid-token")
+	eli.RequestPasswordRecovery("some-app-token", "user@example.com")
+
+	expected := []string{
+		"sign in user@example.com 200 true",
+		"refresh 200 <nil>",
+		"sign out 200 <nil>",
+		"recovery user@example.com 200",
+	}
+	if strings.Join(events, "\n") == strings.Join(expected, "\n") {
+		t.Log("Hooks run synchronously, in call order, with the outcome of each call")
+	} else {
+		t.Errorf("Hooks expected %q, got %q", expected, events)
+	}
+}
+
+func TestHooksReportFailuresAndRejectedSessions(t *testing.T) {
+	server := newSessionServer(t, http.StatusUnauthorized)
+
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+
+	var signIns []goeli.SignInEvent
+	eli.OnSignIn(func(ctx context.Context, event goeli.SignInEvent) {
+		signIns = append(signIns, event)
+	})
+
+	var rejected []string
+	eli.OnSessionRejected(func(ctx context.Context, event goeli.SessionRejectedEvent) {
+		rejected = append(rejected, event.Operation)
+	})
+
+	eli.SignIn("user@example.com", "Secret.123!")
+	if len(signIns) == 1 && !signIns[0].Succeeded() && signIns[0].StatusCode == http.StatusUnauthorized {
+		t.Log("OnSignIn reports failed sign ins")
+	} else {
+		t.Errorf("OnSignIn expected one failed sign in, got %+v", signIns)
+	}
+
+	if strings.Contains(fmt.Sprintf("%+v", signIns), "Secret.123!") {
+		t.Error("OnSignIn expected never to receive the password")
+	}
+
+	eli.SignedIn("an-expired-token")
+	eli.CurrentUser("an-expired-token")
+	eli.Refresh("an-expired-token")
+	eli.SignOut("an-expired-token")
+
+	if strings.Join(rejected, ",") == "SignedIn,CurrentUser,Refresh,SignOut" {
+		t.Log("OnSessionRejected runs for every call Letmein answers with 401")
+	} else {
+		t.Errorf("OnSessionRejected expected SignedIn,CurrentUser,Refresh,SignOut, got %v", rejected)
+	}
+}
+
+func TestAsyncHooksDoNotBlockTheCall(t *testing.T) {
+	server := newSessionServer(t, http.StatusOK)
+
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+	eli.AsyncHooks = true
+
+	release := make(chan struct{})
+	var wait sync.WaitGroup
+	wait.Add(1)
+
+	var hookErr error
+	eli.OnSignIn(func(ctx context.Context, event goeli.SignInEvent) {
+		defer wait.Done()
+		<-release
+		hookErr = ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		eli.WithContext(ctx).SignIn("user@example.com", "Secret.123!")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Log("SignIn returns while an async hook is still running")
+	case <-time.After(2 * time.Second):
+		t.Fatal("SignIn expected not to wait for async hooks")
+	}
+
+	cancel()
+	close(release)
+	wait.Wait()
+
+	if hookErr == nil {
+		t.Log("Async hooks keep running after the call's context is canceled")
+	} else {
+		t.Errorf("Async hook context expected not to be canceled, got %s", hookErr)
+	}
+}
@@ -0,0 +1,16 @@
+// Package httperr writes error responses for the HTTP handlers and
+// middlewares in this module.
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Write answers in the same shape as Letmein error responses, so
+// letmeinerr.New maps the response to the sentinel of statusCode.
+func Write(w http.ResponseWriter, statusCode int, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]map[string]string{"errors": {"detail": detail}})
+}
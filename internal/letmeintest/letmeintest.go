@@ -0,0 +1,47 @@
+// Package letmeintest fakes Letmein servers for the tests in this module.
+package letmeintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/internal/httperr"
+)
+
+// NewServer starts a server running handler and closes it when the test
+// ends.
+func NewServer(t testing.TB, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// JSON answers every request with statusCode and body. An empty body is
+// sent without a Content-Type, as Letmein does for 204.
+func JSON(statusCode int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if body != "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}
+}
+
+// Routes answers requests by "METHOD /path" and any other request with a
+// Letmein 404.
+type Routes map[string]http.Handler
+
+func (routes Routes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := routes[r.Method+" "+r.URL.Path]
+	if !ok {
+		httperr.Write(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
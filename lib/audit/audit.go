@@ -0,0 +1,336 @@
+// Package audit records every mutating admin call: who made it, what it
+// targeted, the values before and after, and how it ended. Records go to a
+// pluggable Sink; FileSink writes them as JSON Lines.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/lib/redact"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// ErrSinkFailed wraps the errors passed to the onError callback of
+// Middleware when a record could not be written.
+var ErrSinkFailed = errors.New("audit record not written")
+
+type Actor struct {
+	ID    string `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Record describes one mutating call. Targets holds the IDs found in the
+// path plus the ID of a created object, keyed like "organization_id".
+// Request, Before and After are redacted JSON.
+type Record struct {
+	Time       time.Time         `json:"time"`
+	Actor      Actor             `json:"actor"`
+	Operation  string            `json:"operation"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Targets    map[string]string `json:"targets,omitempty"`
+	Request    json.RawMessage   `json:"request,omitempty"`
+	Before     json.RawMessage   `json:"before,omitempty"`
+	After      json.RawMessage   `json:"after,omitempty"`
+	Outcome    string            `json:"outcome"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type Sink interface {
+	Write(record Record) error
+}
+
+type SinkFunc func(record Record) error
+
+func (fn SinkFunc) Write(record Record) error {
+	return fn(record)
+}
+
+// ActorResolver names the admin behind a request, usually from its
+// Authorization header.
+type ActorResolver interface {
+	Actor(req *restapi.Request) (Actor, error)
+}
+
+const (
+	// ActorTTL is how long CurrentUser remembers the actor of a session.
+	ActorTTL = 5 * time.Minute
+	// MaxCachedActors bounds the sessions CurrentUser remembers at once.
+	MaxCachedActors = 1024
+)
+
+// CurrentUser resolves actors with auth.CurrentUser and remembers the answer
+// per session token for ActorTTL, so each admin session costs one lookup.
+// Once MaxCachedActors sessions are remembered, expired ones are dropped
+// first, then the one closest to expiring.
+func CurrentUser(auth *goeli.Config) ActorResolver {
+	return &currentUserResolver{auth: auth, actors: make(map[string]cachedActor), now: time.Now}
+}
+
+type cachedActor struct {
+	actor     Actor
+	expiresAt time.Time
+}
+
+type currentUserResolver struct {
+	auth   *goeli.Config
+	mutex  sync.Mutex
+	actors map[string]cachedActor
+	now    func() time.Time
+}
+
+func (resolver *currentUserResolver) Actor(req *restapi.Request) (Actor, error) {
+	scheme, sessionToken, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || sessionToken == "" {
+		return Actor{}, errors.New("request has no session token")
+	}
+
+	sum := sha256.Sum256([]byte(sessionToken))
+	key := hex.EncodeToString(sum[:])
+
+	if actor, ok := resolver.cached(key); ok {
+		return actor, nil
+	}
+
+	user, _, err := resolver.auth.WithContext(req.Context).CurrentUser(sessionToken)
+	if err != nil {
+		return Actor{}, fmt.Errorf("error resolving current user: %w", err)
+	}
+
+	actor := Actor{ID: user.ID, Email: user.Email}
+	resolver.remember(key, actor)
+
+	return actor, nil
+}
+
+func (resolver *currentUserResolver) cached(key string) (Actor, bool) {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+
+	entry, ok := resolver.actors[key]
+	if !ok || !resolver.now().Before(entry.expiresAt) {
+		delete(resolver.actors, key)
+		return Actor{}, false
+	}
+
+	return entry.actor, true
+}
+
+func (resolver *currentUserResolver) remember(key string, actor Actor) {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+
+	now := resolver.now()
+	if _, exists := resolver.actors[key]; !exists && len(resolver.actors) >= MaxCachedActors {
+		for entryKey, entry := range resolver.actors {
+			if !now.Before(entry.expiresAt) {
+				delete(resolver.actors, entryKey)
+			}
+		}
+		if len(resolver.actors) >= MaxCachedActors {
+			resolver.evictOldest()
+		}
+	}
+
+	resolver.actors[key] = cachedActor{actor: actor, expiresAt: now.Add(ActorTTL)}
+}
+
+func (resolver *currentUserResolver) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	found := false
+
+	for entryKey, entry := range resolver.actors {
+		if !found || entry.expiresAt.Before(oldest) {
+			oldestKey, oldest, found = entryKey, entry.expiresAt, true
+		}
+	}
+
+	delete(resolver.actors, oldestKey)
+}
+
+// readableCollections are the collections whose members have a GET
+// endpoint. Members of the others, such as admin_users, cannot be read back,
+// so no before value is fetched for them.
+var readableCollections = map[string]bool{
+	"organizations": true,
+	"apps":          true,
+	"tokens":        true,
+}
+
+// targetKeys names the IDs that follow each collection in admin paths.
+var targetKeys = map[string]string{
+	"organizations": "organization_id",
+	"admin_users":   "admin_user_id",
+	"apps":          "app_id",
+	"users":         "app_user_id",
+	"tokens":        "token_id",
+}
+
+// Middleware writes a Record for every call that is not a GET or HEAD.
+// Before an update or delete of a readable object it reads the target with a
+// GET through the rest of the chain, so the record can hold the previous
+// value; targets that cannot be read are recorded without it.
+//
+// A record that cannot be written never changes the outcome of the call,
+// which Letmein has already applied: the error, wrapping ErrSinkFailed, goes
+// to onError, or to slog's default logger when onError is nil.
+//
+// Register it before retry and other middlewares so a record describes the
+// final outcome of the call, not each attempt. A nil actors leaves records
+// without an actor.
+func Middleware(sink Sink, actors ActorResolver, onError func(err error)) restapi.Middleware {
+	if onError == nil {
+		onError = func(err error) {
+			slog.Default().Error("audit record not written", slog.String("error", err.Error()))
+		}
+	}
+
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			if req.Method == http.MethodGet || req.Method == http.MethodHead {
+				return next(req)
+			}
+
+			record := Record{
+				Operation: req.Operation,
+				Method:    req.Method,
+				Path:      req.Path(),
+				Targets:   targets(req.Path()),
+			}
+			if len(req.Body) > 0 {
+				record.Request = redact.JSON(req.Body)
+			}
+
+			if actors != nil {
+				actor, err := actors.Actor(req)
+				if err != nil {
+					actor.Error = err.Error()
+				}
+				record.Actor = actor
+			}
+
+			if req.Method == http.MethodPut || req.Method == http.MethodPatch || req.Method == http.MethodDelete {
+				if readable(req.Path()) {
+					record.Before = before(next, req)
+				}
+			}
+
+			res, err := next(req)
+			record.Time = time.Now().UTC()
+
+			switch {
+			case err != nil:
+				record.Outcome = OutcomeFailure
+				record.Error = err.Error()
+				if res != nil {
+					record.StatusCode = res.StatusCode
+				}
+			case res.StatusCode >= http.StatusBadRequest:
+				record.Outcome = OutcomeFailure
+				record.StatusCode = res.StatusCode
+				record.Error = string(redact.JSON(res.Body))
+			default:
+				record.Outcome = OutcomeSuccess
+				record.StatusCode = res.StatusCode
+				record.After = data(res.Body)
+				if req.Method == http.MethodPost {
+					addCreatedTarget(record.Targets, req.Path(), record.After)
+				}
+			}
+
+			if sinkErr := sink.Write(record); sinkErr != nil {
+				onError(fmt.Errorf("%w: %s: %w", ErrSinkFailed, req.Operation, sinkErr))
+			}
+
+			return res, err
+		}
+	}
+}
+
+// readable reports whether path names a member of a readable collection.
+func readable(path string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	return len(segments) >= 2 && readableCollections[segments[len(segments)-2]]
+}
+
+func before(next restapi.RoundTrip, req *restapi.Request) json.RawMessage {
+	res, err := next(&restapi.Request{
+		Operation: req.Operation + ".Before",
+		Method:    http.MethodGet,
+		URL:       req.URL,
+		Header:    req.Header.Clone(),
+		Attempt:   1,
+		Context:   req.Context,
+	})
+	if err != nil || res.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	return data(res.Body)
+}
+
+// data returns the redacted "data" member of a Letmein response body.
+func data(body []byte) json.RawMessage {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &envelope) != nil || len(envelope.Data) == 0 {
+		return nil
+	}
+
+	return redact.JSON(envelope.Data)
+}
+
+func targets(path string) map[string]string {
+	found := make(map[string]string)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i := 0; i+1 < len(segments); i++ {
+		if key, ok := targetKeys[segments[i]]; ok {
+			id, err := url.PathUnescape(segments[i+1])
+			if err != nil {
+				id = segments[i+1]
+			}
+			found[key] = id
+			i++
+		}
+	}
+
+	return found
+}
+
+// addCreatedTarget records the ID of an object created by a POST to a
+// collection path.
+func addCreatedTarget(found map[string]string, path string, after json.RawMessage) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	key, ok := targetKeys[segments[len(segments)-1]]
+	if !ok || len(after) == 0 {
+		return
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(after, &created) == nil && created.ID != "" {
+		found[key] = created.ID
+	}
+}
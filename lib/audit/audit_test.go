@@ -0,0 +1,235 @@
+package audit_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/audit"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+func newLetmein(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/rest/sessions":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": {"id": "42", "email": "admin@acme.com"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/admin/organizations":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data": {"id": "1", "name": "Acme", "description": "Acme Inc."}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/admin/organizations/1":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": {"id": "1", "name": "Acme", "description": "Old"}}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/rest/admin/organizations/1":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data": {"id": "1", "name": "Acme", "description": "New"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/admin/organizations/1/apps/7/tokens":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data": {"id": "9", "app_id": "7", "token": "a-secret-app-token", "created_at": "2026-01-01"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": {"detail": "not found"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func readRecords(t *testing.T, path string) []audit.Record {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("could not open audit file: %s", err)
+	}
+	defer file.Close()
+
+	var records []audit.Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record audit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("audit line is not JSON: %s", scanner.Text())
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestMutationsAreAudited(t *testing.T) {
+	server := newLetmein(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	sink, err := audit.NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink expected no errors, got %s", err)
+	}
+	defer sink.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(audit.Middleware(sink, audit.CurrentUser(goeli.NewServiceConfig("", server.URL, "some-app-token")), nil))
+	organizationRepo := organizations.NewRepo(adminConfig)
+	appRepo := apps.NewRepo(adminConfig)
+
+	organizationRepo.Create(organizations.Organization{Name: "Acme", Description: "Acme Inc."})
+	organizationRepo.List(1, 10)
+	organizationRepo.Update(organizations.Organization{ID: "1", Name: "Acme", Description: "New"})
+	organizationRepo.Delete("missing")
+	appRepo.CreateToken("1", "7")
+
+	records := readRecords(t, path)
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records without the List call, got %d", len(records))
+	}
+
+	created := records[0]
+	if created.Operation == "organizations.Create" && created.Outcome == audit.OutcomeSuccess && created.Targets["organization_id"] == "1" {
+		t.Log("Create is recorded with the new organization ID")
+	} else {
+		t.Errorf("unexpected Create record %+v", created)
+	}
+
+	if created.Actor.ID == "42" && created.Actor.Email == "admin@acme.com" && !created.Time.IsZero() {
+		t.Log("records carry the acting admin and a timestamp")
+	} else {
+		t.Errorf("unexpected actor %+v", created.Actor)
+	}
+
+	updated := records[1]
+	if strings.Contains(string(updated.Before), `"Old"`) && strings.Contains(string(updated.After), `"New"`) {
+		t.Log("Update is recorded with before and after values")
+	} else {
+		t.Errorf("unexpected Update record before=%s after=%s", updated.Before, updated.After)
+	}
+
+	deleted := records[2]
+	if deleted.Outcome == audit.OutcomeFailure && deleted.StatusCode == http.StatusNotFound && deleted.Targets["organization_id"] == "missing" {
+		t.Log("failed Delete is recorded with its status")
+	} else {
+		t.Errorf("unexpected Delete record %+v", deleted)
+	}
+
+	token := records[3]
+	if token.Targets["app_id"] == "7" && token.Targets["token_id"] == "9" && !strings.Contains(string(token.After), "a-secret-app-token") {
+		t.Log("app token creation is recorded without the token")
+	} else {
+		t.Errorf("unexpected CreateToken record %+v after=%s", token.Targets, token.After)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() == 0o600 {
+		t.Log("audit file is created with 0600 permissions")
+	} else {
+		t.Errorf("audit file expected 0600 permissions, got %o", info.Mode().Perm())
+	}
+}
+
+func TestSinkFailureIsReported(t *testing.T) {
+	server := newLetmein(t)
+
+	var reported []error
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(audit.Middleware(audit.SinkFunc(func(record audit.Record) error {
+		return errors.New("disk full")
+	}), nil, func(err error) {
+		reported = append(reported, err)
+	}))
+	organizationRepo := organizations.NewRepo(adminConfig)
+
+	organization, err := organizationRepo.Create(organizations.Organization{Name: "Acme"})
+	if err == nil && organization.ID == "1" {
+		t.Log("a record that cannot be written keeps the committed response")
+	} else {
+		t.Errorf("expected the created organization, got %+v, %v", organization, err)
+	}
+
+	if len(reported) == 1 && errors.Is(reported[0], audit.ErrSinkFailed) && strings.Contains(reported[0].Error(), "disk full") {
+		t.Log("the sink failure goes to onError")
+	} else {
+		t.Errorf("expected one ErrSinkFailed reported to onError, got %v", reported)
+	}
+}
+
+func TestUnreadableTargetsAreNotFetched(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var records []audit.Record
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(audit.Middleware(audit.SinkFunc(func(record audit.Record) error {
+		records = append(records, record)
+		return nil
+	}), nil, nil))
+	organizationRepo := organizations.NewRepo(adminConfig)
+
+	if err := organizationRepo.RemoveAdminUser("1", "5"); err != nil {
+		t.Fatalf("RemoveAdminUser expected no errors, got %s", err)
+	}
+
+	if strings.Join(requests, ",") == "DELETE /rest/admin/organizations/1/admin_users/5" && len(records) == 1 && records[0].Before == nil {
+		t.Log("removing an admin user sends no GET for the before value")
+	} else {
+		t.Errorf("expected only the DELETE, got %v", requests)
+	}
+}
+
+func TestCurrentUserForgetsSessionsBeyondTheBound(t *testing.T) {
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"id": "1", "email": "admin@acme.com"}}`))
+	}))
+	defer server.Close()
+
+	resolver := audit.CurrentUser(goeli.NewServiceConfig("", server.URL, "some-app-token"))
+	resolve := func(sessionToken string) {
+		header := make(http.Header)
+		header.Set("Authorization", "Bearer "+sessionToken)
+		if _, err := resolver.Actor(&restapi.Request{Header: header}); err != nil {
+			t.Fatalf("Actor expected no errors, got %s", err)
+		}
+	}
+
+	resolve("session-0")
+	resolve("session-0")
+	if lookups == 1 {
+		t.Log("a session is looked up once")
+	} else {
+		t.Errorf("expected one lookup for a repeated session, got %d", lookups)
+	}
+
+	for i := 1; i <= audit.MaxCachedActors; i++ {
+		resolve(fmt.Sprintf("session-%d", i))
+	}
+	resolve("session-0")
+
+	if lookups == audit.MaxCachedActors+2 {
+		t.Log("the oldest session is forgotten once the bound is reached")
+	} else {
+		t.Errorf("expected %d lookups, got %d", audit.MaxCachedActors+2, lookups)
+	}
+}
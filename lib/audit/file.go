@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileSink appends records to a JSON Lines file created with 0600
+// permissions. Each record is written with a single write call, so records
+// from several processes appending to the same file do not interleave.
+type FileSink struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit file %s: %w", path, err)
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+func (sink *FileSink) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	if _, err := sink.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing audit record: %w", err)
+	}
+
+	return nil
+}
+
+func (sink *FileSink) Close() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	return sink.file.Close()
+}
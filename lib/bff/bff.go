@@ -0,0 +1,387 @@
+// Package bff keeps Letmein session tokens on the server for browser apps.
+// The browser only holds an opaque signed session cookie and a CSRF token;
+// the session token itself stays in a tokenstore.Store keyed by session ID,
+// stored together with the session's expiry.
+package bff
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/internal/httperr"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+const (
+	DefaultCookieName     = "goeli_session"
+	DefaultCSRFCookieName = "goeli_csrf"
+	DefaultCSRFHeader     = "X-CSRF-Token"
+	DefaultMaxAge         = 12 * time.Hour
+
+	minSecretSize   = 32
+	maxSignInBody   = 64 << 10
+	sessionIDSize   = 32
+	csrfPurpose     = "csrf"
+	sessionPurpose  = "session"
+	cookieSeparator = "."
+)
+
+var (
+	ErrNoSession     = errors.New("no valid session")
+	ErrInvalidCSRF   = errors.New("missing or invalid CSRF token")
+	ErrWeakSecret    = fmt.Errorf("secret must be at least %d bytes", minSecretSize)
+	ErrMissingOption = errors.New("missing option")
+)
+
+// Options configures a BFF. Auth and Sessions are required, and Secret must
+// hold at least 32 random bytes. Cookies are Secure unless
+// AllowInsecureCookies is set, which is meant for local development over
+// plain HTTP.
+type Options struct {
+	Auth                 *goeli.Config
+	Sessions             tokenstore.Store
+	Secret               []byte
+	CookieName           string
+	CSRFCookieName       string
+	CSRFHeader           string
+	CookiePath           string
+	CookieDomain         string
+	SameSite             http.SameSite
+	MaxAge               time.Duration
+	AllowInsecureCookies bool
+}
+
+type BFF struct {
+	auth    goeli.Config
+	options Options
+	now     func() time.Time
+}
+
+func New(options Options) (*BFF, error) {
+	if options.Auth == nil {
+		return nil, fmt.Errorf("%w: Auth", ErrMissingOption)
+	}
+	if options.Sessions == nil {
+		return nil, fmt.Errorf("%w: Sessions", ErrMissingOption)
+	}
+	if len(options.Secret) < minSecretSize {
+		return nil, ErrWeakSecret
+	}
+
+	if options.CookieName == "" {
+		options.CookieName = DefaultCookieName
+	}
+	if options.CSRFCookieName == "" {
+		options.CSRFCookieName = DefaultCSRFCookieName
+	}
+	if options.CSRFHeader == "" {
+		options.CSRFHeader = DefaultCSRFHeader
+	}
+	if options.CookiePath == "" {
+		options.CookiePath = "/"
+	}
+	if options.SameSite == 0 || options.SameSite == http.SameSiteDefaultMode {
+		options.SameSite = http.SameSiteLaxMode
+	}
+	if options.MaxAge <= 0 {
+		options.MaxAge = DefaultMaxAge
+	}
+
+	// Sessions are keyed by session ID here, so the auth config must not
+	// also store tokens under its own key.
+	auth := *options.Auth
+	auth.TokenStore = nil
+	auth.TokenKey = ""
+
+	return &BFF{auth: auth, options: options, now: time.Now}, nil
+}
+
+type sessionKey struct{}
+
+// SessionToken returns the Letmein session token of the request handled by
+// Protect, for handlers that call Letmein on the user's behalf.
+func SessionToken(ctx context.Context) (string, bool) {
+	sessionToken, ok := ctx.Value(sessionKey{}).(string)
+	return sessionToken, ok && sessionToken != ""
+}
+
+type signInRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type signInResponse struct {
+	CSRFToken string `json:"csrf_token"`
+}
+
+// SignInHandler accepts a JSON body with email and password, signs in with
+// Letmein and starts a session. The response carries the CSRF token the
+// browser must echo in the CSRF header of state-changing requests. A
+// rejected sign in answers 401 and an unreachable or failing Letmein 502,
+// both with a fixed message.
+func (bff *BFF) SignInHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			httperr.Write(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var credentials signInRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSignInBody)).Decode(&credentials); err != nil {
+			httperr.Write(w, http.StatusBadRequest, "invalid sign in request")
+			return
+		}
+
+		sessionToken, statusCode, err := bff.auth.WithContext(r.Context()).SignIn(credentials.Email, credentials.Password)
+		if err != nil {
+			// Letmein's error text can describe the account, so the browser
+			// only learns whether the credentials or the upstream failed.
+			if statusCode == 0 || statusCode >= http.StatusInternalServerError {
+				httperr.Write(w, http.StatusBadGateway, "could not sign in")
+				return
+			}
+			httperr.Write(w, http.StatusUnauthorized, "invalid email or password")
+			return
+		}
+
+		sessionID, err := newSessionID()
+		if err != nil {
+			httperr.Write(w, http.StatusInternalServerError, "could not start session")
+			return
+		}
+
+		expiresAt := bff.now().Add(bff.options.MaxAge).Unix()
+		if err := bff.options.Sessions.Put(sessionID, storedSession(expiresAt, sessionToken)); err != nil {
+			httperr.Write(w, http.StatusInternalServerError, "could not start session")
+			return
+		}
+
+		// A browser signing in again replaces its session, so the old one
+		// would never be used or signed out otherwise.
+		if previousID, previousToken, err := bff.session(r); err == nil {
+			bff.endSession(r.Context(), previousID, previousToken)
+		}
+
+		csrfToken := bff.sign(csrfPurpose, sessionID)
+		bff.setCookies(w, bff.sessionCookieValue(sessionID, expiresAt), csrfToken, bff.options.MaxAge)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(signInResponse{CSRFToken: csrfToken})
+	})
+}
+
+// SignOutHandler signs the session out of Letmein, deletes the stored token
+// and clears both cookies. It requires a valid session and CSRF token.
+func (bff *BFF) SignOutHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID, sessionToken, err := bff.session(r)
+		if err != nil {
+			bff.setCookies(w, "", "", -1)
+			httperr.Write(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if err := bff.checkCSRF(r, sessionID); err != nil {
+			httperr.Write(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		// The local session ends even if Letmein already considers the
+		// token expired.
+		_, signOutErr := bff.auth.WithContext(r.Context()).SignOut(sessionToken)
+		deleteErr := bff.options.Sessions.Delete(sessionID)
+		bff.setCookies(w, "", "", -1)
+
+		if deleteErr != nil {
+			httperr.Write(w, http.StatusInternalServerError, "could not end session")
+			return
+		}
+		if signOutErr != nil {
+			httperr.Write(w, http.StatusBadGateway, "could not sign out of Letmein")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Protect rejects requests without a valid session with 401 and
+// state-changing requests without a matching CSRF token with 403. Handlers
+// behind it read the session token with SessionToken.
+func (bff *BFF) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID, sessionToken, err := bff.session(r)
+		if err != nil {
+			httperr.Write(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if !isSafeMethod(r.Method) {
+			if err := bff.checkCSRF(r, sessionID); err != nil {
+				httperr.Write(w, http.StatusForbidden, err.Error())
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, sessionToken)))
+	})
+}
+
+// session verifies the session cookie and loads the stored token. The
+// cookie holds the session ID, its expiry and an HMAC of both. A session
+// found expired is signed out and deleted from the store.
+func (bff *BFF) session(r *http.Request) (string, string, error) {
+	cookie, err := r.Cookie(bff.options.CookieName)
+	if err != nil {
+		return "", "", ErrNoSession
+	}
+
+	parts := strings.Split(cookie.Value, cookieSeparator)
+	if len(parts) != 3 {
+		return "", "", ErrNoSession
+	}
+
+	payload := parts[0] + cookieSeparator + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(bff.sign(sessionPurpose, payload))) {
+		return "", "", ErrNoSession
+	}
+
+	cookieExpiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", "", ErrNoSession
+	}
+
+	sessionID := parts[0]
+	stored, err := bff.options.Sessions.Get(sessionID)
+	if err != nil {
+		return "", "", ErrNoSession
+	}
+
+	expiresAt, sessionToken, ok := parseStoredSession(stored)
+	if !ok {
+		bff.options.Sessions.Delete(sessionID)
+		return "", "", ErrNoSession
+	}
+
+	if now := bff.now().Unix(); now >= expiresAt || now >= cookieExpiresAt {
+		bff.endSession(r.Context(), sessionID, sessionToken)
+		return "", "", ErrNoSession
+	}
+
+	return sessionID, sessionToken, nil
+}
+
+// endSession signs sessionToken out of Letmein and deletes the session.
+// Both are best effort: Letmein may already have expired the token, and a
+// session left in the store is retried the next time it is presented.
+func (bff *BFF) endSession(ctx context.Context, sessionID, sessionToken string) {
+	bff.auth.WithContext(ctx).SignOut(sessionToken)
+	bff.options.Sessions.Delete(sessionID)
+}
+
+// storedSession is what the store keeps per session ID: the expiry in Unix
+// seconds and the Letmein session token.
+func storedSession(expiresAt int64, sessionToken string) string {
+	return strconv.FormatInt(expiresAt, 10) + cookieSeparator + sessionToken
+}
+
+func parseStoredSession(stored string) (int64, string, bool) {
+	expiry, sessionToken, ok := strings.Cut(stored, cookieSeparator)
+	if !ok || sessionToken == "" {
+		return 0, "", false
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return expiresAt, sessionToken, true
+}
+
+// checkCSRF is a signed double submit: the header must match the CSRF
+// cookie, and both must be the token derived from this session.
+func (bff *BFF) checkCSRF(r *http.Request, sessionID string) error {
+	header := r.Header.Get(bff.options.CSRFHeader)
+	cookie, err := r.Cookie(bff.options.CSRFCookieName)
+	if header == "" || err != nil {
+		return ErrInvalidCSRF
+	}
+
+	expected := bff.sign(csrfPurpose, sessionID)
+	if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(header), []byte(expected)) != 1 {
+		return ErrInvalidCSRF
+	}
+
+	return nil
+}
+
+func (bff *BFF) sessionCookieValue(sessionID string, expiresAt int64) string {
+	payload := sessionID + cookieSeparator + strconv.FormatInt(expiresAt, 10)
+
+	return payload + cookieSeparator + bff.sign(sessionPurpose, payload)
+}
+
+// setCookies writes the session and CSRF cookies. A negative maxAge clears
+// them. Only the session cookie is HttpOnly: the browser app has to read the
+// CSRF cookie to echo it.
+func (bff *BFF) setCookies(w http.ResponseWriter, sessionValue, csrfValue string, maxAge time.Duration) {
+	seconds := int(maxAge / time.Second)
+	if maxAge < 0 {
+		seconds = -1
+	}
+
+	for _, cookie := range []*http.Cookie{
+		{Name: bff.options.CookieName, Value: sessionValue, HttpOnly: true},
+		{Name: bff.options.CSRFCookieName, Value: csrfValue},
+	} {
+		cookie.Path = bff.options.CookiePath
+		cookie.Domain = bff.options.CookieDomain
+		cookie.MaxAge = seconds
+		cookie.Secure = !bff.options.AllowInsecureCookies
+		cookie.SameSite = bff.options.SameSite
+		http.SetCookie(w, cookie)
+	}
+}
+
+func (bff *BFF) sign(purpose, value string) string {
+	mac := hmac.New(sha256.New, bff.options.Secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newSessionID() (string, error) {
+	id := make([]byte, sessionIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,284 @@
+package bff_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/internal/letmeintest"
+	"github.com/adilsonchacon/goeli/lib/bff"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+type testApp struct {
+	server   *httptest.Server
+	sessions *tokenstore.Memory
+	signOuts []string
+}
+
+func newTestApp(t *testing.T, maxAge time.Duration) *testApp {
+	t.Helper()
+	app := &testApp{sessions: tokenstore.NewMemory()}
+
+	letmein := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			app.signOuts = append(app.signOuts, r.Header.Get("authorization"))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"message": "signed out"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"token": "a-valid-token"}}`))
+	}))
+	t.Cleanup(letmein.Close)
+
+	handler, err := bff.New(bff.Options{
+		Auth:     goeli.NewServiceConfig("", letmein.URL, "some-app-token"),
+		Sessions: app.sessions,
+		Secret:   secret,
+		MaxAge:   maxAge,
+	})
+	if err != nil {
+		t.Fatalf("New expected no errors, got %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/sign_in", handler.SignInHandler())
+	mux.Handle("/sign_out", handler.SignOutHandler())
+	mux.Handle("/api/", handler.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionToken, _ := bff.SessionToken(r.Context())
+		w.Write([]byte(sessionToken))
+	})))
+
+	app.server = httptest.NewServer(mux)
+	t.Cleanup(app.server.Close)
+
+	return app
+}
+
+func (app *testApp) do(t *testing.T, method, path string, cookies []*http.Cookie, csrfToken string) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, app.server.URL+path, strings.NewReader(`{"email": "test@test.com", "password": "Secret.123!"}`))
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if csrfToken != "" {
+		req.Header.Set(bff.DefaultCSRFHeader, csrfToken)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	return res
+}
+
+func cookieNamed(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestSignInSetsOpaqueSessionCookie(t *testing.T) {
+	app := newTestApp(t, 0)
+	res := app.do(t, http.MethodPost, "/sign_in", nil, "")
+
+	session := cookieNamed(res.Cookies(), bff.DefaultCookieName)
+	csrf := cookieNamed(res.Cookies(), bff.DefaultCSRFCookieName)
+	if res.StatusCode != http.StatusOK || session == nil || csrf == nil {
+		t.Fatalf("SignIn expected 200 with both cookies, got %d and %v", res.StatusCode, res.Cookies())
+	}
+
+	if !strings.Contains(session.Value, "a-valid-token") {
+		t.Log("session cookie does not expose the Letmein token")
+	} else {
+		t.Error("session cookie should not contain the Letmein token")
+	}
+
+	if session.HttpOnly && session.Secure && session.SameSite == http.SameSiteLaxMode {
+		t.Log("session cookie is HttpOnly, Secure and SameSite")
+	} else {
+		t.Errorf("unexpected session cookie attributes %+v", session)
+	}
+
+	if !csrf.HttpOnly {
+		t.Log("CSRF cookie is readable by the browser app")
+	} else {
+		t.Error("CSRF cookie should not be HttpOnly")
+	}
+}
+
+func TestSignInFailuresHideLetmeinErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		body     string
+		expected int
+	}{
+		{"rejected credentials", http.StatusUnprocessableEntity, `{"errors": {"detail": "account user-42 is locked"}}`, http.StatusUnauthorized},
+		{"server failure", http.StatusInternalServerError, `{"errors": {"detail": "db01 connection refused"}}`, http.StatusBadGateway},
+	}
+
+	for _, c := range cases {
+		letmein := letmeintest.NewServer(t, letmeintest.JSON(c.status, c.body))
+		handler, err := bff.New(bff.Options{
+			Auth:     goeli.NewServiceConfig("", letmein.URL, "some-app-token"),
+			Sessions: tokenstore.NewMemory(),
+			Secret:   secret,
+		})
+		if err != nil {
+			t.Fatalf("New expected no errors, got %s", err)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.SignInHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sign_in", strings.NewReader(`{"email": "test@test.com", "password": "Secret.123!"}`)))
+
+		body := recorder.Body.String()
+		if recorder.Code == c.expected && !strings.Contains(body, "user-42") && !strings.Contains(body, "db01") {
+			t.Logf("%s answers %d with a generic message", c.name, c.expected)
+		} else {
+			t.Errorf("%s expected %d without Letmein's detail, got %d %s", c.name, c.expected, recorder.Code, body)
+		}
+	}
+}
+
+func TestProtectChecksSessionAndCSRF(t *testing.T) {
+	app := newTestApp(t, 0)
+	signIn := app.do(t, http.MethodPost, "/sign_in", nil, "")
+	cookies := signIn.Cookies()
+	csrfToken := cookieNamed(cookies, bff.DefaultCSRFCookieName).Value
+
+	tampered := *cookieNamed(cookies, bff.DefaultCookieName)
+	tampered.Value = "x" + tampered.Value[1:]
+
+	cases := []struct {
+		name     string
+		method   string
+		cookies  []*http.Cookie
+		csrf     string
+		expected int
+	}{
+		{"GET with session", http.MethodGet, cookies, "", http.StatusOK},
+		{"GET without session", http.MethodGet, nil, "", http.StatusUnauthorized},
+		{"GET with tampered cookie", http.MethodGet, []*http.Cookie{&tampered}, "", http.StatusUnauthorized},
+		{"POST without CSRF header", http.MethodPost, cookies, "", http.StatusForbidden},
+		{"POST with wrong CSRF header", http.MethodPost, cookies, "not-the-token", http.StatusForbidden},
+		{"POST with CSRF header", http.MethodPost, cookies, csrfToken, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		res := app.do(t, c.method, "/api/orders", c.cookies, c.csrf)
+		if res.StatusCode == c.expected {
+			t.Logf("%s returns %d", c.name, c.expected)
+		} else {
+			t.Errorf("%s expected %d, got %d", c.name, c.expected, res.StatusCode)
+		}
+	}
+}
+
+func TestSignOutClearsSession(t *testing.T) {
+	app := newTestApp(t, 0)
+	cookies := app.do(t, http.MethodPost, "/sign_in", nil, "").Cookies()
+	csrfToken := cookieNamed(cookies, bff.DefaultCSRFCookieName).Value
+
+	if res := app.do(t, http.MethodPost, "/sign_out", cookies, ""); res.StatusCode == http.StatusForbidden {
+		t.Log("SignOut without CSRF header is rejected")
+	} else {
+		t.Errorf("SignOut without CSRF header expected 403, got %d", res.StatusCode)
+	}
+
+	res := app.do(t, http.MethodPost, "/sign_out", cookies, csrfToken)
+	if res.StatusCode == http.StatusNoContent && len(app.signOuts) == 1 && app.signOuts[0] == "Bearer a-valid-token" {
+		t.Log("SignOut calls Letmein with the stored token")
+	} else {
+		t.Errorf("SignOut expected 204 and a Letmein sign out, got %d and %v", res.StatusCode, app.signOuts)
+	}
+
+	if session := cookieNamed(res.Cookies(), bff.DefaultCookieName); session != nil && session.MaxAge < 0 {
+		t.Log("SignOut clears the session cookie")
+	} else {
+		t.Errorf("SignOut expected to clear the session cookie, got %v", res.Cookies())
+	}
+
+	sessionID := strings.Split(cookieNamed(cookies, bff.DefaultCookieName).Value, ".")[0]
+	if _, err := app.sessions.Get(sessionID); errors.Is(err, tokenstore.ErrNotFound) {
+		t.Log("SignOut deletes the stored token")
+	} else {
+		t.Errorf("SignOut expected the stored token to be deleted, got %v", err)
+	}
+
+	if res := app.do(t, http.MethodGet, "/api/orders", cookies, ""); res.StatusCode == http.StatusUnauthorized {
+		t.Log("old cookie no longer opens a session")
+	} else {
+		t.Errorf("old cookie expected 401, got %d", res.StatusCode)
+	}
+}
+
+func TestExpiredSessionIsSignedOutAndDeleted(t *testing.T) {
+	app := newTestApp(t, time.Second)
+	cookies := app.do(t, http.MethodPost, "/sign_in", nil, "").Cookies()
+	sessionID := strings.Split(cookieNamed(cookies, bff.DefaultCookieName).Value, ".")[0]
+
+	time.Sleep(time.Second)
+
+	if res := app.do(t, http.MethodGet, "/api/orders", cookies, ""); res.StatusCode == http.StatusUnauthorized {
+		t.Log("expired session is rejected")
+	} else {
+		t.Errorf("expired session expected 401, got %d", res.StatusCode)
+	}
+
+	if _, err := app.sessions.Get(sessionID); errors.Is(err, tokenstore.ErrNotFound) && len(app.signOuts) == 1 {
+		t.Log("expired session is signed out of Letmein and deleted from the store")
+	} else {
+		t.Errorf("expired session expected a sign out and deletion, got %v and %v", err, app.signOuts)
+	}
+}
+
+func TestSignInAgainEndsPreviousSession(t *testing.T) {
+	app := newTestApp(t, 0)
+	previous := app.do(t, http.MethodPost, "/sign_in", nil, "").Cookies()
+	previousID := strings.Split(cookieNamed(previous, bff.DefaultCookieName).Value, ".")[0]
+
+	current := app.do(t, http.MethodPost, "/sign_in", previous, "").Cookies()
+
+	if _, err := app.sessions.Get(previousID); errors.Is(err, tokenstore.ErrNotFound) && len(app.signOuts) == 1 {
+		t.Log("signing in again signs out and deletes the previous session")
+	} else {
+		t.Errorf("signing in again expected the previous session to end, got %v and %v", err, app.signOuts)
+	}
+
+	if res := app.do(t, http.MethodGet, "/api/orders", current, ""); res.StatusCode == http.StatusOK {
+		t.Log("the new session opens protected routes")
+	} else {
+		t.Errorf("the new session expected 200, got %d", res.StatusCode)
+	}
+}
+
+func TestNewRejectsWeakSecret(t *testing.T) {
+	_, err := bff.New(bff.Options{
+		Auth:     goeli.NewServiceConfig("", "http://localhost", "some-app-token"),
+		Sessions: tokenstore.NewMemory(),
+		Secret:   []byte("short"),
+	})
+
+	if errors.Is(err, bff.ErrWeakSecret) {
+		t.Log("New rejects secrets shorter than 32 bytes")
+	} else {
+		t.Errorf("New expected ErrWeakSecret, got %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+// Package cassette records real Letmein interactions into files and serves
+// them back in tests. Set a Recorder or a Replayer as the Transport of
+// goeli.Config or admin.Config.
+//
+// Cassettes are redacted with the redact package before they are written,
+// so they can be committed. Request bodies and query strings are redacted
+// the same way before they are matched, so a replayed SignIn matches
+// whatever password the test sends.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/redact"
+)
+
+const Version = 1
+
+// ErrNoInteraction is returned for a request the cassette has no answer
+// for. It also wraps letmeinerr.ErrPermanent, so the miss is not retried.
+var ErrNoInteraction = errors.New("request not found in cassette")
+
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  string          `json:"query,omitempty"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int             `json:"status_code"`
+	Header     http.Header     `json:"header,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// Recorder sends requests through Transport, or http.DefaultTransport, and
+// keeps every interaction until Save writes them to Path.
+type Recorder struct {
+	Path      string
+	Transport http.RoundTripper
+
+	mutex        sync.Mutex
+	interactions []Interaction
+}
+
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	return &Recorder{Path: path, Transport: transport}
+}
+
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: error reading request body: %w", err)
+	}
+
+	transport := recorder.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := readBody(&res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: error reading response body: %w", err)
+	}
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			Path:   req.URL.EscapedPath(),
+			Query:  recordedQuery(req.URL.RawQuery),
+			Header: recordedHeader(req.Header),
+			Body:   redactBody(requestBody),
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     recordedHeader(res.Header),
+			Body:       redactBody(responseBody),
+		},
+	}
+
+	recorder.mutex.Lock()
+	recorder.interactions = append(recorder.interactions, interaction)
+	recorder.mutex.Unlock()
+
+	return res, nil
+}
+
+// Save writes the interactions recorded so far, replacing the file.
+func (recorder *Recorder) Save() error {
+	recorder.mutex.Lock()
+	cassette := Cassette{Version: Version, Interactions: append([]Interaction(nil), recorder.interactions...)}
+	recorder.mutex.Unlock()
+
+	content, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(recorder.Path), 0o755); err != nil {
+		return fmt.Errorf("cassette: error creating directory: %w", err)
+	}
+
+	if err := os.WriteFile(recorder.Path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: error writing %s: %w", recorder.Path, err)
+	}
+
+	return nil
+}
+
+// Replayer answers requests from a cassette and never touches the network.
+// A request matches an interaction with the same method, path, query and
+// redacted JSON body; identical requests are answered in recorded order.
+// Any other request fails with ErrNoInteraction.
+type Replayer struct {
+	path         string
+	mutex        sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Read parses a cassette file without replaying it.
+func Read(path string) (*Cassette, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: error reading %s: %w", path, err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(content, &cassette); err != nil {
+		return nil, fmt.Errorf("cassette: error parsing %s: %w", path, err)
+	}
+	if cassette.Version != Version {
+		return nil, fmt.Errorf("cassette: %s has version %d, expected %d", path, cassette.Version, Version)
+	}
+
+	return &cassette, nil
+}
+
+func Load(path string) (*Replayer, error) {
+	cassette, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Replayer{path: path, interactions: cassette.Interactions, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+func (replayer *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: error reading request body: %w", err)
+	}
+
+	wanted := Request{
+		Method: req.Method,
+		Path:   req.URL.EscapedPath(),
+		Query:  recordedQuery(req.URL.RawQuery),
+		Body:   redactBody(body),
+	}
+
+	replayer.mutex.Lock()
+	defer replayer.mutex.Unlock()
+
+	for i, interaction := range replayer.interactions {
+		if replayer.used[i] || !matches(interaction.Request, wanted) {
+			continue
+		}
+		replayer.used[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w %s: %s %s?%s body %s (%w)", ErrNoInteraction, replayer.path, wanted.Method, wanted.Path, wanted.Query, wanted.Body, letmeinerr.ErrPermanent)
+}
+
+// Unused lists the interactions no request has matched, so a test can
+// check the code under test made every recorded call.
+func (replayer *Replayer) Unused() []Interaction {
+	replayer.mutex.Lock()
+	defer replayer.mutex.Unlock()
+
+	var unused []Interaction
+	for i, interaction := range replayer.interactions {
+		if !replayer.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+
+	return unused
+}
+
+func matches(recorded, wanted Request) bool {
+	return recorded.Method == wanted.Method &&
+		recorded.Path == wanted.Path &&
+		recorded.Query == wanted.Query &&
+		sameJSON(recorded.Body, wanted.Body)
+}
+
+func sameJSON(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+
+	var first, second any
+	if json.Unmarshal(a, &first) != nil || json.Unmarshal(b, &second) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	canonicalFirst, _ := json.Marshal(first)
+	canonicalSecond, _ := json.Marshal(second)
+
+	return bytes.Equal(canonicalFirst, canonicalSecond)
+}
+
+// readBody reads body fully and replaces it with a fresh reader, so the
+// caller can still send or return it.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	content, err := io.ReadAll(*body)
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(content))
+
+	return content, err
+}
+
+func redactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	return redact.JSON(body)
+}
+
+// recordedHeader keeps the headers worth replaying, redacted. Hop-by-hop
+// and per-run headers such as Date would only make cassettes churn.
+func recordedHeader(header http.Header) http.Header {
+	kept := make(http.Header)
+	for _, name := range []string{"Content-Type", "Authorization", "App-Token", "Idempotency-Key", "Retry-After", "X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset"} {
+		if values := header.Values(name); len(values) > 0 {
+			kept[name] = append([]string(nil), values...)
+		}
+	}
+
+	return redact.Header(kept)
+}
+
+// recordedQuery redacts sensitive query parameters, as redact.URL does, and
+// sorts the rest so equal queries compare equal.
+func recordedQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	return canonicalQuery(strings.TrimPrefix(redact.URL("?"+rawQuery), "?"))
+}
+
+func canonicalQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+
+	return values.Encode()
+}
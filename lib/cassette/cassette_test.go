@@ -0,0 +1,218 @@
+package cassette_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/internal/letmeintest"
+	"github.com/adilsonchacon/goeli/lib/cassette"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+	"github.com/adilsonchacon/goeli/lib/retry"
+)
+
+func newLetmeinServer(t *testing.T, calls *int) *httptest.Server {
+	routes := letmeintest.Routes{
+		"POST /rest/sessions": letmeintest.JSON(http.StatusCreated, `{"token":"a-real-session-token","user":{"email":"user@example.com"}}`),
+		"GET /rest/organizations": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			letmeintest.JSON(http.StatusOK, `{"organizations":[{"id":"1","name":"Acme"}],"page":"`+r.URL.Query().Get("page")+`"}`)(w, r)
+		}),
+	}
+
+	return letmeintest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		routes.ServeHTTP(w, r)
+	}))
+}
+
+func signIn(baseURL string, transport http.RoundTripper, password string) (int, []byte, error) {
+	req := restapi.New(baseURL+"/rest/sessions", http.MethodPost)
+	req.Transport = transport
+	req.AddHeader("App-Token", "a-real-app-token")
+	req.AddBody("email", "user@example.com")
+	req.AddBody("password", password)
+
+	return req.DoRequest()
+}
+
+func listOrganizations(baseURL string, transport http.RoundTripper, query string) (int, []byte, error) {
+	req := restapi.New(baseURL+"/rest/organizations?"+query, http.MethodGet)
+	req.Transport = transport
+
+	return req.DoRequest()
+}
+
+func record(t *testing.T, path string) {
+	t.Helper()
+
+	calls := 0
+	server := newLetmeinServer(t, &calls)
+
+	recorder := cassette.NewRecorder(path, nil)
+	if _, _, err := signIn(server.URL, recorder, "the-real-password"); err != nil {
+		t.Fatalf("signIn expected no errors, got %s", err)
+	}
+	if _, _, err := listOrganizations(server.URL, recorder, "per_page=10&page=2"); err != nil {
+		t.Fatalf("listOrganizations expected no errors, got %s", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save expected no errors, got %s", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Recorder expected to reach the server twice, got %d", calls)
+	}
+}
+
+func TestRecorderRedactsCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "sign_in.json")
+	record(t, path)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile expected no errors, got %s", err)
+	}
+
+	for _, secret := range []string{"the-real-password", "a-real-session-token", "a-real-app-token"} {
+		if strings.Contains(string(content), secret) {
+			t.Errorf("Cassette expected %q to be redacted, got %s", secret, content)
+		} else {
+			t.Logf("Cassette redacts %q", secret)
+		}
+	}
+	if !strings.Contains(string(content), "user@example.com") {
+		t.Errorf("Cassette expected to keep non-sensitive fields, got %s", content)
+	}
+}
+
+func TestReplayerServesRecordedInteractions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sign_in.json")
+	record(t, path)
+
+	replayer, err := cassette.Load(path)
+	if err != nil {
+		t.Fatalf("Load expected no errors, got %s", err)
+	}
+
+	// Nothing listens here: every response must come from the cassette.
+	const offline = "http://letmein.invalid"
+
+	statusCode, body, err := signIn(offline, replayer, "any-test-password")
+	if err == nil && statusCode == http.StatusCreated && strings.Contains(string(body), "user@example.com") {
+		t.Log("Replayer answers sign in from the cassette, ignoring the redacted password")
+	} else {
+		t.Errorf("signIn expected the recorded 201, got %d %s, %v", statusCode, body, err)
+	}
+
+	statusCode, body, err = listOrganizations(offline, replayer, "page=2&per_page=10")
+	if err == nil && statusCode == http.StatusOK && strings.Contains(string(body), "Acme") {
+		t.Log("Replayer matches queries regardless of parameter order")
+	} else {
+		t.Errorf("listOrganizations expected the recorded 200, got %d %s, %v", statusCode, body, err)
+	}
+
+	if unused := replayer.Unused(); len(unused) == 0 {
+		t.Log("Replayer reports every interaction as used")
+	} else {
+		t.Errorf("Unused expected no interactions, got %d", len(unused))
+	}
+}
+
+func TestReplayerFailsOnUnknownRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sign_in.json")
+	record(t, path)
+
+	replayer, err := cassette.Load(path)
+	if err != nil {
+		t.Fatalf("Load expected no errors, got %s", err)
+	}
+
+	const offline = "http://letmein.invalid"
+
+	_, _, err = listOrganizations(offline, replayer, "page=3&per_page=10")
+	if errors.Is(err, cassette.ErrNoInteraction) && strings.Contains(err.Error(), "page=3") {
+		t.Log("Replayer fails loudly on a request with a different query")
+	} else {
+		t.Errorf("listOrganizations expected ErrNoInteraction naming the query, got %v", err)
+	}
+
+	if _, _, err := listOrganizations(offline, replayer, "page=2&per_page=10"); err != nil {
+		t.Fatalf("listOrganizations expected no errors, got %s", err)
+	}
+	_, _, err = listOrganizations(offline, replayer, "page=2&per_page=10")
+	if errors.Is(err, cassette.ErrNoInteraction) {
+		t.Log("Replayer serves each interaction once")
+	} else {
+		t.Errorf("A repeated request expected ErrNoInteraction, got %v", err)
+	}
+
+	if unused := replayer.Unused(); len(unused) == 1 && unused[0].Request.Path == "/rest/sessions" {
+		t.Log("Unused lists the interaction nobody asked for")
+	} else {
+		t.Errorf("Unused expected the sign in interaction, got %+v", unused)
+	}
+}
+
+func TestRecorderRedactsQueryStrings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "query.json")
+
+	calls := 0
+	server := newLetmeinServer(t, &calls)
+
+	recorder := cassette.NewRecorder(path, nil)
+	if _, _, err := listOrganizations(server.URL, recorder, "page=1&access_token=a-real-query-token"); err != nil {
+		t.Fatalf("listOrganizations expected no errors, got %s", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save expected no errors, got %s", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.Contains(string(content), "a-real-query-token") && strings.Contains(string(content), "page=1") {
+		t.Log("Cassette redacts sensitive query parameters")
+	} else {
+		t.Errorf("Cassette expected the query token to be redacted, got %s", content)
+	}
+
+	replayer, err := cassette.Load(path)
+	if err != nil {
+		t.Fatalf("Load expected no errors, got %s", err)
+	}
+
+	if _, _, err := listOrganizations("http://letmein.invalid", replayer, "access_token=any-test-token&page=1"); err == nil {
+		t.Log("Replayer matches a query whatever its token")
+	} else {
+		t.Errorf("listOrganizations expected the recorded answer, got %v", err)
+	}
+}
+
+func TestReplayerMissIsNotRetried(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sign_in.json")
+	record(t, path)
+
+	replayer, err := cassette.Load(path)
+	if err != nil {
+		t.Fatalf("Load expected no errors, got %s", err)
+	}
+
+	attempts := 0
+	req := restapi.New("http://letmein.invalid/rest/organizations?page=9", http.MethodGet)
+	req.Transport = replayer
+	req.Use(retry.Middleware(retry.DefaultPolicy()), func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			attempts++
+			return next(req)
+		}
+	})
+
+	_, _, err = req.DoRequest()
+	if errors.Is(err, cassette.ErrNoInteraction) && attempts == 1 && strings.Contains(err.Error(), "GET /rest/organizations?page=9") {
+		t.Log("a cassette miss names the request and is not retried")
+	} else {
+		t.Errorf("expected one attempt failing with ErrNoInteraction, got %d attempts, %v", attempts, err)
+	}
+}
@@ -0,0 +1,461 @@
+// Package contract bundles an OpenAPI 3 description of the Letmein endpoints
+// goeli calls, and checks requests and responses against it. Use it in tests
+// so a change on either side of the wire fails the build:
+//
+//	validator, _ := contract.New()
+//	config.Middlewares = append(config.Middlewares, validator.Middleware())
+//
+// or, for interactions recorded with the cassette package:
+//
+//	err := validator.ValidateCassette("testdata/sign_in.json")
+package contract
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adilsonchacon/goeli/lib/cassette"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+var (
+	// ErrUndocumented means the document has no operation for the method and
+	// path, or no response for the status code.
+	ErrUndocumented = errors.New("not described by the contract")
+	// ErrViolation means the operation is documented but the request or the
+	// response does not match it.
+	ErrViolation = errors.New("contract violation")
+)
+
+// OpenAPI returns a copy of the bundled document.
+func OpenAPI() []byte {
+	return bytes.Clone(openAPI)
+}
+
+type document struct {
+	Paths      map[string]*pathItem `json:"paths"`
+	Components struct {
+		Schemas         map[string]*Schema         `json:"schemas"`
+		Parameters      map[string]*parameter      `json:"parameters"`
+		Responses       map[string]*response       `json:"responses"`
+		SecuritySchemes map[string]*securityScheme `json:"securitySchemes"`
+	} `json:"components"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Post       *operation   `json:"post"`
+	Put        *operation   `json:"put"`
+	Patch      *operation   `json:"patch"`
+	Delete     *operation   `json:"delete"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Parameters  []*parameter          `json:"parameters"`
+	Security    []map[string][]string `json:"security"`
+	RequestBody *requestBody          `json:"requestBody"`
+	Responses   map[string]*response  `json:"responses"`
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+	In     string `json:"in"`
+	Name   string `json:"name"`
+}
+
+type Validator struct {
+	document  document
+	templates []string
+}
+
+// New returns a Validator for the bundled document.
+func New() (*Validator, error) {
+	return Parse(openAPI)
+}
+
+// Parse returns a Validator for an OpenAPI 3 JSON document, for callers that
+// pin their own copy of the contract.
+func Parse(content []byte) (*Validator, error) {
+	var validator Validator
+	if err := json.Unmarshal(content, &validator.document); err != nil {
+		return nil, fmt.Errorf("contract: error parsing document: %w", err)
+	}
+
+	for template := range validator.document.Paths {
+		validator.templates = append(validator.templates, template)
+	}
+	sort.Strings(validator.templates)
+
+	return &validator, nil
+}
+
+// ValidateRequest checks the path, query, credentials and body of req.
+func (validator *Validator) ValidateRequest(req *restapi.Request) error {
+	item, op, err := validator.operation(req)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	problems = append(problems, validator.validateParameters(item, op, req)...)
+	problems = append(problems, validator.validateSecurity(op, req.Header)...)
+	problems = append(problems, validator.validateRequestBody(op, req.Body)...)
+
+	return violation(req, "request", problems)
+}
+
+// ValidateResponse checks that res has a documented status code for req and
+// a body of the documented shape.
+func (validator *Validator) ValidateResponse(req *restapi.Request, res *restapi.Response) error {
+	_, op, err := validator.operation(req)
+	if err != nil {
+		return err
+	}
+
+	documented := validator.response(op, res.StatusCode)
+	if documented == nil {
+		return fmt.Errorf("%w: status %d for %s %s", ErrUndocumented, res.StatusCode, req.Method, req.Path())
+	}
+
+	var problems []string
+	media := documented.Content["application/json"]
+	switch {
+	case media != nil:
+		problems = validator.validateBody(media.Schema, res.Body)
+	case res.StatusCode == http.StatusNoContent && len(bytes.TrimSpace(res.Body)) > 0:
+		problems = []string{"expected no body"}
+	}
+
+	return violation(req, fmt.Sprintf("response %d", res.StatusCode), problems)
+}
+
+// Middleware validates every request before it is sent and every response
+// before the caller sees it. A mismatch fails the call.
+func (validator *Validator) Middleware() restapi.Middleware {
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			if err := validator.ValidateRequest(req); err != nil {
+				return nil, err
+			}
+
+			res, err := next(req)
+			if err != nil {
+				return res, err
+			}
+
+			if err := validator.ValidateResponse(req, res); err != nil {
+				return nil, err
+			}
+
+			return res, nil
+		}
+	}
+}
+
+// ValidateCassette validates every interaction recorded in a cassette file
+// and returns all the mismatches joined.
+func (validator *Validator) ValidateCassette(path string) error {
+	recorded, err := cassette.Read(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, interaction := range recorded.Interactions {
+		requestURL := interaction.Request.Path
+		if interaction.Request.Query != "" {
+			requestURL += "?" + interaction.Request.Query
+		}
+
+		req := &restapi.Request{
+			Method: interaction.Request.Method,
+			URL:    requestURL,
+			Header: interaction.Request.Header,
+			Body:   interaction.Request.Body,
+		}
+		res := &restapi.Response{
+			StatusCode: interaction.Response.StatusCode,
+			Header:     interaction.Response.Header,
+			Body:       interaction.Response.Body,
+		}
+
+		if err := validator.ValidateRequest(req); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := validator.ValidateResponse(req, res); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// operation finds the documented operation for the request. Templates are
+// matched against the end of the path, so a BaseURL with a path prefix
+// still matches; the template with the most literal segments wins.
+func (validator *Validator) operation(req *restapi.Request) (*pathItem, *operation, error) {
+	segments := strings.Split(strings.Trim(req.Path(), "/"), "/")
+
+	var best *pathItem
+	bestLiterals := -1
+	for _, template := range validator.templates {
+		literals, ok := matchTemplate(strings.Split(strings.Trim(template, "/"), "/"), segments)
+		if ok && literals > bestLiterals {
+			best, bestLiterals = validator.document.Paths[template], literals
+		}
+	}
+
+	var op *operation
+	if best != nil {
+		op = best.method(req.Method)
+	}
+	if op == nil {
+		return nil, nil, fmt.Errorf("%w: %s %s", ErrUndocumented, req.Method, req.Path())
+	}
+
+	return best, op, nil
+}
+
+func matchTemplate(template, segments []string) (int, bool) {
+	if len(template) > len(segments) {
+		return 0, false
+	}
+
+	segments = segments[len(segments)-len(template):]
+	literals := 0
+	for i, part := range template {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+		if part != segments[i] {
+			return 0, false
+		}
+		literals++
+	}
+
+	return literals, true
+}
+
+func (item *pathItem) method(method string) *operation {
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPost:
+		return item.Post
+	case http.MethodPut:
+		return item.Put
+	case http.MethodPatch:
+		return item.Patch
+	case http.MethodDelete:
+		return item.Delete
+	default:
+		return nil
+	}
+}
+
+func (validator *Validator) validateParameters(item *pathItem, op *operation, req *restapi.Request) []string {
+	query := url.Values{}
+	if parsed, err := url.Parse(req.URL); err == nil {
+		query = parsed.Query()
+	}
+
+	var problems []string
+	known := make(map[string]bool)
+	for _, param := range append(append([]*parameter(nil), item.Parameters...), op.Parameters...) {
+		param = validator.parameter(param)
+		if param == nil {
+			continue
+		}
+
+		switch param.In {
+		case "query":
+			known[param.Name] = true
+			values, present := query[param.Name]
+			if !present {
+				if param.Required {
+					problems = append(problems, fmt.Sprintf("missing query parameter %q", param.Name))
+				}
+				continue
+			}
+			for _, value := range values {
+				if param.Schema != nil && param.Schema.Type == "integer" {
+					if _, err := strconv.Atoi(value); err != nil {
+						problems = append(problems, fmt.Sprintf("query parameter %q: expected integer, got %q", param.Name, value))
+					}
+				}
+			}
+		case "header":
+			if param.Required && req.Header.Get(param.Name) == "" {
+				problems = append(problems, fmt.Sprintf("missing header %q", param.Name))
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(query) {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("undocumented query parameter %q", name))
+		}
+	}
+
+	return problems
+}
+
+func (validator *Validator) parameter(param *parameter) *parameter {
+	if param == nil || param.Ref == "" {
+		return param
+	}
+
+	name, _ := strings.CutPrefix(param.Ref, "#/components/parameters/")
+	return validator.document.Components.Parameters[name]
+}
+
+// validateSecurity checks that at least one of the operation's security
+// requirements has its credentials set. Values are not inspected, so
+// redacted recordings still pass.
+func (validator *Validator) validateSecurity(op *operation, header http.Header) []string {
+	if len(op.Security) == 0 {
+		return nil
+	}
+
+	var missing []string
+	for _, requirement := range op.Security {
+		satisfied := true
+		for name := range requirement {
+			headerName := validator.credentialHeader(name)
+			if header.Get(headerName) == "" {
+				satisfied = false
+				missing = append(missing, headerName)
+			}
+		}
+		if satisfied {
+			return nil
+		}
+	}
+
+	return []string{fmt.Sprintf("missing credentials, expected %s", strings.Join(missing, " or "))}
+}
+
+func (validator *Validator) credentialHeader(name string) string {
+	scheme := validator.document.Components.SecuritySchemes[name]
+	if scheme != nil && scheme.Type == "apiKey" && scheme.In == "header" {
+		return scheme.Name
+	}
+
+	return "Authorization"
+}
+
+func (validator *Validator) validateRequestBody(op *operation, body []byte) []string {
+	if op.RequestBody == nil {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return []string{"expected no body"}
+		}
+		return nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return []string{"missing body"}
+		}
+		return nil
+	}
+
+	media := op.RequestBody.Content["application/json"]
+	if media == nil {
+		return []string{"expected no JSON body"}
+	}
+
+	return validator.validateBody(media.Schema, body)
+}
+
+func (validator *Validator) validateBody(schema *Schema, body []byte) []string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return []string{"missing body"}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %s", err)}
+	}
+
+	return validator.validate(schema, value, "$")
+}
+
+// response picks the documented response for statusCode: an exact match,
+// then a range such as "4XX", then "default".
+func (validator *Validator) response(op *operation, statusCode int) *response {
+	code := strconv.Itoa(statusCode)
+	for _, key := range []string{code, code[:1] + "XX", "default"} {
+		documented, ok := op.Responses[key]
+		if !ok {
+			continue
+		}
+		if documented.Ref != "" {
+			name, _ := strings.CutPrefix(documented.Ref, "#/components/responses/")
+			return validator.document.Components.Responses[name]
+		}
+		return documented
+	}
+
+	return nil
+}
+
+func violation(req *restapi.Request, part string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %s %s: %s", ErrViolation, req.Method, req.Path(), part, strings.Join(problems, "; "))
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
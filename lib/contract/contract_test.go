@@ -0,0 +1,298 @@
+package contract_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/internal/letmeintest"
+	"github.com/adilsonchacon/goeli/lib/cassette"
+	"github.com/adilsonchacon/goeli/lib/contract"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+const pagination = `{"count":1,"first":1,"last":1,"next":null,"prev":null,"page":1,"per_page":10,"serie":[1]}`
+
+// letmeinResponses answers like Letmein does today, keyed by method and path.
+var letmeinResponses = map[string]struct {
+	status int
+	body   string
+}{
+	"POST /rest/sessions":                                   {200, `{"data":{"token":"a-session-token"}}`},
+	"GET /rest/sessions":                                    {200, `{"data":{"id":"u1","name":"User","email":"user@example.com","active":true,"language":"en","timezone":"UTC"}}`},
+	"PUT /rest/sessions":                                    {200, `{"data":{"token":"a-refreshed-token"}}`},
+	"DELETE /rest/sessions":                                 {200, `{"data":{"message":"signed out"}}`},
+	"GET /rest/sessions/signed_in":                          {200, `{}`},
+	"PUT /rest/accounts/confirm":                            {202, `{"data":{"message":"confirmed"}}`},
+	"PUT /rest/accounts/unlock":                             {202, `{"data":{"message":"unlocked"}}`},
+	"POST /rest/accounts/password/recover":                  {200, `{"data":{"message":"sent"}}`},
+	"PUT /rest/accounts/password/recover":                   {200, `{"data":{"message":"changed"}}`},
+	"GET /rest/admin/organizations":                         {200, `{"data":[{"id":"o1","name":"Acme","description":null}],"pagination":` + pagination + `}`},
+	"POST /rest/admin/organizations":                        {201, `{"data":{"id":"o1","name":"Acme","description":"Tenant"}}`},
+	"PUT /rest/admin/organizations/o1":                      {201, `{"data":{"id":"o1","name":"Acme","description":"Tenant"}}`},
+	"DELETE /rest/admin/organizations/o1":                   {204, ``},
+	"GET /rest/admin/organizations/o1/admin_users":          {200, `{"data":[{"id":"a1","user":{"name":"Ann","email":"ann@example.com"}}],"pagination":` + pagination + `}`},
+	"POST /rest/admin/organizations/o1/admin_users":         {201, `{"data":{"id":"a1","user":{"name":"Ann","email":"ann@example.com"}}}`},
+	"POST /rest/admin/organizations/o1/apps":                {201, `{"data":{"id":"p1","organization_id":"o1","name":"Portal","description":""}}`},
+	"GET /rest/admin/organizations/o1/apps/p1/users":        {200, `{"data":[{"id":"m1","user":{"name":"Bob","email":"bob@example.com"}}]}`},
+	"POST /rest/admin/organizations/o1/apps/p1/tokens":      {201, `{"data":{"id":"t1","app_id":"p1","token":"a-token","revoked_at":null,"revoked_by":null,"created_at":"2026-01-01T00:00:00Z"}}`},
+	"DELETE /rest/admin/organizations/o1/apps/p1/tokens/t1": {204, ``},
+}
+
+func newLetmeinServer(t *testing.T, overrides map[string]string) *httptest.Server {
+	routes := make(letmeintest.Routes)
+	for key, answer := range letmeinResponses {
+		if body, ok := overrides[key]; ok {
+			answer.body = body
+		}
+		routes[key] = letmeintest.JSON(answer.status, answer.body)
+	}
+
+	return letmeintest.NewServer(t, routes)
+}
+
+func newValidator(t *testing.T) *contract.Validator {
+	t.Helper()
+
+	validator, err := contract.New()
+	if err != nil {
+		t.Fatalf("New expected no errors, got %s", err)
+	}
+
+	return validator
+}
+
+func TestOpenAPIIsAnOpenAPI3Document(t *testing.T) {
+	var document struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(contract.OpenAPI(), &document); err != nil {
+		t.Fatalf("OpenAPI expected valid JSON, got %s", err)
+	}
+
+	if strings.HasPrefix(document.OpenAPI, "3.") && document.Paths["/rest/admin/organizations/{orgID}/apps/{appID}/tokens/{id}"] != nil {
+		t.Log("OpenAPI returns the bundled OpenAPI 3 document")
+	} else {
+		t.Errorf("OpenAPI expected an OpenAPI 3 document describing app tokens, got version %q", document.OpenAPI)
+	}
+}
+
+func TestClientMatchesContract(t *testing.T) {
+	server := newLetmeinServer(t, nil)
+
+	validator := newValidator(t)
+
+	eli := goeli.NewServiceConfig("", server.URL, "an-app-token")
+	eli.Use(validator.Middleware())
+
+	if _, _, err := eli.SignIn("user@example.com", "Secret.123!"); err != nil {
+		t.Errorf("SignIn expected to match the contract, got %s", err)
+	}
+	if _, err := eli.SignedIn("a-session-token"); err != nil {
+		t.Errorf("SignedIn expected to match the contract, got %s", err)
+	}
+	if _, _, err := eli.CurrentUser("a-session-token"); err != nil {
+		t.Errorf("CurrentUser expected to match the contract, got %s", err)
+	}
+	if _, _, err := eli.Refresh("a-session-token"); err != nil {
+		t.Errorf("Refresh expected to match the contract, got %s", err)
+	}
+	if _, err := eli.SignOut("a-refreshed-token"); err != nil {
+		t.Errorf("SignOut expected to match the contract, got %s", err)
+	}
+	if _, err := eli.Confirm("a-confirmation-token"); err != nil {
+		t.Errorf("Confirm expected to match the contract, got %s", err)
+	}
+	if _, err := eli.Unlock("an-unlock-token"); err != nil {
+		t.Errorf("Unlock expected to match the contract, got %s", err)
+	}
+	if _, err := eli.RequestPasswordRecovery("an-app-token", "user@example.com"); err != nil {
+		t.Errorf("RequestPasswordRecovery expected to match the contract, got %s", err)
+	}
+	if _, err := eli.RecoverPassword("a-recovery-token", "Secret.456!", "Secret.456!"); err != nil {
+		t.Errorf("RecoverPassword expected to match the contract, got %s", err)
+	}
+
+	adminConfig := admin.NewConfig(server.URL, "a-session-token")
+	adminConfig.Middlewares = append(adminConfig.Middlewares, validator.Middleware())
+	organizationRepo := organizations.NewRepo(adminConfig)
+	appRepo := apps.NewRepo(adminConfig)
+
+	if _, err := organizationRepo.List(1, 10); err != nil {
+		t.Errorf("organizations.List expected to match the contract, got %s", err)
+	}
+	if _, err := organizationRepo.Create(organizations.Organization{Name: "Acme", Description: "Tenant"}); err != nil {
+		t.Errorf("organizations.Create expected to match the contract, got %s", err)
+	}
+	if _, err := organizationRepo.Update(organizations.Organization{ID: "o1", Name: "Acme"}); err != nil {
+		t.Errorf("organizations.Update expected to match the contract, got %s", err)
+	}
+	if err := organizationRepo.Delete("o1"); err != nil {
+		t.Errorf("organizations.Delete expected to match the contract, got %s", err)
+	}
+	if _, err := organizationRepo.ListAdminUsers("o1", 1, 10); err != nil {
+		t.Errorf("organizations.ListAdminUsers expected to match the contract, got %s", err)
+	}
+	if _, err := organizationRepo.AddAdminUser("o1", "ann@example.com"); err != nil {
+		t.Errorf("organizations.AddAdminUser expected to match the contract, got %s", err)
+	}
+	if _, err := appRepo.Create(apps.App{OrganizationID: "o1", Name: "Portal"}); err != nil {
+		t.Errorf("apps.Create expected to match the contract, got %s", err)
+	}
+	if _, err := appRepo.Users("o1", "p1", 1, 10); err != nil {
+		t.Errorf("apps.Users expected to match the contract, got %s", err)
+	}
+	if _, err := appRepo.CreateToken("o1", "p1"); err != nil {
+		t.Errorf("apps.CreateToken expected to match the contract, got %s", err)
+	}
+	if err := appRepo.RevokeToken("o1", "p1", "t1"); err != nil {
+		t.Errorf("apps.RevokeToken expected to match the contract, got %s", err)
+	}
+}
+
+func TestMiddlewareRejectsResponseDrift(t *testing.T) {
+	server := newLetmeinServer(t, map[string]string{
+		"GET /rest/admin/organizations": `{"data":[{"id":1,"name":"Acme"}],"pagination":{"page":1,"per_page":10,"next":"2"}}`,
+	})
+
+	adminConfig := admin.NewConfig(server.URL, "a-session-token")
+	adminConfig.Middlewares = append(adminConfig.Middlewares, newValidator(t).Middleware())
+	organizationRepo := organizations.NewRepo(adminConfig)
+
+	_, err := organizationRepo.List(1, 10)
+	if errors.Is(err, contract.ErrViolation) &&
+		strings.Contains(err.Error(), "$.data[0].id: expected string, got number") &&
+		strings.Contains(err.Error(), "$.pagination.next: expected integer, got string") {
+		t.Log("Middleware reports every field that drifted from the contract")
+	} else {
+		t.Errorf("List expected ErrViolation naming id and next, got %v", err)
+	}
+}
+
+func TestValidateResponseRejectsUndocumentedStatus(t *testing.T) {
+	validator := newValidator(t)
+	req := &restapi.Request{Method: http.MethodPut, URL: "https://letmein.example.com/rest/accounts/confirm", Body: []byte(`{"token":"t"}`)}
+
+	if err := validator.ValidateResponse(req, &restapi.Response{StatusCode: http.StatusAccepted}); err == nil {
+		t.Log("ValidateResponse accepts 202 for Confirm")
+	} else {
+		t.Errorf("ValidateResponse expected 202 to be documented for Confirm, got %s", err)
+	}
+
+	if err := validator.ValidateResponse(req, &restapi.Response{StatusCode: http.StatusOK}); errors.Is(err, contract.ErrUndocumented) {
+		t.Log("ValidateResponse rejects 200 for Confirm")
+	} else {
+		t.Errorf("ValidateResponse expected ErrUndocumented for 200, got %v", err)
+	}
+
+	req = &restapi.Request{Method: http.MethodDelete, URL: "/rest/admin/organizations/o1/apps/p1"}
+	if err := validator.ValidateResponse(req, &restapi.Response{StatusCode: http.StatusNoContent, Body: []byte(`{}`)}); errors.Is(err, contract.ErrViolation) {
+		t.Log("ValidateResponse rejects a body on 204")
+	} else {
+		t.Errorf("ValidateResponse expected ErrViolation for a 204 body, got %v", err)
+	}
+
+	if err := validator.ValidateResponse(req, &restapi.Response{StatusCode: http.StatusNotFound, Body: []byte(`{"errors":{"detail":"not found"}}`)}); err == nil {
+		t.Log("ValidateResponse accepts Letmein error bodies for 4xx")
+	} else {
+		t.Errorf("ValidateResponse expected a 404 error body to be documented, got %s", err)
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	validator := newValidator(t)
+
+	tests := []struct {
+		name    string
+		req     *restapi.Request
+		wantErr error
+		message string
+	}{
+		{
+			name:    "undocumented path",
+			req:     &restapi.Request{Method: http.MethodGet, URL: "/rest/admin/users"},
+			wantErr: contract.ErrUndocumented,
+		},
+		{
+			name:    "undocumented method",
+			req:     &restapi.Request{Method: http.MethodPatch, URL: "/rest/sessions"},
+			wantErr: contract.ErrUndocumented,
+		},
+		{
+			name:    "missing credentials",
+			req:     &restapi.Request{Method: http.MethodGet, URL: "/rest/admin/organizations", Header: http.Header{}},
+			wantErr: contract.ErrViolation,
+			message: `missing credentials, expected Authorization`,
+		},
+		{
+			name:    "missing required field",
+			req:     &restapi.Request{Method: http.MethodPost, URL: "/rest/sessions", Header: http.Header{"App-Token": {"x"}}, Body: []byte(`{"email":"user@example.com"}`)},
+			wantErr: contract.ErrViolation,
+			message: `$: missing required property "password"`,
+		},
+		{
+			name:    "invalid query parameter",
+			req:     &restapi.Request{Method: http.MethodGet, URL: "/rest/admin/organizations?page=first&sort=name", Header: http.Header{"Authorization": {"Bearer x"}}},
+			wantErr: contract.ErrViolation,
+			message: `query parameter "page": expected integer, got "first"; undocumented query parameter "sort"`,
+		},
+		{
+			name: "prefixed base URL",
+			req:  &restapi.Request{Method: http.MethodDelete, URL: "https://example.com/letmein/rest/admin/organizations/o1/admin_users/a1", Header: http.Header{"Authorization": {"Bearer x"}}},
+		},
+	}
+
+	for _, test := range tests {
+		err := validator.ValidateRequest(test.req)
+		if test.wantErr == nil && err == nil || errors.Is(err, test.wantErr) && strings.Contains(err.Error(), test.message) {
+			t.Logf("ValidateRequest with %s returns %v", test.name, err)
+		} else {
+			t.Errorf("ValidateRequest with %s expected %v containing %q, got %v", test.name, test.wantErr, test.message, err)
+		}
+	}
+}
+
+func TestValidateCassette(t *testing.T) {
+	server := newLetmeinServer(t, map[string]string{
+		"POST /rest/admin/organizations/o1/apps": `{"data":{"id":"p1","organization_id":"o1"}}`,
+	})
+
+	path := filepath.Join(t.TempDir(), "letmein.json")
+	recorder := cassette.NewRecorder(path, nil)
+
+	eli := goeli.NewServiceConfig("", server.URL, "an-app-token")
+	eli.Transport = recorder
+	if _, _, err := eli.SignIn("user@example.com", "Secret.123!"); err != nil {
+		t.Fatalf("SignIn expected no errors, got %s", err)
+	}
+
+	adminConfig := admin.NewConfig(server.URL, "a-session-token")
+	adminConfig.Transport = recorder
+	appRepo := apps.NewRepo(adminConfig)
+	if _, err := appRepo.Create(apps.App{OrganizationID: "o1", Name: "Portal"}); err != nil {
+		t.Fatalf("apps.Create expected no errors, got %s", err)
+	}
+
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save expected no errors, got %s", err)
+	}
+
+	err := newValidator(t).ValidateCassette(path)
+	if errors.Is(err, contract.ErrViolation) &&
+		strings.Contains(err.Error(), "POST /rest/admin/organizations/o1/apps response 201") &&
+		strings.Contains(err.Error(), `missing required property "name"`) &&
+		!strings.Contains(err.Error(), "/rest/sessions") {
+		t.Log("ValidateCassette reports only the interaction that drifted, despite redaction")
+	} else {
+		t.Errorf("ValidateCassette expected a violation for apps.Create only, got %v", err)
+	}
+}
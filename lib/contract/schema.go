@@ -0,0 +1,159 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Schema is the part of an OpenAPI 3.0 schema object the bundled document
+// uses. Keywords outside this subset are ignored.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// validate returns one message per mismatch, each starting with the JSON
+// path of the offending value, such as "$.data.pagination.next".
+func (validator *Validator) validate(schema *Schema, value any, path string) []string {
+	if schema == nil {
+		return nil
+	}
+
+	if schema.Ref != "" {
+		resolved, err := validator.schema(schema.Ref)
+		if err != nil {
+			return []string{fmt.Sprintf("%s: %s", path, err)}
+		}
+		return validator.validate(resolved, value, path)
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: expected %s, got null", path, schema.Type)}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return []string{fmt.Sprintf("%s: %v is not one of %v", path, value, schema.Enum)}
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return []string{mismatch(path, schema.Type, value)}
+		}
+		return validator.validateObject(schema, fields, path)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return []string{mismatch(path, schema.Type, value)}
+		}
+		var problems []string
+		for i, item := range items {
+			problems = append(problems, validator.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{mismatch(path, schema.Type, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{mismatch(path, schema.Type, value)}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return []string{mismatch(path, schema.Type, value)}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return []string{mismatch(path, schema.Type, value)}
+		}
+		if _, err := number.Int64(); err != nil {
+			return []string{fmt.Sprintf("%s: expected integer, got %s", path, number)}
+		}
+	default:
+		return []string{fmt.Sprintf("%s: unsupported schema type %q", path, schema.Type)}
+	}
+
+	return nil
+}
+
+func (validator *Validator) validateObject(schema *Schema, fields map[string]any, path string) []string {
+	var problems []string
+	for _, name := range schema.Required {
+		if _, ok := fields[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, name))
+		}
+	}
+
+	for _, name := range sortedKeys(fields) {
+		property, declared := schema.Properties[name]
+		if !declared {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				problems = append(problems, fmt.Sprintf("%s: unexpected property %q", path, name))
+			}
+			continue
+		}
+		problems = append(problems, validator.validate(property, fields[name], path+"."+name)...)
+	}
+
+	return problems
+}
+
+func (validator *Validator) schema(ref string) (*Schema, error) {
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+
+	schema, ok := validator.document.Components.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+
+	return schema, nil
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mismatch(path, expected string, value any) string {
+	return fmt.Sprintf("%s: expected %s, got %s", path, expected, jsonType(value))
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,195 @@
+package letmeinerr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/adilsonchacon/goeli/lib/redact"
+)
+
+var ErrNetwork = errors.New("network error")
+
+// ErrPermanent marks a transport failure that sending the request again
+// cannot fix, such as a replayed cassette with no recorded answer.
+// Transports wrap it so IsRetryable returns false.
+var ErrPermanent = errors.New("permanent failure")
+
+const (
+	NetworkTimeout           = "timeout"
+	NetworkDNS               = "dns"
+	NetworkConnectionRefused = "connection refused"
+	NetworkCanceled          = "canceled"
+	NetworkOther             = "other"
+)
+
+// NetworkError wraps a transport failure, where no HTTP status was received.
+type NetworkError struct {
+	Operation string
+	Kind      string
+	Err       error
+}
+
+func NewNetworkError(operation string, err error) *NetworkError {
+	return &NetworkError{Operation: operation, Kind: networkKind(err), Err: err}
+}
+
+func (e *NetworkError) Error() string {
+	if e.Operation == "" {
+		return fmt.Sprintf("Letmein Error: network error (%s): %s", e.Kind, e.Err)
+	}
+
+	return fmt.Sprintf("Letmein Error: network error (%s) for %s: %s", e.Kind, e.Operation, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+func (e *NetworkError) Is(target error) bool {
+	return target == ErrNetwork
+}
+
+func (e *NetworkError) Timeout() bool {
+	return e.Kind == NetworkTimeout
+}
+
+func networkKind(err error) string {
+	var dnsError *net.DNSError
+	var netError net.Error
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return NetworkCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return NetworkTimeout
+	case errors.As(err, &dnsError):
+		if dnsError.IsTimeout {
+			return NetworkTimeout
+		}
+		return NetworkDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NetworkConnectionRefused
+	case errors.As(err, &netError) && netError.Timeout():
+		return NetworkTimeout
+	}
+
+	return NetworkOther
+}
+
+// IsRetryableStatus reports whether a response status is worth retrying:
+// rate limiting and temporary gateway or availability failures.
+func IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// IsRetryable reports whether the same request may succeed if sent again.
+// Whether sending it again is safe is a separate question; retry.Retryable
+// also checks the HTTP method and Idempotency-Key.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrPermanent) {
+		return false
+	}
+
+	var networkError *NetworkError
+	if errors.As(err, &networkError) {
+		switch networkError.Kind {
+		case NetworkCanceled:
+			return false
+		case NetworkDNS:
+			var dnsError *net.DNSError
+			return errors.As(err, &dnsError) && dnsError.IsTemporary
+		}
+		return true
+	}
+
+	var letmeinError *LetmeinError
+	if errors.As(err, &letmeinError) {
+		return IsRetryableStatus(letmeinError.StatusCode)
+	}
+
+	var unexpected *UnexpectedResponseError
+	if errors.As(err, &unexpected) {
+		return IsRetryableStatus(unexpected.StatusCode)
+	}
+
+	return false
+}
+
+// IsAuthFailure reports whether Letmein rejected the credentials or session
+// (401) or the caller's permissions (403).
+func IsAuthFailure(err error) bool {
+	return errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrForbidden)
+}
+
+const snippetSize = 256
+
+// UnexpectedResponseError is returned when a response cannot be decoded as a
+// Letmein JSON body: it is too large or it is not JSON, such as an HTML error
+// page from a proxy. Snippet holds the start of the body, redacted: JSON
+// bodies go through redact.JSON, HTML pages are kept for diagnosis and any
+// other body is replaced, since it cannot be inspected for secrets. Error
+// leaves the snippet out, so logs never carry it. An error status
+// still unwraps to its sentinel, so a proxy's HTML 503 is
+// ErrServiceUnavailable and retryable.
+type UnexpectedResponseError struct {
+	Operation   string
+	StatusCode  int
+	ContentType string
+	Reason      string
+	Snippet     string
+}
+
+func NewUnexpectedResponseError(operation string, statusCode int, contentType, reason string, body []byte) *UnexpectedResponseError {
+	return &UnexpectedResponseError{
+		Operation:   operation,
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Reason:      reason,
+		Snippet:     snippet(contentType, body),
+	}
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("Letmein Error: unexpected response for %s (status %d, content-type %q): %s",
+		e.Operation, e.StatusCode, e.ContentType, e.Reason)
+}
+
+// Unwrap returns the sentinel of the response status, or nil when the
+// status itself was not an error.
+func (e *UnexpectedResponseError) Unwrap() error {
+	if e.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	return statusError(e.StatusCode)
+}
+
+func snippet(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(body) == 0:
+		return ""
+	case json.Valid(body):
+		body = redact.JSON(body)
+	case mediaType != "text/html":
+		return redact.Placeholder
+	}
+
+	if len(body) <= snippetSize {
+		return strings.ToValidUTF8(string(body), "")
+	}
+
+	return strings.ToValidUTF8(string(body[:snippetSize]), "") + "..."
+}
@@ -0,0 +1,134 @@
+package letmeinerr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+func TestNewClassifiesStatusCodes(t *testing.T) {
+	cases := map[int]error{
+		http.StatusBadRequest:          letmeinerr.ErrBadRequest,
+		http.StatusUnauthorized:        letmeinerr.ErrUnauthorized,
+		http.StatusForbidden:           letmeinerr.ErrForbidden,
+		http.StatusNotFound:            letmeinerr.ErrNotFound,
+		http.StatusConflict:            letmeinerr.ErrConflict,
+		http.StatusUnprocessableEntity: letmeinerr.ErrUnprocessableEntity,
+		http.StatusTooManyRequests:     letmeinerr.ErrTooManyRequests,
+		http.StatusInternalServerError: letmeinerr.ErrServerError,
+		http.StatusBadGateway:          letmeinerr.ErrServerError,
+		http.StatusServiceUnavailable:  letmeinerr.ErrServiceUnavailable,
+		http.StatusTeapot:              letmeinerr.ErrGeneral,
+	}
+
+	for statusCode, expected := range cases {
+		err := fmt.Errorf("wrapped: %w", letmeinerr.New(statusCode, nil))
+		if errors.Is(err, expected) {
+			t.Logf("status %d is %s", statusCode, expected)
+		} else {
+			t.Errorf("status %d expected %s, got %s", statusCode, expected, err)
+		}
+	}
+}
+
+func TestIsRetryableAndIsAuthFailure(t *testing.T) {
+	cases := []struct {
+		statusCode  int
+		retryable   bool
+		authFailure bool
+	}{
+		{http.StatusUnauthorized, false, true},
+		{http.StatusForbidden, false, true},
+		{http.StatusUnprocessableEntity, false, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusInternalServerError, false, false},
+		{http.StatusServiceUnavailable, true, false},
+		{http.StatusGatewayTimeout, true, false},
+	}
+
+	for _, c := range cases {
+		err := letmeinerr.New(c.statusCode, nil)
+		if letmeinerr.IsRetryable(err) == c.retryable && letmeinerr.IsAuthFailure(err) == c.authFailure {
+			t.Logf("status %d: retryable=%t auth failure=%t", c.statusCode, c.retryable, c.authFailure)
+		} else {
+			t.Errorf("status %d expected retryable=%t auth failure=%t", c.statusCode, c.retryable, c.authFailure)
+		}
+	}
+
+	proxyPage := letmeinerr.NewUnexpectedResponseError("organizations.List", http.StatusServiceUnavailable, "text/html", "body is not JSON", []byte("<html>Service Unavailable</html>"))
+	if errors.Is(fmt.Errorf("wrapped: %w", proxyPage), letmeinerr.ErrServiceUnavailable) && letmeinerr.IsRetryable(proxyPage) {
+		t.Log("a proxy's HTML 503 is ErrServiceUnavailable and retryable")
+	} else {
+		t.Error("a proxy's HTML 503 expected to be ErrServiceUnavailable and retryable")
+	}
+
+	oversized := letmeinerr.NewUnexpectedResponseError("organizations.List", http.StatusOK, "application/json", "body exceeds 1024 bytes", nil)
+	if errors.Unwrap(oversized) == nil && !letmeinerr.IsRetryable(oversized) {
+		t.Log("an oversized 200 carries no status sentinel and is not retryable")
+	} else {
+		t.Error("an oversized 200 expected no status sentinel and not to be retryable")
+	}
+
+	if !letmeinerr.IsRetryable(errors.New("json parser error")) {
+		t.Log("unclassified errors are not retryable")
+	} else {
+		t.Error("unclassified errors should not be retryable")
+	}
+}
+
+func TestTransportErrorsAreNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	baseURL := server.URL
+	server.Close()
+
+	_, _, err := restapi.New(baseURL, http.MethodGet).DoRequest()
+
+	var networkError *letmeinerr.NetworkError
+	if errors.As(err, &networkError) && errors.Is(err, letmeinerr.ErrNetwork) && networkError.Kind == letmeinerr.NetworkConnectionRefused {
+		t.Log("connection refused is a retryable NetworkError")
+	} else {
+		t.Errorf("expected connection refused NetworkError, got %v", err)
+	}
+
+	if letmeinerr.IsRetryable(err) {
+		t.Log("connection refused is retryable")
+	} else {
+		t.Error("connection refused should be retryable")
+	}
+}
+
+func TestTimeoutsAreNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	req := restapi.New(server.URL, http.MethodGet)
+	req.Timeout = 20 * time.Millisecond
+	_, _, err := req.DoRequest()
+
+	var networkError *letmeinerr.NetworkError
+	if errors.As(err, &networkError) && networkError.Timeout() && letmeinerr.IsRetryable(err) {
+		t.Log("client timeouts are retryable NetworkErrors")
+	} else {
+		t.Errorf("expected timeout NetworkError, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req = restapi.New(server.URL, http.MethodGet)
+	req.Context = ctx
+	_, _, err = req.DoRequest()
+	if errors.As(err, &networkError) && networkError.Kind == letmeinerr.NetworkCanceled && !letmeinerr.IsRetryable(err) {
+		t.Log("canceled requests are not retryable")
+	} else {
+		t.Errorf("expected canceled NetworkError, got %v", err)
+	}
+}
@@ -1,9 +1,14 @@
 package letmeinerr
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"time"
+
+	"github.com/adilsonchacon/goeli/entities"
 )
 
 var (
@@ -11,6 +16,11 @@ var (
 	ErrNotFound            = errors.New("not found")
 	ErrForbidden           = errors.New("forbidden")
 	ErrBadRequest          = errors.New("bad request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrConflict            = errors.New("conflict")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrServerError         = errors.New("server error")
+	ErrServiceUnavailable  = errors.New("service unavailable")
 	ErrGeneral             = errors.New("general error")
 )
 
@@ -24,20 +34,135 @@ func (e *LetmeinError) Error() string {
 	return fmt.Sprintf("Letmein Error: %s", e.MainError)
 }
 
+func (e *LetmeinError) Unwrap() error {
+	return e.MainError
+}
+
+// Detail returns the message of a {"errors": {"detail": "..."}} body.
+func (e *LetmeinError) Detail() string {
+	detail, _ := parseErrorBody(e.Body)
+	return detail
+}
+
+// Fields returns the per-field messages of a body such as
+// {"errors": {"name": ["can't be blank"]}}, keyed by field name.
+func (e *LetmeinError) Fields() map[string][]string {
+	_, fields := parseErrorBody(e.Body)
+	return fields
+}
+
+// FieldErrors returns the same messages as Fields, sorted by field name.
+func (e *LetmeinError) FieldErrors() []entities.LetmeinValidationError {
+	fields := e.Fields()
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fieldErrors := make([]entities.LetmeinValidationError, 0, len(names))
+	for _, name := range names {
+		fieldErrors = append(fieldErrors, entities.LetmeinValidationError{Field: name, Message: fields[name]})
+	}
+
+	return fieldErrors
+}
+
+func (e *LetmeinError) HasField(name string) bool {
+	_, ok := e.Fields()[name]
+	return ok
+}
+
 func New(statusCode int, body []byte) *LetmeinError {
-	var err error
+	return &LetmeinError{StatusCode: statusCode, Body: body, MainError: statusError(statusCode)}
+}
+
+// statusError returns the sentinel of an HTTP error status.
+func statusError(statusCode int) error {
 	switch statusCode {
 	case http.StatusUnprocessableEntity:
-		err = ErrUnprocessableEntity
+		return ErrUnprocessableEntity
 	case http.StatusNotFound:
-		err = ErrNotFound
+		return ErrNotFound
 	case http.StatusForbidden:
-		err = ErrForbidden
+		return ErrForbidden
 	case http.StatusBadRequest:
-		err = ErrBadRequest
-	default:
-		err = ErrGeneral
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ErrServiceUnavailable
+	}
+
+	if statusCode >= http.StatusInternalServerError && statusCode <= 599 {
+		return ErrServerError
 	}
 
-	return &LetmeinError{StatusCode: statusCode, Body: body, MainError: err}
+	return ErrGeneral
+}
+
+// RateLimitError is returned when Letmein answers 429. Limit and Remaining
+// are -1 when the response did not include them.
+type RateLimitError struct {
+	LetmeinError
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("Letmein Error: %s, retry after %s", e.MainError, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return &e.LetmeinError
+}
+
+// parseErrorBody accepts "detail" either as a message or as an object of
+// field messages, and every other key of "errors" as a field whose messages
+// are a list or a single string.
+func parseErrorBody(body []byte) (string, map[string][]string) {
+	var envelope struct {
+		Errors map[string]json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", nil
+	}
+
+	detail := ""
+	fields := make(map[string][]string)
+	for key, raw := range envelope.Errors {
+		if key == "detail" {
+			var nested map[string]json.RawMessage
+			if json.Unmarshal(raw, &detail) != nil && json.Unmarshal(raw, &nested) == nil {
+				for field, messages := range nested {
+					addFieldMessages(fields, field, messages)
+				}
+			}
+			continue
+		}
+
+		addFieldMessages(fields, key, raw)
+	}
+
+	return detail, fields
+}
+
+func addFieldMessages(fields map[string][]string, field string, raw json.RawMessage) {
+	var messages []string
+	if err := json.Unmarshal(raw, &messages); err == nil {
+		fields[field] = append(fields[field], messages...)
+		return
+	}
+
+	var message string
+	if err := json.Unmarshal(raw, &message); err == nil {
+		fields[field] = append(fields[field], message)
+	}
 }
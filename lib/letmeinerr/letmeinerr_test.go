@@ -0,0 +1,63 @@
+package letmeinerr_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+)
+
+func TestFieldErrorsFromPerFieldBody(t *testing.T) {
+	body := []byte(`{
+		"errors": {
+			"name": ["can't be blank", "is too short"],
+			"email": ["has already been taken"]
+		}
+	}`)
+
+	err := letmeinerr.New(http.StatusUnprocessableEntity, body)
+
+	fieldErrors := err.FieldErrors()
+	if len(fieldErrors) == 2 && fieldErrors[0].Field == "email" && fieldErrors[1].Field == "name" && len(fieldErrors[1].Message) == 2 {
+		t.Log("FieldErrors returns every field sorted by name")
+	} else {
+		t.Errorf("unexpected field errors %+v", fieldErrors)
+	}
+
+	if err.HasField("email") && !err.HasField("password") {
+		t.Log("HasField reports only fields present in the body")
+	} else {
+		t.Error("HasField returned unexpected results")
+	}
+
+	if err.Fields()["name"][0] == "can't be blank" && err.Detail() == "" {
+		t.Log("Fields maps each field to its messages")
+	} else {
+		t.Errorf("unexpected fields %v, detail %q", err.Fields(), err.Detail())
+	}
+}
+
+func TestFieldErrorsFromDetailBody(t *testing.T) {
+	err := letmeinerr.New(http.StatusForbidden, []byte(`{"errors": {"detail": "Invalid token"}}`))
+	if err.Detail() == "Invalid token" && len(err.FieldErrors()) == 0 {
+		t.Log("Detail returns the message and no field errors")
+	} else {
+		t.Errorf("unexpected detail %q and fields %v", err.Detail(), err.FieldErrors())
+	}
+
+	nested := letmeinerr.New(http.StatusUnprocessableEntity, []byte(`{"errors": {"detail": {"email": ["is invalid"], "name": "can't be blank"}}}`))
+	if nested.HasField("email") && nested.Fields()["name"][0] == "can't be blank" {
+		t.Log("field messages nested under detail are parsed")
+	} else {
+		t.Errorf("unexpected fields %v", nested.Fields())
+	}
+}
+
+func TestFieldErrorsFromInvalidBody(t *testing.T) {
+	err := letmeinerr.New(http.StatusBadGateway, []byte(`<html>Bad Gateway</html>`))
+	if err.Detail() == "" && len(err.FieldErrors()) == 0 && !err.HasField("name") {
+		t.Log("bodies that are not JSON have no detail or field errors")
+	} else {
+		t.Errorf("unexpected parse of invalid body: %q %v", err.Detail(), err.Fields())
+	}
+}
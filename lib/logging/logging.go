@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/adilsonchacon/goeli/lib/redact"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+// Middleware logs every Letmein call once it completes. Headers and bodies go
+// through the redact package before they reach the logger, so passwords,
+// session tokens, app tokens and AppToken.Token values are never logged.
+func Middleware(logger *slog.Logger) restapi.Middleware {
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			start := time.Now()
+			res, err := next(req)
+
+			attrs := []slog.Attr{
+				slog.String("operation", req.Operation),
+				slog.String("method", req.Method),
+				slog.String("path", req.Path()),
+				slog.Int("attempt", req.Attempt),
+				slog.Duration("duration", time.Since(start)),
+				slog.Any("headers", redact.Header(req.Header)),
+			}
+			if len(req.Body) > 0 {
+				attrs = append(attrs, slog.String("request_body", string(redact.JSON(req.Body))))
+			}
+
+			level := slog.LevelInfo
+			message := "letmein request"
+			switch {
+			case err != nil:
+				level = slog.LevelError
+				message = "letmein request failed"
+				attrs = append(attrs, slog.String("error", err.Error()))
+				if res != nil {
+					attrs = append(attrs, slog.Int("status", res.StatusCode))
+				}
+			case res.StatusCode >= http.StatusBadRequest:
+				level = slog.LevelWarn
+				attrs = append(attrs,
+					slog.Int("status", res.StatusCode),
+					slog.String("response_body", string(redact.JSON(res.Body))),
+				)
+			default:
+				attrs = append(attrs, slog.Int("status", res.StatusCode))
+			}
+
+			ctx := req.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			logger.LogAttrs(ctx, level, message, attrs...)
+
+			return res, err
+		}
+	}
+}
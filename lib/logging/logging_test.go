@@ -0,0 +1,189 @@
+package logging_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/logging"
+)
+
+type captureHandler struct {
+	mu       sync.Mutex
+	records  []slog.Record
+	contexts []context.Context
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record.Clone())
+	h.contexts = append(h.contexts, ctx)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *captureHandler) WithGroup(string) slog.Handler { return h }
+
+func (h *captureHandler) dump() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var out strings.Builder
+	for _, record := range h.records {
+		out.WriteString(record.Message)
+		record.Attrs(func(attr slog.Attr) bool {
+			fmt.Fprintf(&out, " %s=%v", attr.Key, attr.Value.Resolve().Any())
+			return true
+		})
+		out.WriteString("\n")
+	}
+
+	return out.String()
+}
+
+func (h *captureHandler) attr(index int, key string) (slog.Value, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var found slog.Value
+	ok := false
+	h.records[index].Attrs(func(attr slog.Attr) bool {
+		if attr.Key == key {
+			found, ok = attr.Value, true
+			return false
+		}
+		return true
+	})
+
+	return found, ok
+}
+
+func TestLoggingRecordsCallDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"id": "123456789", "name": "My Organization", "description": ""}}`))
+	}))
+	defer server.Close()
+
+	handler := &captureHandler{}
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(logging.Middleware(slog.New(handler)))
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	_, err := organizationRepo.Find("123456789")
+	if err != nil {
+		t.Fatalf("Find Organization expected no errors, got %s", err)
+	}
+
+	if len(handler.records) != 1 {
+		t.Fatalf("expected one log record, got %d", len(handler.records))
+	}
+
+	expected := map[string]string{
+		"operation": "organizations.Find",
+		"method":    "GET",
+		"path":      "/rest/admin/organizations/123456789",
+		"status":    "200",
+		"attempt":   "1",
+	}
+	for key, value := range expected {
+		attr, ok := handler.attr(0, key)
+		if ok && attr.String() == value {
+			t.Logf("log record has %s=%s", key, value)
+		} else {
+			t.Errorf("log record expected %s=%s, got %v", key, value, attr)
+		}
+	}
+
+	if _, ok := handler.attr(0, "duration"); ok {
+		t.Log("log record has the call duration")
+	} else {
+		t.Error("log record has no duration")
+	}
+}
+
+type requestIDKey struct{}
+
+func TestLoggingUsesRequestContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"id": "123456789", "name": "My Organization", "description": ""}}`))
+	}))
+	defer server.Close()
+
+	handler := &captureHandler{}
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(logging.Middleware(slog.New(handler)))
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "request-1")
+	organizationRepo := organizations.NewRepo(adminConfig.WithContext(ctx))
+	if _, err := organizationRepo.Find("123456789"); err != nil {
+		t.Fatalf("Find Organization expected no errors, got %s", err)
+	}
+
+	if len(handler.contexts) == 1 && handler.contexts[0].Value(requestIDKey{}) == "request-1" {
+		t.Log("the log record carries the caller's context")
+	} else {
+		t.Errorf("expected the caller's context on the log record, got %v", handler.contexts)
+	}
+}
+
+func TestLoggingNeverLeaksSecrets(t *testing.T) {
+	secrets := []string{
+		"Secret.123!",
+		"New.Secret.456!",
+		"some-app-token",
+		"a-session-token",
+		"a-recovery-token",
+		"an-app-token-value",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"errors": {"detail": "invalid"}, "data": {"id": "1", "token": "an-app-token-value"}}`))
+	}))
+	defer server.Close()
+
+	handler := &captureHandler{}
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+	eli.Use(logging.Middleware(slog.New(handler)))
+
+	_, _, _ = eli.SignIn("test@test.com", "Secret.123!")
+	_, _, _ = eli.CurrentUser("a-session-token")
+	_, _ = eli.RequestPasswordRecovery("some-app-token", "test@test.com")
+	_, _ = eli.RecoverPassword("a-recovery-token", "New.Secret.456!", "New.Secret.456!")
+
+	logged := handler.dump()
+	if len(handler.records) != 4 {
+		t.Errorf("expected four log records, got %d", len(handler.records))
+	}
+
+	for _, secret := range secrets {
+		if strings.Contains(logged, secret) {
+			t.Errorf("secret %q reached the log handler:\n%s", secret, logged)
+		} else {
+			t.Logf("secret %q was redacted", secret)
+		}
+	}
+
+	if strings.Contains(logged, "test@test.com") {
+		t.Log("non-sensitive fields are still logged")
+	} else {
+		t.Errorf("expected email in request body log, got:\n%s", logged)
+	}
+}
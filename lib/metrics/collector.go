@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	requestsMetric = "goeli_letmein_requests_total"
+	errorsMetric   = "goeli_letmein_request_errors_total"
+	durationMetric = "goeli_letmein_request_duration_seconds"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type requestKey struct {
+	operation   string
+	statusClass string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Collector is a dependency-free Hook that keeps request counters and latency
+// histograms and serves them in the Prometheus text exposition format.
+type Collector struct {
+	mu        sync.Mutex
+	buckets   []float64
+	requests  map[requestKey]uint64
+	errors    map[string]uint64
+	durations map[string]*histogram
+}
+
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &Collector{
+		buckets:   sorted,
+		requests:  make(map[requestKey]uint64),
+		errors:    make(map[string]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+func (collector *Collector) Observe(operation, statusClass string, duration time.Duration) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	collector.requests[requestKey{operation: operation, statusClass: statusClass}]++
+	if IsFailure(statusClass) {
+		collector.errors[operation]++
+	}
+
+	observed, ok := collector.durations[operation]
+	if !ok {
+		observed = &histogram{counts: make([]uint64, len(collector.buckets))}
+		collector.durations[operation] = observed
+	}
+
+	seconds := duration.Seconds()
+	for i, upperBound := range collector.buckets {
+		if seconds <= upperBound {
+			observed.counts[i]++
+		}
+	}
+	observed.count++
+	observed.sum += seconds
+}
+
+func (collector *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = collector.WriteTo(w)
+}
+
+// WriteTo renders a snapshot taken under the lock, so a slow scrape never
+// holds up Observe on the request path.
+func (collector *Collector) WriteTo(w io.Writer) (int64, error) {
+	requests, errors, durations := collector.snapshot()
+
+	counter := &countingWriter{writer: bufio.NewWriter(w)}
+
+	fmt.Fprintf(counter, "# HELP %s Letmein calls by operation and status class.\n", requestsMetric)
+	fmt.Fprintf(counter, "# TYPE %s counter\n", requestsMetric)
+	requestKeys := make([]requestKey, 0, len(requests))
+	for key := range requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		if requestKeys[i].operation != requestKeys[j].operation {
+			return requestKeys[i].operation < requestKeys[j].operation
+		}
+		return requestKeys[i].statusClass < requestKeys[j].statusClass
+	})
+	for _, key := range requestKeys {
+		fmt.Fprintf(counter, "%s{operation=\"%s\",status_class=\"%s\"} %d\n",
+			requestsMetric, escapeLabel(key.operation), escapeLabel(key.statusClass), requests[key])
+	}
+
+	fmt.Fprintf(counter, "# HELP %s Letmein calls that failed or returned a 4xx or 5xx status.\n", errorsMetric)
+	fmt.Fprintf(counter, "# TYPE %s counter\n", errorsMetric)
+	for _, operation := range sortedKeys(errors) {
+		fmt.Fprintf(counter, "%s{operation=\"%s\"} %d\n", errorsMetric, escapeLabel(operation), errors[operation])
+	}
+
+	fmt.Fprintf(counter, "# HELP %s Letmein call latency in seconds.\n", durationMetric)
+	fmt.Fprintf(counter, "# TYPE %s histogram\n", durationMetric)
+	for _, operation := range sortedKeys(durations) {
+		observed := durations[operation]
+		label := escapeLabel(operation)
+		for i, upperBound := range collector.buckets {
+			fmt.Fprintf(counter, "%s_bucket{operation=\"%s\",le=\"%s\"} %d\n",
+				durationMetric, label, strconv.FormatFloat(upperBound, 'g', -1, 64), observed.counts[i])
+		}
+		fmt.Fprintf(counter, "%s_bucket{operation=\"%s\",le=\"+Inf\"} %d\n", durationMetric, label, observed.count)
+		fmt.Fprintf(counter, "%s_sum{operation=\"%s\"} %s\n", durationMetric, label, strconv.FormatFloat(observed.sum, 'g', -1, 64))
+		fmt.Fprintf(counter, "%s_count{operation=\"%s\"} %d\n", durationMetric, label, observed.count)
+	}
+
+	if counter.err != nil {
+		return counter.written, counter.err
+	}
+
+	return counter.written, counter.writer.Flush()
+}
+
+func (collector *Collector) snapshot() (map[requestKey]uint64, map[string]uint64, map[string]histogram) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	requests := make(map[requestKey]uint64, len(collector.requests))
+	for key, count := range collector.requests {
+		requests[key] = count
+	}
+
+	errors := make(map[string]uint64, len(collector.errors))
+	for operation, count := range collector.errors {
+		errors[operation] = count
+	}
+
+	durations := make(map[string]histogram, len(collector.durations))
+	for operation, observed := range collector.durations {
+		copied := *observed
+		copied.counts = append([]uint64(nil), observed.counts...)
+		durations[operation] = copied
+	}
+
+	return requests, errors, durations
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+type countingWriter struct {
+	writer  *bufio.Writer
+	written int64
+	err     error
+}
+
+func (counter *countingWriter) Write(p []byte) (int, error) {
+	if counter.err != nil {
+		return 0, counter.err
+	}
+
+	n, err := counter.writer.Write(p)
+	counter.written += int64(n)
+	counter.err = err
+
+	return n, err
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+const StatusClassError = "error"
+
+// Hook receives one observation per completed Letmein call.
+type Hook interface {
+	Observe(operation, statusClass string, duration time.Duration)
+}
+
+func Middleware(hook Hook) restapi.Middleware {
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			start := time.Now()
+			res, err := next(req)
+
+			statusCode := 0
+			if res != nil {
+				statusCode = res.StatusCode
+			}
+			hook.Observe(req.Operation, StatusClass(statusCode, err), time.Since(start))
+
+			return res, err
+		}
+	}
+}
+
+// StatusClass groups a response into "1xx" to "5xx", or "error" when the call
+// failed before a status code was received. A response rejected after its
+// status arrived, such as an HTML error page, keeps the class of its status.
+func StatusClass(statusCode int, err error) string {
+	if statusCode < 100 || statusCode > 599 {
+		return StatusClassError
+	}
+
+	return string(rune('0'+statusCode/100)) + "xx"
+}
+
+func IsFailure(statusClass string) bool {
+	return statusClass == StatusClassError || statusClass == "4xx" || statusClass == "5xx"
+}
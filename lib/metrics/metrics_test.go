@@ -0,0 +1,122 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/lib/metrics"
+)
+
+func TestStatusClass(t *testing.T) {
+	cases := map[int]string{200: "2xx", 204: "2xx", 404: "4xx", 503: "5xx", 0: "error"}
+	for statusCode, expected := range cases {
+		if got := metrics.StatusClass(statusCode, nil); got == expected {
+			t.Logf("status %d is class %s", statusCode, expected)
+		} else {
+			t.Errorf("status %d expected class %s, got %s", statusCode, expected, got)
+		}
+	}
+}
+
+func TestCollectorExposesPrometheusText(t *testing.T) {
+	collector := metrics.NewCollector(0.1, 1)
+	collector.Observe("SignIn", "2xx", 50*time.Millisecond)
+	collector.Observe("SignIn", "4xx", 500*time.Millisecond)
+	collector.Observe("organizations.List", "error", 2*time.Second)
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	exposition := recorder.Body.String()
+
+	expectedLines := []string{
+		"# TYPE goeli_letmein_requests_total counter",
+		`goeli_letmein_requests_total{operation="SignIn",status_class="2xx"} 1`,
+		`goeli_letmein_requests_total{operation="SignIn",status_class="4xx"} 1`,
+		`goeli_letmein_request_errors_total{operation="SignIn"} 1`,
+		`goeli_letmein_request_errors_total{operation="organizations.List"} 1`,
+		"# TYPE goeli_letmein_request_duration_seconds histogram",
+		`goeli_letmein_request_duration_seconds_bucket{operation="SignIn",le="0.1"} 1`,
+		`goeli_letmein_request_duration_seconds_bucket{operation="SignIn",le="1"} 2`,
+		`goeli_letmein_request_duration_seconds_bucket{operation="SignIn",le="+Inf"} 2`,
+		`goeli_letmein_request_duration_seconds_sum{operation="SignIn"} 0.55`,
+		`goeli_letmein_request_duration_seconds_count{operation="organizations.List"} 1`,
+	}
+
+	for _, line := range expectedLines {
+		if strings.Contains(exposition, line+"\n") {
+			t.Logf("exposition has %s", line)
+		} else {
+			t.Errorf("exposition is missing %s, got:\n%s", line, exposition)
+		}
+	}
+
+	if strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Log("exposition is served with the Prometheus text content type")
+	} else {
+		t.Errorf("unexpected content type %s", recorder.Header().Get("Content-Type"))
+	}
+}
+
+func TestMiddlewareObservesAuthCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"errors": {"detail": "maintenance"}}`))
+	}))
+	defer server.Close()
+
+	collector := metrics.NewCollector()
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+	eli.Use(metrics.Middleware(collector))
+
+	_, _, _ = eli.SignIn("test@test.com", "Secret.123!")
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if strings.Contains(recorder.Body.String(), `goeli_letmein_requests_total{operation="SignIn",status_class="5xx"} 1`) {
+		t.Log("middleware reports operation and status class")
+	} else {
+		t.Errorf("middleware observation missing, got:\n%s", recorder.Body.String())
+	}
+}
+
+// stalledWriter blocks every write until the test ends, like a scrape
+// whose client stopped reading.
+type stalledWriter struct {
+	writing chan struct{}
+	done    chan struct{}
+}
+
+func (writer *stalledWriter) Write(p []byte) (int, error) {
+	close(writer.writing)
+	<-writer.done
+	return len(p), nil
+}
+
+func TestSlowScrapeDoesNotBlockObserve(t *testing.T) {
+	collector := metrics.NewCollector()
+	collector.Observe("SignIn", "2xx", 50*time.Millisecond)
+
+	writer := &stalledWriter{writing: make(chan struct{}), done: make(chan struct{})}
+	defer close(writer.done)
+	go collector.WriteTo(writer)
+	<-writer.writing
+
+	observed := make(chan struct{})
+	go func() {
+		collector.Observe("SignIn", "2xx", 50*time.Millisecond)
+		close(observed)
+	}()
+
+	select {
+	case <-observed:
+		t.Log("Observe completes while a scrape is stalled writing")
+	case <-time.After(time.Second):
+		t.Error("Observe expected not to wait for a stalled scrape")
+	}
+}
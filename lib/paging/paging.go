@@ -0,0 +1,67 @@
+package paging
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/adilsonchacon/goeli/entities"
+)
+
+// MaxPages bounds every listing, so a server that never reports the last
+// page cannot keep a caller looping.
+const MaxPages = 1000
+
+var (
+	ErrTooManyPages = errors.New("too many pages")
+	ErrRepeatedPage = errors.New("page repeated")
+)
+
+// Fetch returns one page of items. Pagination is nil for endpoints that do
+// not return it, such as the app users list.
+type Fetch[T any] func(page, perPage int) ([]T, *entities.Pagination, error)
+
+// All collects every page. It stops on an empty page, once the total count
+// is reached, when Pagination has no next page or, without Pagination, on a
+// short page. A page equal to the previous one, as returned by a server that
+// ignores the page parameter, is ErrRepeatedPage.
+func All[T any](perPage int, fetch Fetch[T]) ([]T, error) {
+	var all, previous []T
+
+	for page := 1; ; {
+		if page > MaxPages {
+			return all, fmt.Errorf("%w: stopped after %d", ErrTooManyPages, MaxPages)
+		}
+
+		items, pagination, err := fetch(page, perPage)
+		if err != nil {
+			return all, err
+		}
+		if len(items) == 0 {
+			return all, nil
+		}
+		if page > 1 && reflect.DeepEqual(items, previous) {
+			return all, fmt.Errorf("%w: page %d is the same as the page before it", ErrRepeatedPage, page)
+		}
+		all = append(all, items...)
+		previous = items
+
+		if pagination == nil {
+			if len(items) < perPage {
+				return all, nil
+			}
+			page++
+			continue
+		}
+
+		if pagination.Count > 0 && len(all) >= pagination.Count {
+			return all, nil
+		}
+
+		next := pagination.Next
+		if next == nil || *next <= page {
+			return all, nil
+		}
+		page = *next
+	}
+}
@@ -0,0 +1,89 @@
+package paging_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/entities"
+	"github.com/adilsonchacon/goeli/lib/paging"
+)
+
+func TestAllFollowsNextPage(t *testing.T) {
+	pages := map[int][]string{1: {"a", "b"}, 2: {"c"}}
+	next := 2
+
+	items, err := paging.All(2, func(page, perPage int) ([]string, *entities.Pagination, error) {
+		pagination := &entities.Pagination{Page: page}
+		if page == 1 {
+			pagination.Next = &next
+		}
+		return pages[page], pagination, nil
+	})
+
+	if err == nil && len(items) == 3 {
+		t.Log("All collects every page until there is no next page")
+	} else {
+		t.Errorf("All expected 3 items, got %v, %v", items, err)
+	}
+}
+
+func TestAllStopsOnEmptyPage(t *testing.T) {
+	calls := 0
+
+	items, err := paging.All(2, func(page, perPage int) ([]string, *entities.Pagination, error) {
+		calls++
+		if page == 1 {
+			return []string{"a", "b"}, nil, nil
+		}
+		return nil, nil, nil
+	})
+
+	if err == nil && len(items) == 2 && calls == 2 {
+		t.Log("All stops on an empty page")
+	} else {
+		t.Errorf("All expected 2 items in 2 calls, got %v, %d calls, %v", items, calls, err)
+	}
+}
+
+func TestAllStopsAtTotalCount(t *testing.T) {
+	calls := 0
+
+	items, err := paging.All(1, func(page, perPage int) ([]int, *entities.Pagination, error) {
+		calls++
+		next := page + 1
+		return []int{page}, &entities.Pagination{Page: page, Next: &next, Count: 2}, nil
+	})
+
+	if err == nil && len(items) == 2 && calls == 2 {
+		t.Log("All stops once the total count is reached")
+	} else {
+		t.Errorf("All expected 2 items in 2 calls, got %v, %d calls, %v", items, calls, err)
+	}
+}
+
+func TestAllDetectsRepeatedPage(t *testing.T) {
+	items, err := paging.All(2, func(page, perPage int) ([]string, *entities.Pagination, error) {
+		return []string{"a", "b"}, nil, nil
+	})
+
+	if errors.Is(err, paging.ErrRepeatedPage) && len(items) == 2 {
+		t.Log("All fails when the server ignores the page parameter")
+	} else {
+		t.Errorf("All expected ErrRepeatedPage, got %v, %v", items, err)
+	}
+}
+
+func TestAllCapsPages(t *testing.T) {
+	calls := 0
+
+	_, err := paging.All(1, func(page, perPage int) ([]int, *entities.Pagination, error) {
+		calls++
+		return []int{page}, nil, nil
+	})
+
+	if errors.Is(err, paging.ErrTooManyPages) && calls == paging.MaxPages {
+		t.Logf("All stops after %d pages", paging.MaxPages)
+	} else {
+		t.Errorf("All expected ErrTooManyPages after %d calls, got %d calls, %v", paging.MaxPages, calls, err)
+	}
+}
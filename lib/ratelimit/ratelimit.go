@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+const (
+	LimitHeader      = "X-RateLimit-Limit"
+	RemainingHeader  = "X-RateLimit-Remaining"
+	ResetHeader      = "X-RateLimit-Reset"
+	RetryAfterHeader = "Retry-After"
+)
+
+// Limiter is a token bucket shared by every request of the clients it is
+// registered on. It also holds requests back while Letmein has reported the
+// quota as exhausted.
+type Limiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+// NewLimiter allows perSecond requests on average with bursts of up to burst
+// requests. A perSecond of zero or less disables the bucket, leaving only
+// the pacing driven by response headers.
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+func (limiter *Limiter) Wait(ctx context.Context) error {
+	for {
+		delay := limiter.reserve()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (limiter *Limiter) reserve() time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	if now.Before(limiter.pausedUntil) {
+		return limiter.pausedUntil.Sub(now)
+	}
+
+	if limiter.rate <= 0 {
+		return 0
+	}
+
+	if !limiter.last.IsZero() {
+		limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+		if limiter.tokens > limiter.burst {
+			limiter.tokens = limiter.burst
+		}
+	}
+	limiter.last = now
+
+	if limiter.tokens >= 1 {
+		limiter.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+}
+
+// PauseUntil holds every request back until t.
+func (limiter *Limiter) PauseUntil(t time.Time) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if t.After(limiter.pausedUntil) {
+		limiter.pausedUntil = t
+	}
+}
+
+type Info struct {
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+// Parse reads the rate-limit headers of a response. Limit and Remaining are
+// -1 when missing. X-RateLimit-Reset is accepted both as a Unix timestamp and
+// as seconds from now; Retry-After both as seconds and as an HTTP date.
+func Parse(header http.Header, now time.Time) Info {
+	info := Info{
+		Limit:     headerInt(header, LimitHeader),
+		Remaining: headerInt(header, RemainingHeader),
+	}
+
+	if reset, err := strconv.ParseInt(strings.TrimSpace(header.Get(ResetHeader)), 10, 64); err == nil {
+		if reset > 1_000_000_000 {
+			info.Reset = time.Unix(reset, 0)
+		} else {
+			info.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	retryAfter := strings.TrimSpace(header.Get(RetryAfterHeader))
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		info.RetryAfter = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(retryAfter); err == nil {
+		info.RetryAfter = date.Sub(now)
+	}
+
+	if info.RetryAfter < 0 {
+		info.RetryAfter = 0
+	}
+	if info.RetryAfter == 0 && info.Reset.After(now) {
+		info.RetryAfter = info.Reset.Sub(now)
+	}
+
+	return info
+}
+
+func headerInt(header http.Header, name string) int {
+	value, err := strconv.Atoi(strings.TrimSpace(header.Get(name)))
+	if err != nil {
+		return -1
+	}
+
+	return value
+}
+
+// Middleware waits for the limiter before each request, paces later requests
+// when a response reports the quota as exhausted, and turns 429 responses into
+// a *letmeinerr.RateLimitError.
+func Middleware(limiter *Limiter) restapi.Middleware {
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			ctx := req.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+
+			res, err := next(req)
+			if res == nil {
+				return res, err
+			}
+
+			now := limiter.now()
+			info := Parse(res.Header, now)
+			if info.Remaining == 0 && info.Reset.After(now) {
+				limiter.PauseUntil(info.Reset)
+			}
+
+			if res.StatusCode != http.StatusTooManyRequests {
+				return res, err
+			}
+
+			limiter.PauseUntil(now.Add(info.RetryAfter))
+			if err != nil {
+				// A 429 whose body was rejected, such as a proxy's HTML
+				// page, is still paced but keeps its error.
+				return res, err
+			}
+
+			return res, &letmeinerr.RateLimitError{
+				LetmeinError: *letmeinerr.New(res.StatusCode, res.Body),
+				Limit:        info.Limit,
+				Remaining:    info.Remaining,
+				Reset:        info.Reset,
+				RetryAfter:   info.RetryAfter,
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/ratelimit"
+)
+
+func TestLimiterPacesBursts(t *testing.T) {
+	limiter := ratelimit.NewLimiter(50, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait expected no errors, got %s", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	if elapsed >= 30*time.Millisecond {
+		t.Logf("four requests with burst 2 at 50/s took %s", elapsed)
+	} else {
+		t.Errorf("expected the limiter to delay requests beyond the burst, took %s", elapsed)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "100")
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", "1700000030")
+	header.Set("Retry-After", "12")
+
+	info := ratelimit.Parse(header, now)
+	if info.Limit == 100 && info.Remaining == 0 && info.Reset.Equal(now.Add(30*time.Second)) && info.RetryAfter == 12*time.Second {
+		t.Log("rate-limit headers are parsed")
+	} else {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	header = http.Header{}
+	header.Set("X-RateLimit-Reset", "5")
+	info = ratelimit.Parse(header, now)
+	if info.Remaining == -1 && info.RetryAfter == 5*time.Second {
+		t.Log("relative reset is used as retry delay when Retry-After is missing")
+	} else {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestMiddlewareReturnsRateLimitError(t *testing.T) {
+	jsonResponse := `{
+		"errors": {
+			"detail": "Too Many Requests"
+		}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(jsonResponse))
+	}))
+	defer server.Close()
+
+	limiter := ratelimit.NewLimiter(0, 1)
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(ratelimit.Middleware(limiter))
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	_, err := organizationRepo.List(1, 20)
+
+	var rateLimitError *letmeinerr.RateLimitError
+	if errors.As(err, &rateLimitError) && rateLimitError.RetryAfter == 30*time.Second && rateLimitError.Remaining == 0 {
+		t.Log("429 returns a RateLimitError with the retry delay")
+	} else {
+		t.Errorf("expected RateLimitError, got %v", err)
+	}
+
+	var letmeinError *letmeinerr.LetmeinError
+	if errors.As(err, &letmeinError) && letmeinError.MainError == letmeinerr.ErrTooManyRequests {
+		t.Log("RateLimitError is also a LetmeinError with ErrTooManyRequests")
+	} else {
+		t.Errorf("expected LetmeinError with ErrTooManyRequests, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); errors.Is(err, context.DeadlineExceeded) {
+		t.Log("later requests wait until Retry-After has passed")
+	} else {
+		t.Errorf("expected the limiter to pause after 429, got %v", err)
+	}
+}
+
+func TestMiddlewarePausesWhenQuotaIsExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "60")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": [], "pagination": {}}`))
+	}))
+	defer server.Close()
+
+	limiter := ratelimit.NewLimiter(0, 1)
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(ratelimit.Middleware(limiter))
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	if _, err := organizationRepo.List(1, 20); err != nil {
+		t.Fatalf("List Organizations expected no errors, got %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	pacedRepo := organizations.NewRepo(adminConfig.WithContext(ctx))
+	_, err := pacedRepo.List(1, 20)
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Log("requests wait for the reset once the quota is exhausted")
+	} else {
+		t.Errorf("expected the request to wait for the reset, got %v", err)
+	}
+}
+
+func TestMiddlewarePacesNonJSON429(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("<html><body>Too Many Requests</body></html>"))
+	}))
+	defer server.Close()
+
+	limiter := ratelimit.NewLimiter(0, 1)
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(ratelimit.Middleware(limiter))
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	_, err := organizationRepo.List(1, 20)
+
+	var unexpected *letmeinerr.UnexpectedResponseError
+	if errors.As(err, &unexpected) {
+		t.Log("an HTML 429 keeps its UnexpectedResponseError")
+	} else {
+		t.Errorf("expected UnexpectedResponseError, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); errors.Is(err, context.DeadlineExceeded) {
+		t.Log("an HTML 429 still pauses later requests until Retry-After")
+	} else {
+		t.Errorf("expected the limiter to pause after an HTML 429, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const Placeholder = "[REDACTED]"
+
+var sensitiveHeaders = map[string]bool{
+	"authorization": true,
+	"app-token":     true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"x-csrf-token":  true,
+}
+
+var sensitiveKeyParts = []string{"password", "token", "secret"}
+
+func IsSensitiveHeader(name string) bool {
+	return sensitiveHeaders[strings.ToLower(name)]
+}
+
+// IsSensitiveKey reports whether a JSON or query key holds a credential, such
+// as "password", "password_confirmation", "token" or "session_token".
+func IsSensitiveKey(key string) bool {
+	lowerKey := strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(lowerKey, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Header(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		if IsSensitiveHeader(name) {
+			redacted[name] = []string{Placeholder}
+		} else {
+			redacted[name] = append([]string(nil), values...)
+		}
+	}
+
+	return redacted
+}
+
+// JSON returns body with the value of every sensitive key replaced, at any
+// depth. Bodies that are not valid JSON cannot be inspected, so they are
+// replaced as a whole.
+func JSON(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []byte(`"` + Placeholder + `"`)
+	}
+
+	redacted, err := json.Marshal(Value(value))
+	if err != nil {
+		return []byte(`"` + Placeholder + `"`)
+	}
+
+	return redacted
+}
+
+func Value(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(typed))
+		for key, item := range typed {
+			if IsSensitiveKey(key) && item != nil {
+				redacted[key] = Placeholder
+			} else {
+				redacted[key] = Value(item)
+			}
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(typed))
+		for i, item := range typed {
+			redacted[i] = Value(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+func URL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return Placeholder
+	}
+
+	if parsed.User != nil {
+		parsed.User = url.User(Placeholder)
+	}
+
+	query := parsed.Query()
+	changed := false
+	for key := range query {
+		if IsSensitiveKey(key) {
+			query[key] = []string{Placeholder}
+			changed = true
+		}
+	}
+	if changed {
+		parsed.RawQuery = query.Encode()
+	}
+
+	return parsed.String()
+}
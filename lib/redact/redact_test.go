@@ -0,0 +1,55 @@
+package redact_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/lib/redact"
+)
+
+func TestJSONRedactsNestedSecrets(t *testing.T) {
+	body := []byte(`{"email": "a@b.com", "password": "p1", "data": [{"token": "t1", "revoked_at": null}], "user": {"session_token": "t2"}}`)
+
+	redacted := string(redact.JSON(body))
+	for _, secret := range []string{"p1", "t1", "t2"} {
+		if strings.Contains(redacted, `"`+secret+`"`) {
+			t.Errorf("secret %s was not redacted: %s", secret, redacted)
+		}
+	}
+
+	if strings.Contains(redacted, "a@b.com") && strings.Contains(redacted, `"revoked_at":null`) {
+		t.Log("non-sensitive values are kept")
+	} else {
+		t.Errorf("non-sensitive values were changed: %s", redacted)
+	}
+}
+
+func TestJSONRedactsInvalidBodiesWhole(t *testing.T) {
+	redacted := string(redact.JSON([]byte(`password=secret`)))
+	if !strings.Contains(redacted, "secret") {
+		t.Log("bodies that are not JSON are redacted whole")
+	} else {
+		t.Errorf("invalid JSON body leaked: %s", redacted)
+	}
+}
+
+func TestHeaderRedactsCredentials(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer t1")
+	header.Set("App-Token", "t2")
+	header.Set("X-Correlation-Id", "abc")
+
+	redacted := redact.Header(header)
+	if redacted.Get("Authorization") == redact.Placeholder && redacted.Get("App-Token") == redact.Placeholder {
+		t.Log("credential headers are redacted")
+	} else {
+		t.Errorf("credential headers leaked: %v", redacted)
+	}
+
+	if redacted.Get("X-Correlation-Id") == "abc" && header.Get("Authorization") == "Bearer t1" {
+		t.Log("other headers are kept and the original is untouched")
+	} else {
+		t.Errorf("unexpected header redaction: %v / %v", redacted, header)
+	}
+}
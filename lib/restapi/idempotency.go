@@ -0,0 +1,64 @@
+package restapi
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotent marks the request as a non-idempotent operation that must carry
+// an Idempotency-Key. The key is set once, before the middleware chain runs,
+// so every retry of the same logical operation reuses it. A caller key is
+// sent as given; an empty key generates a new one.
+func (restApi *RESTApi) Idempotent(key string) {
+	restApi.idempotent = true
+	restApi.idempotencyKey = key
+	restApi.idempotencySeed = ""
+}
+
+// DeriveIdempotencyKey is Idempotent with a key built from seed, the
+// operation, method, URL and body, formatted as a version 8 UUID. Sending
+// the same call again with the same seed repeats the key, while other calls
+// made with the seed never share one.
+func (restApi *RESTApi) DeriveIdempotencyKey(seed string) {
+	restApi.idempotent = true
+	restApi.idempotencyKey = ""
+	restApi.idempotencySeed = seed
+}
+
+func (restApi *RESTApi) idempotencyKeyFor(body []byte) string {
+	if restApi.idempotencyKey != "" {
+		return restApi.idempotencyKey
+	}
+	if restApi.idempotencySeed == "" {
+		return NewIdempotencyKey()
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{restApi.idempotencySeed, restApi.Operation, restApi.HttpMethod, restApi.URL} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+
+	return formatUUID([16]byte(hash.Sum(nil)[:16]), 0x80)
+}
+
+// NewIdempotencyKey returns a random RFC 4122 version 4 UUID.
+func NewIdempotencyKey() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+
+	return formatUUID(uuid, 0x40)
+}
+
+// formatUUID sets the version and RFC 4122 variant bits; derived keys use
+// version 8, the RFC 9562 custom version.
+func formatUUID(uuid [16]byte, version byte) string {
+	uuid[6] = (uuid[6] & 0x0f) | version
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
@@ -0,0 +1,52 @@
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Request is the outgoing call as seen by middlewares. Operation names the
+// Letmein call, for example "SignIn" or "organizations.List".
+type Request struct {
+	Operation string
+	Method    string
+	URL       string
+	Header    http.Header
+	Body      []byte
+	Attempt   int
+	Context   context.Context
+}
+
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// RoundTrip sends a request. It returns a Response without Body together
+// with an error when the status and headers arrived but the body was
+// rejected.
+type RoundTrip func(req *Request) (*Response, error)
+
+// Middleware wraps a RoundTrip. It may change the request before calling
+// next, change the response after it, or return without calling next at all.
+type Middleware func(next RoundTrip) RoundTrip
+
+func Chain(final RoundTrip, middlewares ...Middleware) RoundTrip {
+	roundTrip := final
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		roundTrip = middlewares[i](roundTrip)
+	}
+
+	return roundTrip
+}
+
+func (req *Request) Path() string {
+	parsed, err := url.Parse(req.URL)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.EscapedPath()
+}
@@ -0,0 +1,95 @@
+package restapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+func TestMiddlewaresRunInRegistrationOrder(t *testing.T) {
+	var calls []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "server:"+r.Header.Get("x-tenant"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": "original"}`))
+	}))
+	defer server.Close()
+
+	trace := func(name string) restapi.Middleware {
+		return func(next restapi.RoundTrip) restapi.RoundTrip {
+			return func(req *restapi.Request) (*restapi.Response, error) {
+				calls = append(calls, name+":before:"+req.Operation)
+				req.Header.Set("x-tenant", req.Header.Get("x-tenant")+name)
+				res, err := next(req)
+				calls = append(calls, name+":after")
+				return res, err
+			}
+		}
+	}
+
+	rewrite := func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			res, err := next(req)
+			if err == nil {
+				res.Body = []byte(`{"data": "rewritten"}`)
+			}
+			return res, err
+		}
+	}
+
+	req := restapi.New(server.URL, http.MethodGet)
+	req.Operation = "organizations.List"
+	req.Use(trace("a"), trace("b"), rewrite)
+	_, body, err := req.DoRequest()
+	if err != nil {
+		t.Fatalf("DoRequest expected no errors, got %s", err)
+	}
+
+	expected := "a:before:organizations.List,b:before:organizations.List,server:ab,b:after,a:after"
+	if strings.Join(calls, ",") == expected {
+		t.Log("middlewares wrap each other in registration order")
+	} else {
+		t.Errorf("expected calls %s, got %s", expected, strings.Join(calls, ","))
+	}
+
+	if string(body) == `{"data": "rewritten"}` {
+		t.Log("middlewares can modify the response")
+	} else {
+		t.Errorf("expected rewritten body, got %s", body)
+	}
+}
+
+func TestMiddlewareCanShortCircuit(t *testing.T) {
+	requested := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cached := func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			return &restapi.Response{StatusCode: http.StatusNoContent, Header: http.Header{}}, nil
+		}
+	}
+
+	req := restapi.New(server.URL, http.MethodDelete)
+	req.Use(cached)
+	statusCode, _, err := req.DoRequest()
+	if err != nil {
+		t.Fatalf("DoRequest expected no errors, got %s", err)
+	}
+
+	if statusCode == http.StatusNoContent && !requested {
+		t.Log("short-circuiting middleware answers without sending")
+	} else {
+		t.Errorf("expected short-circuit with 204, got %d (requested: %t)", statusCode, requested)
+	}
+}
@@ -0,0 +1,95 @@
+package restapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrEmptyPathParam    = errors.New("empty path parameter")
+	ErrInvalidPathParam  = errors.New("invalid path parameter")
+	ErrPathParamMismatch = errors.New("path parameters do not match template")
+)
+
+// Path fills every {name} placeholder of template, in order, with the
+// matching param escaped as a single path segment. Empty params and the dot
+// segments "." and ".." are rejected so a request can never silently reach a
+// different endpoint.
+func Path(template string, params ...string) (string, error) {
+	var path strings.Builder
+	rest := template
+	index := 0
+
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			path.WriteString(rest)
+			break
+		}
+
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("%w: unclosed placeholder in %q", ErrPathParamMismatch, template)
+		}
+		end += start
+
+		name := rest[start+1 : end]
+		if index >= len(params) {
+			return "", fmt.Errorf("%w: missing value for {%s} in %q", ErrPathParamMismatch, name, template)
+		}
+
+		segment, err := pathSegment(name, params[index])
+		if err != nil {
+			return "", err
+		}
+
+		path.WriteString(rest[:start])
+		path.WriteString(segment)
+		rest = rest[end+1:]
+		index++
+	}
+
+	if index != len(params) {
+		return "", fmt.Errorf("%w: %d values for %d placeholders in %q", ErrPathParamMismatch, len(params), index, template)
+	}
+
+	return path.String(), nil
+}
+
+// BuildURL joins baseURL with the path built from template and params, and
+// appends the encoded query when it is not empty.
+func BuildURL(baseURL, template string, query url.Values, params ...string) (string, error) {
+	path, err := Path(template, params...)
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := strings.TrimRight(baseURL, "/") + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	return requestURL, nil
+}
+
+func PageQuery(page, perPage int) url.Values {
+	return url.Values{
+		"page":    []string{strconv.Itoa(page)},
+		"perPage": []string{strconv.Itoa(perPage)},
+	}
+}
+
+func pathSegment(name, value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("%w: {%s}", ErrEmptyPathParam, name)
+	}
+
+	if value == "." || value == ".." {
+		return "", fmt.Errorf("%w: {%s} cannot be %q", ErrInvalidPathParam, name, value)
+	}
+
+	return url.PathEscape(value), nil
+}
@@ -2,18 +2,39 @@ package restapi
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+)
+
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxBodySize = 1 << 20
 )
 
 type RESTApi struct {
-	URL        string
-	HttpMethod string
-	Headers    map[string]string
-	Body       map[string]string
+	URL         string
+	HttpMethod  string
+	Headers     map[string]string
+	Body        any
+	Operation   string
+	Timeout     time.Duration
+	MaxBodySize int64
+	Middlewares []Middleware
+	Context     context.Context
+	// Transport sends the request. Nil means http.DefaultTransport.
+	Transport http.RoundTripper
+
+	idempotent      bool
+	idempotencyKey  string
+	idempotencySeed string
 }
 
 func New(url string, httpMethod string) *RESTApi {
@@ -35,59 +56,163 @@ func (restApi *RESTApi) AddHeader(key, value string) {
 	restApi.Headers[key] = value
 }
 
-func (restApi *RESTApi) SetBody(body map[string]string) {
-
+// SetBody sets the request body to any value encoding/json can marshal.
+// A nil body sends no payload at all.
+func (restApi *RESTApi) SetBody(body any) {
 	restApi.Body = body
 }
 
-func (restApi *RESTApi) AddBody(key, value string) {
-	if restApi.Body == nil {
-		restApi.Body = make(map[string]string)
+// AddBody adds a single field to a JSON object body. If the current body is
+// not an object built by AddBody or SetBody with a map, it is replaced.
+func (restApi *RESTApi) AddBody(key string, value any) {
+	fields, ok := restApi.Body.(map[string]any)
+	if !ok {
+		fields = make(map[string]any)
+		if current, isStringMap := restApi.Body.(map[string]string); isStringMap {
+			for currentKey, currentValue := range current {
+				fields[currentKey] = currentValue
+			}
+		}
+		restApi.Body = fields
 	}
 
-	restApi.Body[key] = value
+	fields[key] = value
+}
+
+// Use appends middlewares to the chain. The first middleware registered is
+// the outermost one: it sees the request first and the response last.
+func (restApi *RESTApi) Use(middlewares ...Middleware) {
+	restApi.Middlewares = append(restApi.Middlewares, middlewares...)
 }
 
 func (restApi *RESTApi) DoRequest() (int, []byte, error) {
-	jsonBody := []byte(restApi.stringyBody())
-	bodyReader := bytes.NewReader(jsonBody)
+	res, err := restApi.Do()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.StatusCode, res.Body, nil
+}
 
-	req, err := http.NewRequest(restApi.HttpMethod, restApi.URL, bodyReader)
+func (restApi *RESTApi) Do() (*Response, error) {
+	req, err := restApi.newRequest()
 	if err != nil {
-		return 0, nil, fmt.Errorf("could not create request: %s", err)
+		return nil, err
+	}
+
+	return Chain(restApi.send, restApi.Middlewares...)(req)
+}
+
+func (restApi *RESTApi) newRequest() (*Request, error) {
+	jsonBody, err := restApi.encodeBody()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode body: %w", err)
+	}
+
+	ctx := restApi.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	header := make(http.Header)
+	if jsonBody != nil {
+		header.Set("content-type", "application/json")
 	}
-	req.Header.Set("content-type", "application/json")
 	for key, value := range restApi.Headers {
-		req.Header.Set(key, scapeString(value))
+		header.Set(key, value)
+	}
+	if restApi.idempotent {
+		header.Set(IdempotencyKeyHeader, restApi.idempotencyKeyFor(jsonBody))
+	}
+
+	return &Request{
+		Operation: restApi.Operation,
+		Method:    restApi.HttpMethod,
+		URL:       restApi.URL,
+		Header:    header,
+		Body:      jsonBody,
+		Attempt:   1,
+		Context:   ctx,
+	}, nil
+}
+
+func (restApi *RESTApi) send(request *Request) (*Response, error) {
+	var bodyReader io.Reader
+	if request.Body != nil {
+		bodyReader = bytes.NewReader(request.Body)
+	}
+
+	ctx := request.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, request.Method, request.URL, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %s", err)
+	}
+	req.Header = request.Header.Clone()
+
+	timeout := restApi.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
 	}
 
 	client := http.Client{
-		Timeout: 10 * time.Second,
+		Timeout:   timeout,
+		Transport: restApi.Transport,
 	}
 
 	res, err := client.Do(req)
 	if err != nil {
-		return 0, nil, fmt.Errorf("error requesting: %s", err)
+		return nil, fmt.Errorf("error requesting: %w", letmeinerr.NewNetworkError(request.Operation, err))
 	}
 	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	maxBodySize := restApi.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize+1))
 	if err != nil {
-		return 0, nil, fmt.Errorf("body reader error: %s", err)
+		return nil, fmt.Errorf("body reader error: %s", err)
+	}
+
+	// A rejected body still returns the status and headers, so middlewares
+	// can log it, count it and honor its Retry-After.
+	contentType := res.Header.Get("content-type")
+	if int64(len(body)) > maxBodySize {
+		reason := fmt.Sprintf("body exceeds %d bytes", maxBodySize)
+		return &Response{StatusCode: res.StatusCode, Header: res.Header}, letmeinerr.NewUnexpectedResponseError(request.Operation, res.StatusCode, contentType, reason, body)
 	}
 
-	return res.StatusCode, body, nil
+	if len(body) > 0 && !isJSON(contentType, body) {
+		return &Response{StatusCode: res.StatusCode, Header: res.Header}, letmeinerr.NewUnexpectedResponseError(request.Operation, res.StatusCode, contentType, "body is not JSON", body)
+	}
+
+	return &Response{StatusCode: res.StatusCode, Header: res.Header, Body: body}, nil
 }
 
-func (restApi *RESTApi) stringyBody() string {
-	var body []string
-	for key, value := range restApi.Body {
-		body = append(body, fmt.Sprintf("\"%s\": \"%s\"", key, scapeString(value)))
+// isJSON accepts application/json and any +json media type. Without a
+// Content-Type header the body itself must be valid JSON.
+func isJSON(contentType string, body []byte) bool {
+	if contentType == "" {
+		return json.Valid(body)
 	}
 
-	return "{" + strings.Join(body, ", ") + "}"
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
 }
 
-func scapeString(str string) string {
-	return strings.ReplaceAll(str, "\"", "\\\"")
+func (restApi *RESTApi) encodeBody() ([]byte, error) {
+	if restApi.Body == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(restApi.Body)
 }
@@ -0,0 +1,307 @@
+package restapi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+type capturedRequest struct {
+	contentType string
+	body        []byte
+}
+
+func newCaptureServer(captured *capturedRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.contentType = r.Header.Get("content-type")
+		captured.body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestDoRequestWithoutBodySendsNothing(t *testing.T) {
+	captured := &capturedRequest{}
+	server := newCaptureServer(captured)
+	defer server.Close()
+
+	req := restapi.New(server.URL, http.MethodGet)
+	statusCode, _, err := req.DoRequest()
+	if err != nil {
+		t.Errorf("DoRequest expected no errors, got %s", err)
+	}
+
+	if statusCode == http.StatusOK {
+		t.Log("DoRequest returned status code 200")
+	} else {
+		t.Errorf("DoRequest expected status code 200, got %d", statusCode)
+	}
+
+	if len(captured.body) == 0 {
+		t.Log("GET without body sends no payload")
+	} else {
+		t.Errorf("GET without body expected no payload, got %s", captured.body)
+	}
+
+	if captured.contentType == "" {
+		t.Log("GET without body sends no content-type")
+	} else {
+		t.Errorf("GET without body expected no content-type, got %s", captured.contentType)
+	}
+}
+
+func TestDoRequestWithArbitraryJSONBody(t *testing.T) {
+	captured := &capturedRequest{}
+	server := newCaptureServer(captured)
+	defer server.Close()
+
+	type nested struct {
+		Tags   []string `json:"tags"`
+		Active bool     `json:"active"`
+	}
+
+	req := restapi.New(server.URL, http.MethodPost)
+	req.SetBody(map[string]any{
+		"count":  42,
+		"path":   "C:\\goeli\n\"quoted\"",
+		"nested": nested{Tags: []string{"a", "b"}, Active: true},
+	})
+	_, _, err := req.DoRequest()
+	if err != nil {
+		t.Errorf("DoRequest expected no errors, got %s", err)
+	}
+
+	if captured.contentType == "application/json" {
+		t.Log("request with body sends JSON content-type")
+	} else {
+		t.Errorf("request with body expected JSON content-type, got %s", captured.contentType)
+	}
+
+	var received struct {
+		Count  int    `json:"count"`
+		Path   string `json:"path"`
+		Nested nested `json:"nested"`
+	}
+	if err := json.Unmarshal(captured.body, &received); err != nil {
+		t.Fatalf("request body is not valid JSON: %s", err)
+	}
+
+	if received.Count == 42 && received.Path == "C:\\goeli\n\"quoted\"" {
+		t.Log("numbers and escaped strings are sent unchanged")
+	} else {
+		t.Errorf("unexpected scalar values in body: %s", captured.body)
+	}
+
+	if received.Nested.Active && len(received.Nested.Tags) == 2 {
+		t.Log("nested objects and arrays are sent unchanged")
+	} else {
+		t.Errorf("unexpected nested values in body: %s", captured.body)
+	}
+}
+
+func TestAddBodyKeepsStringMapFields(t *testing.T) {
+	captured := &capturedRequest{}
+	server := newCaptureServer(captured)
+	defer server.Close()
+
+	req := restapi.New(server.URL, http.MethodPost)
+	req.SetBody(map[string]string{"name": "My \"Organization\""})
+	req.AddBody("description", "Line 1\nLine 2")
+	_, _, err := req.DoRequest()
+	if err != nil {
+		t.Errorf("DoRequest expected no errors, got %s", err)
+	}
+
+	var received map[string]string
+	if err := json.Unmarshal(captured.body, &received); err != nil {
+		t.Fatalf("request body is not valid JSON: %s", err)
+	}
+
+	if received["name"] == "My \"Organization\"" && received["description"] == "Line 1\nLine 2" {
+		t.Log("AddBody merges fields into a string map body")
+	} else {
+		t.Errorf("unexpected body: %s", captured.body)
+	}
+}
+
+func TestDoRequestWithUnmarshalableBodyFails(t *testing.T) {
+	req := restapi.New("http://127.0.0.1:0", http.MethodPost)
+	req.SetBody(map[string]any{"callback": func() {}})
+
+	_, _, err := req.DoRequest()
+	if err != nil {
+		t.Log("unmarshalable body returns an error before sending")
+	} else {
+		t.Error("unmarshalable body expected an error, got none")
+	}
+}
+
+func TestBuildURLEscapesSegments(t *testing.T) {
+	requestURL, err := restapi.BuildURL("https://letmein.example.com/", "/rest/admin/organizations/{orgID}/admin_users/{id}", restapi.PageQuery(2, 20), "a/b?c", "x y")
+	if err != nil {
+		t.Fatalf("BuildURL expected no errors, got %s", err)
+	}
+
+	expected := "https://letmein.example.com/rest/admin/organizations/a%2Fb%3Fc/admin_users/x%20y?page=2&perPage=20"
+	if requestURL == expected {
+		t.Log("BuildURL escapes every path segment and encodes the query")
+	} else {
+		t.Errorf("BuildURL expected %s, got %s", expected, requestURL)
+	}
+}
+
+func TestPathRejectsUnsafeParams(t *testing.T) {
+	cases := []struct {
+		name     string
+		params   []string
+		expected error
+	}{
+		{"empty", []string{""}, restapi.ErrEmptyPathParam},
+		{"blank", []string{"  "}, restapi.ErrEmptyPathParam},
+		{"dot", []string{"."}, restapi.ErrInvalidPathParam},
+		{"dot dot", []string{".."}, restapi.ErrInvalidPathParam},
+		{"missing", []string{}, restapi.ErrPathParamMismatch},
+		{"extra", []string{"1", "2"}, restapi.ErrPathParamMismatch},
+	}
+
+	for _, c := range cases {
+		_, err := restapi.Path("/rest/admin/organizations/{id}", c.params...)
+		if errors.Is(err, c.expected) {
+			t.Logf("Path with %s param returns %s", c.name, c.expected)
+		} else {
+			t.Errorf("Path with %s param expected %s, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestDoRequestRejectsNonJSONBody(t *testing.T) {
+	page := "<html><body>" + strings.Repeat("Bad Gateway ", 100) + "</body></html>"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	req := restapi.New(server.URL, http.MethodGet)
+	req.Operation = "organizations.List"
+	_, _, err := req.DoRequest()
+
+	var unexpected *letmeinerr.UnexpectedResponseError
+	if errors.As(err, &unexpected) && unexpected.StatusCode == http.StatusBadGateway && unexpected.ContentType == "text/html" {
+		t.Log("HTML body returns UnexpectedResponseError")
+	} else {
+		t.Fatalf("expected UnexpectedResponseError, got %v", err)
+	}
+
+	if strings.HasPrefix(unexpected.Snippet, "<html><body>Bad Gateway") && len(unexpected.Snippet) < len(page) {
+		t.Log("error carries a truncated snippet of the body")
+	} else {
+		t.Errorf("unexpected snippet %q", unexpected.Snippet)
+	}
+}
+
+func TestMiddlewaresSeeStatusOfRejectedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("<html><body>Service Unavailable</body></html>"))
+	}))
+	defer server.Close()
+
+	var seen *restapi.Response
+	req := restapi.New(server.URL, http.MethodGet)
+	req.Use(func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(request *restapi.Request) (*restapi.Response, error) {
+			res, err := next(request)
+			seen = res
+			return res, err
+		}
+	})
+
+	res, err := req.Do()
+	var unexpected *letmeinerr.UnexpectedResponseError
+	if !errors.As(err, &unexpected) {
+		t.Fatalf("expected UnexpectedResponseError, got %v", err)
+	}
+
+	if seen != nil && seen == res && res.StatusCode == http.StatusServiceUnavailable && res.Header.Get("Retry-After") == "30" && len(res.Body) == 0 {
+		t.Log("a rejected body still returns the status and headers to middlewares")
+	} else {
+		t.Errorf("expected the 503 status and Retry-After without body, got %+v", seen)
+	}
+}
+
+func TestDoRequestRejectsOversizedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": "` + strings.Repeat("x", 2048) + `"}`))
+	}))
+	defer server.Close()
+
+	req := restapi.New(server.URL, http.MethodGet)
+	req.MaxBodySize = 1024
+	_, _, err := req.DoRequest()
+
+	var unexpected *letmeinerr.UnexpectedResponseError
+	if errors.As(err, &unexpected) && strings.Contains(unexpected.Reason, "1024") {
+		t.Log("body larger than MaxBodySize returns UnexpectedResponseError")
+	} else {
+		t.Errorf("expected UnexpectedResponseError for oversized body, got %v", err)
+	}
+}
+
+func TestDoRequestAcceptsJSONMediaTypes(t *testing.T) {
+	for _, contentType := range []string{"application/json; charset=utf-8", "application/problem+json", ""} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header()["Content-Type"] = []string{contentType}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data": {}}`))
+		}))
+
+		_, _, err := restapi.New(server.URL, http.MethodGet).DoRequest()
+		if err == nil {
+			t.Logf("content-type %q is accepted", contentType)
+		} else {
+			t.Errorf("content-type %q expected no errors, got %s", contentType, err)
+		}
+		server.Close()
+	}
+}
+
+func TestUnexpectedResponseErrorHidesSecrets(t *testing.T) {
+	for _, body := range []string{`{"data": {"token": "a-session-token"}}`, "token=a-session-token"} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(body))
+		}))
+
+		req := restapi.New(server.URL, http.MethodPost)
+		req.Operation = "SignIn"
+		_, _, err := req.DoRequest()
+		server.Close()
+
+		var unexpected *letmeinerr.UnexpectedResponseError
+		if !errors.As(err, &unexpected) {
+			t.Fatalf("expected UnexpectedResponseError for a text/plain body, got %v", err)
+		}
+
+		if !strings.Contains(err.Error(), "a-session-token") && !strings.Contains(unexpected.Snippet, "a-session-token") {
+			t.Logf("text/plain body %q is redacted from the error", body)
+		} else {
+			t.Errorf("expected the session token to be redacted, got error %q and snippet %q", err, unexpected.Snippet)
+		}
+	}
+}
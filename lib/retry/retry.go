@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/adilsonchacon/goeli/lib/letmeinerr"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+type Policy struct {
+	MaxAttempts int
+	Backoff     func(attempt int) time.Duration
+	RetryIf     func(req *restapi.Request, res *restapi.Response, err error) bool
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 3,
+		Backoff:     ExponentialBackoff(200*time.Millisecond, 5*time.Second),
+		RetryIf:     Retryable,
+	}
+}
+
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+
+		return delay
+	}
+}
+
+// Retryable retries transport errors and temporary server statuses, but only
+// for requests that are safe to send twice: idempotent HTTP methods, or any
+// request carrying an Idempotency-Key.
+func Retryable(req *restapi.Request, res *restapi.Response, err error) bool {
+	if !safeToRepeat(req) {
+		return false
+	}
+
+	if err != nil {
+		return letmeinerr.IsRetryable(err)
+	}
+
+	return letmeinerr.IsRetryableStatus(res.StatusCode)
+}
+
+func safeToRepeat(req *restapi.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return req.Header.Get(restapi.IdempotencyKeyHeader) != ""
+}
+
+// Middleware sends the same request again, with Attempt incremented, while
+// the policy allows it. Middlewares registered after it run once per attempt.
+func Middleware(policy Policy) restapi.Middleware {
+	defaults := DefaultPolicy()
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	if policy.Backoff == nil {
+		policy.Backoff = defaults.Backoff
+	}
+	if policy.RetryIf == nil {
+		policy.RetryIf = defaults.RetryIf
+	}
+
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			ctx := req.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			for {
+				res, err := next(req)
+				if req.Attempt >= policy.MaxAttempts || !policy.RetryIf(req, res, err) {
+					return res, err
+				}
+
+				if waitErr := wait(ctx, policy.Backoff(req.Attempt)); waitErr != nil {
+					return res, err
+				}
+				req.Attempt++
+			}
+		}
+	}
+}
+
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package retry_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+	"github.com/adilsonchacon/goeli/lib/retry"
+)
+
+func fastPolicy() retry.Policy {
+	return retry.Policy{
+		MaxAttempts: 3,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+	}
+}
+
+func TestRetryReusesIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		attempt := len(keys)
+		mu.Unlock()
+
+		if attempt == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data": {"id": "123456789", "name": "My Organization", "description": ""}}`))
+	}))
+	defer server.Close()
+
+	var attempts []int
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(retry.Middleware(fastPolicy()), func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			attempts = append(attempts, req.Attempt)
+			return next(req)
+		}
+	})
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	organization, err := organizationRepo.Create(organizations.Organization{Name: "My Organization"})
+	if err != nil {
+		t.Fatalf("Create Organization expected no errors after retry, got %s", err)
+	}
+
+	if organization.ID == "123456789" && len(keys) == 2 {
+		t.Log("Create Organization succeeds on the second attempt")
+	} else {
+		t.Errorf("expected two attempts, got %d", len(keys))
+	}
+
+	if keys[0] != "" && keys[0] == keys[1] {
+		t.Log("both attempts send the same Idempotency-Key")
+	} else {
+		t.Errorf("expected one key reused across attempts, got %v", keys)
+	}
+
+	if len(attempts) == 2 && attempts[0] == 1 && attempts[1] == 2 {
+		t.Log("inner middlewares see the attempt number")
+	} else {
+		t.Errorf("expected attempts [1 2], got %v", attempts)
+	}
+}
+
+func TestRetrySkipsUnsafeRequests(t *testing.T) {
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req := restapi.New(server.URL, http.MethodPost)
+	req.Use(retry.Middleware(fastPolicy()))
+	statusCode, _, _ := req.DoRequest()
+
+	if calls == 1 && statusCode == http.StatusServiceUnavailable {
+		t.Log("POST without Idempotency-Key is not retried")
+	} else {
+		t.Errorf("expected a single call, got %d", calls)
+	}
+}
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	req := restapi.New(server.URL, http.MethodGet)
+	req.Use(retry.Middleware(fastPolicy()))
+	statusCode, _, _ := req.DoRequest()
+
+	if calls == 3 && statusCode == http.StatusBadGateway {
+		t.Log("GET is retried up to MaxAttempts")
+	} else {
+		t.Errorf("expected three calls, got %d", calls)
+	}
+}
+
+func TestRetryRetriesProxyErrorPages(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		attempt := calls
+		mu.Unlock()
+
+		if attempt < 3 {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<html><body>Service Unavailable</body></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"id": "123456789", "name": "My Organization", "description": ""}}`))
+	}))
+	defer server.Close()
+
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(retry.Middleware(fastPolicy()))
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	if _, err := organizationRepo.Find("123456789"); err == nil && calls == 3 {
+		t.Log("A proxy's HTML 503 page is retried like a Letmein 503")
+	} else {
+		t.Errorf("Find expected to succeed on the third call, got %v after %d calls", err, calls)
+	}
+}
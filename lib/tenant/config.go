@@ -0,0 +1,118 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidConfig = errors.New("invalid tenant config")
+
+// File is the registry configuration, for example:
+//
+//	{
+//	  "tenants": {
+//	    "acme": {
+//	      "base_url": "https://auth.acme.example.com",
+//	      "app_token": "...",
+//	      "hosts": ["acme.example.com"]
+//	    }
+//	  }
+//	}
+type File struct {
+	Tenants map[string]Tenant `json:"tenants"`
+}
+
+// Tenant holds the Letmein settings of one customer. Hosts are the request
+// hosts served for the tenant, matched by ByHost. AdminSessionToken is
+// optional; without it admin calls use the token stored under the tenant ID
+// in Options.TokenStore.
+type Tenant struct {
+	BaseURL           string   `json:"base_url"`
+	AppToken          string   `json:"app_token,omitempty"`
+	ServiceType       string   `json:"service_type,omitempty"`
+	AdminSessionToken string   `json:"admin_session_token,omitempty"`
+	Hosts             []string `json:"hosts,omitempty"`
+}
+
+func (tenant Tenant) equal(other Tenant) bool {
+	return tenant.BaseURL == other.BaseURL &&
+		tenant.AppToken == other.AppToken &&
+		tenant.ServiceType == other.ServiceType &&
+		tenant.AdminSessionToken == other.AdminSessionToken &&
+		slices.Equal(tenant.Hosts, other.Hosts)
+}
+
+func parseFile(content []byte) (*File, error) {
+	var file File
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// validate requires an absolute BaseURL per tenant and each host to belong
+// to a single tenant.
+func (file *File) validate() error {
+	ids := make([]string, 0, len(file.Tenants))
+	for id := range file.Tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	hosts := make(map[string]string)
+	for _, id := range ids {
+		if id == "" {
+			return fmt.Errorf("%w: empty tenant ID", ErrInvalidConfig)
+		}
+
+		tenant := file.Tenants[id]
+		parsed, err := url.Parse(tenant.BaseURL)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("%w: tenant %q needs an absolute base_url, got %q", ErrInvalidConfig, id, tenant.BaseURL)
+		}
+
+		for _, host := range tenant.Hosts {
+			host = normalizeHost(host)
+			if owner, taken := hosts[host]; taken {
+				return fmt.Errorf("%w: host %q belongs to both %q and %q", ErrInvalidConfig, host, owner, id)
+			}
+			hosts[host] = id
+		}
+	}
+
+	return nil
+}
+
+// hostIndex maps each normalized host to its tenant ID.
+func (file *File) hostIndex() map[string]string {
+	index := make(map[string]string)
+	for id, tenant := range file.Tenants {
+		for _, host := range tenant.Hosts {
+			index[normalizeHost(host)] = id
+		}
+	}
+
+	return index
+}
+
+// normalizeHost lowercases host and drops its port, so "Acme.example.com:443"
+// matches "acme.example.com".
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if withoutPort, _, err := net.SplitHostPort(host); err == nil {
+		return withoutPort
+	}
+
+	return strings.Trim(host, "[]")
+}
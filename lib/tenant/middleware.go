@@ -0,0 +1,70 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/adilsonchacon/goeli/internal/httperr"
+)
+
+// Resolver returns the tenant ID of a request, or "" when it cannot tell.
+type Resolver func(r *http.Request) string
+
+type clientKey struct{}
+
+// FromContext returns the tenant client attached by Middleware.
+func FromContext(ctx context.Context) (*Client, bool) {
+	client, ok := ctx.Value(clientKey{}).(*Client)
+	return client, ok
+}
+
+// ByHost resolves the tenant from the request host, using the hosts listed
+// in the config file.
+func (registry *Registry) ByHost(r *http.Request) string {
+	id, _ := registry.TenantForHost(r.Host)
+	return id
+}
+
+// ByHeader resolves the tenant from a request header such as X-Tenant-ID.
+// Only use it behind a proxy that sets or strips the header, since clients
+// can send any value.
+func ByHeader(name string) Resolver {
+	return func(r *http.Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	}
+}
+
+// Middleware attaches the client of the first tenant the resolvers return,
+// tried in order. Requests resolving to no tenant get 400, and requests
+// naming a tenant that is not configured get 404.
+func (registry *Registry) Middleware(resolvers ...Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var tenantID string
+			for _, resolve := range resolvers {
+				if tenantID = resolve(r); tenantID != "" {
+					break
+				}
+			}
+
+			if tenantID == "" {
+				httperr.Write(w, http.StatusBadRequest, "missing tenant")
+				return
+			}
+
+			client, err := registry.Get(tenantID)
+			if errors.Is(err, ErrUnknownTenant) {
+				httperr.Write(w, http.StatusNotFound, "unknown tenant")
+				return
+			}
+			if err != nil {
+				httperr.Write(w, http.StatusInternalServerError, "could not load tenant")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, client)))
+		})
+	}
+}
@@ -0,0 +1,298 @@
+// Package tenant serves many Letmein customers from one binary. A Registry
+// maps tenant IDs to auth clients and admin repos built from a JSON config
+// file, creates them on first use, shares one http.Transport per Letmein
+// host, and picks up edits to the file with Reload or Watch.
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/restapi"
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// Options apply to the clients of every tenant. TokenStore holds admin
+// session tokens keyed by tenant ID. NewTransport builds the transport
+// shared by tenants on the same scheme and host; it defaults to a clone of
+// http.DefaultTransport. OnReload is called after every reload Watch
+// attempts, with nil on success.
+type Options struct {
+	Middlewares  []restapi.Middleware
+	MaxBodySize  int64
+	TokenStore   tokenstore.Store
+	NewTransport func(origin string) http.RoundTripper
+	OnReload     func(err error)
+}
+
+// Client is what a tenant gets: an auth client and admin repos. Use
+// WithContext on Auth and Admin for per-request contexts.
+type Client struct {
+	ID            string
+	Auth          *goeli.Config
+	Admin         *admin.Config
+	Organizations *organizations.OrganizationRepo
+	Apps          *apps.AppRepo
+}
+
+type Registry struct {
+	path    string
+	options Options
+
+	mutex      sync.RWMutex
+	file       *File
+	hosts      map[string]string
+	clients    map[string]*Client
+	transports map[string]http.RoundTripper
+	content    []byte
+	modTime    time.Time
+}
+
+// New loads the config file at path. Clients are only built by Get.
+func New(path string, options Options) (*Registry, error) {
+	if options.NewTransport == nil {
+		options.NewTransport = func(string) http.RoundTripper {
+			return http.DefaultTransport.(*http.Transport).Clone()
+		}
+	}
+
+	registry := &Registry{
+		path:       path,
+		options:    options,
+		clients:    make(map[string]*Client),
+		transports: make(map[string]http.RoundTripper),
+	}
+
+	if err := registry.Reload(); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+// Get returns the client of tenantID, building it on first use.
+func (registry *Registry) Get(tenantID string) (*Client, error) {
+	registry.mutex.RLock()
+	client, ok := registry.clients[tenantID]
+	registry.mutex.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if client, ok := registry.clients[tenantID]; ok {
+		return client, nil
+	}
+
+	tenant, ok := registry.file.Tenants[tenantID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, tenantID)
+	}
+
+	client = registry.newClient(tenantID, tenant)
+	registry.clients[tenantID] = client
+
+	return client, nil
+}
+
+// Tenants returns the configured tenant IDs, sorted.
+func (registry *Registry) Tenants() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	ids := make([]string, 0, len(registry.file.Tenants))
+	for id := range registry.file.Tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// TenantForHost returns the tenant serving host, which may carry a port.
+func (registry *Registry) TenantForHost(host string) (string, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	id, ok := registry.hosts[normalizeHost(host)]
+	return id, ok
+}
+
+// Reload reads the config file again. An invalid file is reported and the
+// current config kept. Clients of tenants whose settings are unchanged
+// survive; the others are rebuilt on their next Get.
+func (registry *Registry) Reload() error {
+	info, err := os.Stat(registry.path)
+	if err != nil {
+		return fmt.Errorf("error reading tenant config %s: %w", registry.path, err)
+	}
+
+	content, err := os.ReadFile(registry.path)
+	if err != nil {
+		return fmt.Errorf("error reading tenant config %s: %w", registry.path, err)
+	}
+
+	file, err := parseFile(content)
+	if err != nil {
+		return fmt.Errorf("error parsing tenant config %s: %w", registry.path, err)
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	for id := range registry.clients {
+		current, kept := file.Tenants[id]
+		if !kept || !current.equal(registry.file.Tenants[id]) {
+			delete(registry.clients, id)
+		}
+	}
+
+	registry.file = file
+	registry.hosts = file.hostIndex()
+	registry.content = content
+	registry.modTime = info.ModTime()
+	registry.dropUnusedTransports()
+
+	return nil
+}
+
+// Watch polls the config file every interval and reloads it when it
+// changes, until ctx is done.
+func (registry *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !registry.changed() {
+			continue
+		}
+
+		err := registry.Reload()
+		if registry.options.OnReload != nil {
+			registry.options.OnReload(err)
+		}
+	}
+}
+
+// changed compares the modification time first and the content only when
+// it moved, so an unchanged file costs one stat per poll, and a touch or an
+// editor's save-in-place without edits does not reload.
+func (registry *Registry) changed() bool {
+	info, err := os.Stat(registry.path)
+	if err != nil {
+		return false
+	}
+
+	registry.mutex.RLock()
+	modTime, content := registry.modTime, registry.content
+	registry.mutex.RUnlock()
+
+	if info.ModTime().Equal(modTime) {
+		return false
+	}
+
+	current, err := os.ReadFile(registry.path)
+	if err != nil {
+		return false
+	}
+	if bytes.Equal(current, content) {
+		registry.mutex.Lock()
+		registry.modTime = info.ModTime()
+		registry.mutex.Unlock()
+		return false
+	}
+
+	return true
+}
+
+func (registry *Registry) newClient(id string, tenant Tenant) *Client {
+	transport := registry.transport(tenant.BaseURL)
+
+	auth := goeli.NewServiceConfig(tenant.ServiceType, tenant.BaseURL, tenant.AppToken)
+	auth.Transport = transport
+	auth.MaxBodySize = registry.options.MaxBodySize
+	auth.Use(registry.options.Middlewares...)
+
+	adminConfig := admin.NewConfig(tenant.BaseURL, tenant.AdminSessionToken)
+	adminConfig.Transport = transport
+	adminConfig.MaxBodySize = registry.options.MaxBodySize
+	adminConfig.TokenStore = registry.options.TokenStore
+	adminConfig.TokenKey = id
+	adminConfig.Use(registry.options.Middlewares...)
+
+	organizationRepo := organizations.NewRepo(adminConfig)
+	appRepo := apps.NewRepo(adminConfig)
+
+	return &Client{
+		ID:            id,
+		Auth:          auth,
+		Admin:         adminConfig,
+		Organizations: &organizationRepo,
+		Apps:          &appRepo,
+	}
+}
+
+// transport returns the transport of baseURL's origin, so tenants on the
+// same Letmein host share connections. Callers hold the write lock.
+func (registry *Registry) transport(baseURL string) http.RoundTripper {
+	key := origin(baseURL)
+	if transport, ok := registry.transports[key]; ok {
+		return transport
+	}
+
+	transport := registry.options.NewTransport(key)
+	registry.transports[key] = transport
+
+	return transport
+}
+
+// dropUnusedTransports closes the idle connections of transports no tenant
+// points at anymore. Callers hold the write lock.
+func (registry *Registry) dropUnusedTransports() {
+	used := make(map[string]bool)
+	for _, tenant := range registry.file.Tenants {
+		used[origin(tenant.BaseURL)] = true
+	}
+
+	for key, transport := range registry.transports {
+		if used[key] {
+			continue
+		}
+		if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+		delete(registry.transports, key)
+	}
+}
+
+func origin(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+
+	return parsed.Scheme + "://" + strings.ToLower(parsed.Host)
+}
@@ -0,0 +1,272 @@
+package tenant_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli/internal/letmeintest"
+	"github.com/adilsonchacon/goeli/lib/tenant"
+)
+
+func writeConfig(t *testing.T, path string, file tenant.File) {
+	t.Helper()
+
+	content, err := json.Marshal(file)
+	if err != nil {
+		t.Fatalf("Marshal expected no errors, got %s", err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("WriteFile expected no errors, got %s", err)
+	}
+}
+
+func newLetmeinServer(t *testing.T, appTokens *[]string) *httptest.Server {
+	session := letmeintest.JSON(http.StatusOK, `{"data":{"token":"a-session-token"}}`)
+
+	return letmeintest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*appTokens = append(*appTokens, r.Header.Get("App-Token"))
+		session(w, r)
+	}))
+}
+
+type countingTransports struct {
+	origins []string
+}
+
+func (counting *countingTransports) new(origin string) http.RoundTripper {
+	counting.origins = append(counting.origins, origin)
+	return http.DefaultTransport.(*http.Transport).Clone()
+}
+
+func TestRegistryBuildsClientsLazilyAndSharesTransports(t *testing.T) {
+	var appTokens []string
+	server := newLetmeinServer(t, &appTokens)
+
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	writeConfig(t, path, tenant.File{Tenants: map[string]tenant.Tenant{
+		"acme":    {BaseURL: server.URL, AppToken: "acme-app-token"},
+		"globex":  {BaseURL: server.URL + "/letmein", AppToken: "globex-app-token"},
+		"initech": {BaseURL: "https://letmein.initech.example.com", AppToken: "initech-app-token"},
+	}})
+
+	transports := &countingTransports{}
+	registry, err := tenant.New(path, tenant.Options{NewTransport: transports.new})
+	if err != nil {
+		t.Fatalf("New expected no errors, got %s", err)
+	}
+
+	if len(transports.origins) == 0 {
+		t.Log("New builds no client before Get")
+	} else {
+		t.Errorf("New expected no transports yet, got %v", transports.origins)
+	}
+
+	acme, err := registry.Get("acme")
+	if err != nil {
+		t.Fatalf("Get expected no errors, got %s", err)
+	}
+	globex, _ := registry.Get("globex")
+	again, _ := registry.Get("acme")
+
+	if again == acme {
+		t.Log("Get returns the same client for a tenant")
+	} else {
+		t.Error("Get expected to reuse the acme client")
+	}
+
+	if acme.Auth.Transport == globex.Auth.Transport && acme.Admin.Transport == acme.Auth.Transport && len(transports.origins) == 1 {
+		t.Log("Tenants on the same Letmein host share one transport")
+	} else {
+		t.Errorf("Tenants on one host expected one shared transport, built %v", transports.origins)
+	}
+
+	acme.Auth.SignIn("user@acme.example.com", "Secret.123!")
+	globex.Auth.SignIn("user@globex.example.com", "Secret.123!")
+	if strings.Join(appTokens, ",") == "acme-app-token,globex-app-token" {
+		t.Log("Each tenant signs in with its own app token")
+	} else {
+		t.Errorf("SignIn expected each tenant's app token, got %v", appTokens)
+	}
+
+	if _, err := registry.Get("umbrella"); errors.Is(err, tenant.ErrUnknownTenant) {
+		t.Log("Get of an unconfigured tenant returns ErrUnknownTenant")
+	} else {
+		t.Errorf("Get expected ErrUnknownTenant, got %v", err)
+	}
+
+	if ids := registry.Tenants(); strings.Join(ids, ",") == "acme,globex,initech" {
+		t.Log("Tenants lists the configured tenants")
+	} else {
+		t.Errorf("Tenants expected acme,globex,initech, got %v", ids)
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		file tenant.File
+	}{
+		{"a relative base URL", tenant.File{Tenants: map[string]tenant.Tenant{"acme": {BaseURL: "letmein.acme.example.com"}}}},
+		{"a host served twice", tenant.File{Tenants: map[string]tenant.Tenant{
+			"acme":   {BaseURL: "https://a.example.com", Hosts: []string{"shop.example.com"}},
+			"globex": {BaseURL: "https://b.example.com", Hosts: []string{"Shop.example.com:443"}},
+		}}},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "tenants.json")
+		writeConfig(t, path, test.file)
+
+		if _, err := tenant.New(path, tenant.Options{}); errors.Is(err, tenant.ErrInvalidConfig) {
+			t.Logf("New rejects %s", test.name)
+		} else {
+			t.Errorf("New with %s expected ErrInvalidConfig, got %v", test.name, err)
+		}
+	}
+}
+
+func TestReloadRebuildsOnlyChangedTenants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	writeConfig(t, path, tenant.File{Tenants: map[string]tenant.Tenant{
+		"acme":   {BaseURL: "https://letmein.acme.example.com", AppToken: "old-token"},
+		"globex": {BaseURL: "https://letmein.globex.example.com", AppToken: "globex-token"},
+	}})
+
+	registry, err := tenant.New(path, tenant.Options{})
+	if err != nil {
+		t.Fatalf("New expected no errors, got %s", err)
+	}
+
+	acme, _ := registry.Get("acme")
+	globex, _ := registry.Get("globex")
+
+	writeConfig(t, path, tenant.File{Tenants: map[string]tenant.Tenant{
+		"acme":   {BaseURL: "https://letmein.acme.example.com", AppToken: "new-token"},
+		"globex": {BaseURL: "https://letmein.globex.example.com", AppToken: "globex-token"},
+	}})
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload expected no errors, got %s", err)
+	}
+
+	reloadedAcme, _ := registry.Get("acme")
+	reloadedGlobex, _ := registry.Get("globex")
+	if reloadedAcme != acme && reloadedAcme.Auth.AppToken == "new-token" && reloadedGlobex == globex {
+		t.Log("Reload rebuilds the changed tenant and keeps the others")
+	} else {
+		t.Errorf("Reload expected a new acme client with new-token and the same globex client, got %q", reloadedAcme.Auth.AppToken)
+	}
+
+	os.WriteFile(path, []byte(`{"tenants":`), 0o600)
+	if err := registry.Reload(); errors.Is(err, tenant.ErrInvalidConfig) {
+		t.Log("Reload of a broken file returns ErrInvalidConfig")
+	} else {
+		t.Errorf("Reload expected ErrInvalidConfig, got %v", err)
+	}
+
+	if client, err := registry.Get("acme"); err == nil && client == reloadedAcme {
+		t.Log("A failed reload keeps the current config")
+	} else {
+		t.Errorf("Get after a failed reload expected the current acme client, got %v", err)
+	}
+}
+
+func TestWatchReloadsWhenTheFileChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	writeConfig(t, path, tenant.File{Tenants: map[string]tenant.Tenant{
+		"acme": {BaseURL: "https://letmein.acme.example.com"},
+	}})
+
+	reloads := make(chan error, 1)
+	registry, err := tenant.New(path, tenant.Options{OnReload: func(err error) { reloads <- err }})
+	if err != nil {
+		t.Fatalf("New expected no errors, got %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Watch(ctx, 10*time.Millisecond)
+
+	writeConfig(t, path, tenant.File{Tenants: map[string]tenant.Tenant{
+		"acme":   {BaseURL: "https://letmein.acme.example.com"},
+		"globex": {BaseURL: "https://letmein.globex.example.com"},
+	}})
+	// Coarse file systems may keep the same mtime within a second.
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+
+	select {
+	case err := <-reloads:
+		if err != nil {
+			t.Fatalf("Watch expected a successful reload, got %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch expected to reload the changed file")
+	}
+
+	if _, err := registry.Get("globex"); err == nil {
+		t.Log("Watch picks up tenants added to the file")
+	} else {
+		t.Errorf("Get after Watch expected globex, got %s", err)
+	}
+}
+
+func TestMiddlewareResolvesTenants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	writeConfig(t, path, tenant.File{Tenants: map[string]tenant.Tenant{
+		"acme":   {BaseURL: "https://letmein.acme.example.com", Hosts: []string{"acme.example.com"}},
+		"globex": {BaseURL: "https://letmein.globex.example.com"},
+	}})
+
+	registry, err := tenant.New(path, tenant.Options{})
+	if err != nil {
+		t.Fatalf("New expected no errors, got %s", err)
+	}
+
+	handler := registry.Middleware(registry.ByHost, tenant.ByHeader("X-Tenant-ID"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, ok := tenant.FromContext(r.Context())
+		if !ok {
+			t.Error("FromContext expected the tenant client")
+			return
+		}
+		w.Write([]byte(client.ID))
+	}))
+
+	tests := []struct {
+		name   string
+		host   string
+		header string
+		status int
+		tenant string
+	}{
+		{"a configured host", "ACME.example.com:8443", "", http.StatusOK, "acme"},
+		{"the host before the header", "acme.example.com", "globex", http.StatusOK, "acme"},
+		{"the tenant header", "api.example.com", "globex", http.StatusOK, "globex"},
+		{"no tenant", "api.example.com", "", http.StatusBadRequest, ""},
+		{"an unknown tenant", "api.example.com", "umbrella", http.StatusNotFound, ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = test.host
+		if test.header != "" {
+			req.Header.Set("X-Tenant-ID", test.header)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Code == test.status && (test.tenant == "" || recorder.Body.String() == test.tenant) {
+			t.Logf("Middleware answers %d for %s", test.status, test.name)
+		} else {
+			t.Errorf("Middleware with %s expected %d %q, got %d %q", test.name, test.status, test.tenant, recorder.Code, recorder.Body.String())
+		}
+	}
+}
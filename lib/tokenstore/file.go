@@ -0,0 +1,166 @@
+package tokenstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File is a Store backed by a single JSON file holding every token. Writes
+// go to a temporary file that is renamed over the original, so readers never
+// see a partial file, and the file is created with 0600 permissions.
+//
+// Every operation holds a lock on a sibling ".lock" file, flock on unix and
+// LockFileEx on Windows, so several processes can share the same file. On
+// other platforms every operation fails rather than run unlocked.
+type File struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (store *File) Path() string {
+	return store.path
+}
+
+func (store *File) Get(key string) (string, error) {
+	var token string
+	err := store.withLock(false, func() error {
+		tokens, err := store.read()
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		if token, ok = tokens[key]; !ok {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+
+	return token, err
+}
+
+func (store *File) Put(key, token string) error {
+	return store.update(func(tokens map[string]string) bool {
+		tokens[key] = token
+		return true
+	})
+}
+
+func (store *File) Delete(key string) error {
+	return store.update(func(tokens map[string]string) bool {
+		if _, ok := tokens[key]; !ok {
+			return false
+		}
+
+		delete(tokens, key)
+		return true
+	})
+}
+
+// update reads the tokens, lets change modify them and writes them back if
+// change reports a modification, all under one exclusive lock.
+func (store *File) update(change func(tokens map[string]string) bool) error {
+	return store.withLock(true, func() error {
+		tokens, err := store.read()
+		if err != nil {
+			return err
+		}
+
+		if !change(tokens) {
+			return nil
+		}
+
+		return store.write(tokens)
+	})
+}
+
+func (store *File) withLock(exclusive bool, fn func() error) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(store.path), 0o700); err != nil {
+		return fmt.Errorf("error creating token store directory: %w", err)
+	}
+
+	lockFile, err := os.OpenFile(store.path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening token store lock: %w", err)
+	}
+	defer lockFile.Close()
+
+	if err := lock(lockFile, exclusive); err != nil {
+		return fmt.Errorf("error locking token store: %w", err)
+	}
+	defer unlock(lockFile)
+
+	return fn()
+}
+
+func (store *File) read() (map[string]string, error) {
+	tokens := make(map[string]string)
+
+	content, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading token store %s: %w", store.path, err)
+	}
+
+	if len(content) == 0 {
+		return tokens, nil
+	}
+
+	if err := json.Unmarshal(content, &tokens); err != nil {
+		return nil, fmt.Errorf("error parsing token store %s: %w", store.path, err)
+	}
+
+	return tokens, nil
+}
+
+func (store *File) write(tokens map[string]string) error {
+	content, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(store.path), filepath.Base(store.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating token store temp file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if err := temp.Chmod(0o600); err != nil {
+		temp.Close()
+		return fmt.Errorf("error setting token store permissions: %w", err)
+	}
+
+	if _, err := temp.Write(append(content, '\n')); err != nil {
+		temp.Close()
+		return fmt.Errorf("error writing token store: %w", err)
+	}
+
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("error syncing token store: %w", err)
+	}
+
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error closing token store: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), store.path); err != nil {
+		return fmt.Errorf("error replacing token store %s: %w", store.path, err)
+	}
+
+	return nil
+}
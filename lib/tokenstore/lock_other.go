@@ -0,0 +1,20 @@
+//go:build !unix && !windows
+
+package tokenstore
+
+import (
+	"errors"
+	"os"
+)
+
+// Without a file lock several processes would overwrite each other's
+// tokens, so File refuses to work instead of sharing the file unlocked.
+var errLockUnsupported = errors.New("file locking is not supported on this platform")
+
+func lock(file *os.File, exclusive bool) error {
+	return errLockUnsupported
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
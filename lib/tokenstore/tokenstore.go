@@ -0,0 +1,61 @@
+// Package tokenstore persists Letmein session tokens so callers do not have
+// to keep the token returned by SignIn or Refresh themselves.
+package tokenstore
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrNotFound = errors.New("session token not found")
+
+// Store keeps one session token per key. A key is whatever identifies the
+// session for the caller, usually a profile name or the user's email.
+// Get returns ErrNotFound when key has no token. Delete of a missing key is
+// not an error.
+type Store interface {
+	Get(key string) (string, error)
+	Put(key, token string) error
+	Delete(key string) error
+}
+
+// Memory is a Store that lives as long as the process. It is safe for
+// concurrent use.
+type Memory struct {
+	mutex  sync.RWMutex
+	tokens map[string]string
+}
+
+func NewMemory() *Memory {
+	return &Memory{tokens: make(map[string]string)}
+}
+
+func (store *Memory) Get(key string) (string, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	token, ok := store.tokens[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return token, nil
+}
+
+func (store *Memory) Put(key, token string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.tokens[key] = token
+
+	return nil
+}
+
+func (store *Memory) Delete(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.tokens, key)
+
+	return nil
+}
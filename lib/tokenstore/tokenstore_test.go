@@ -0,0 +1,121 @@
+package tokenstore_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/adilsonchacon/goeli/lib/tokenstore"
+)
+
+func testStore(t *testing.T, store tokenstore.Store) {
+	t.Helper()
+
+	if _, err := store.Get("default"); errors.Is(err, tokenstore.ErrNotFound) {
+		t.Log("Get of a missing key returns ErrNotFound")
+	} else {
+		t.Errorf("Get of a missing key expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.Put("default", "a-valid-token"); err != nil {
+		t.Fatalf("Put expected no errors, got %s", err)
+	}
+	if err := store.Put("staging", "another-token"); err != nil {
+		t.Fatalf("Put expected no errors, got %s", err)
+	}
+
+	if token, err := store.Get("default"); err == nil && token == "a-valid-token" {
+		t.Log("Get returns the token stored by Put")
+	} else {
+		t.Errorf("Get expected a-valid-token, got %q, %v", token, err)
+	}
+
+	if err := store.Delete("default"); err != nil {
+		t.Fatalf("Delete expected no errors, got %s", err)
+	}
+	if err := store.Delete("default"); err != nil {
+		t.Errorf("Delete of a missing key expected no errors, got %s", err)
+	}
+
+	_, err := store.Get("default")
+	token, otherErr := store.Get("staging")
+	if errors.Is(err, tokenstore.ErrNotFound) && otherErr == nil && token == "another-token" {
+		t.Log("Delete removes only the given key")
+	} else {
+		t.Errorf("after Delete expected only staging to remain, got %v, %q, %v", err, token, otherErr)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, tokenstore.NewMemory())
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goeli", "tokens.json")
+	testStore(t, tokenstore.NewFile(path))
+
+	token, err := tokenstore.NewFile(path).Get("staging")
+	if err == nil && token == "another-token" {
+		t.Log("tokens survive in the file for a new store")
+	} else {
+		t.Errorf("new store expected another-token, got %q, %v", token, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat token file: %s", err)
+	}
+	if info.Mode().Perm() == 0o600 {
+		t.Log("token file is written with 0600 permissions")
+	} else {
+		t.Errorf("token file expected 0600 permissions, got %o", info.Mode().Perm())
+	}
+}
+
+func TestFileConcurrentWritersKeepEveryToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Separate stores share nothing but the lock file, like separate
+			// processes would.
+			if err := tokenstore.NewFile(path).Put(fmt.Sprintf("profile-%d", i), "token"); err != nil {
+				t.Errorf("Put expected no errors, got %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	store := tokenstore.NewFile(path)
+	missing := 0
+	for i := 0; i < 20; i++ {
+		if _, err := store.Get(fmt.Sprintf("profile-%d", i)); err != nil {
+			missing++
+		}
+	}
+
+	if missing == 0 {
+		t.Log("concurrent writers do not lose each other's tokens")
+	} else {
+		t.Errorf("expected every token to be kept, %d missing", missing)
+	}
+
+	leftovers, _ := filepath.Glob(path + ".*.tmp")
+	if len(leftovers) == 0 {
+		t.Log("no temporary files are left behind")
+	} else {
+		t.Errorf("expected no temporary files, got %v", leftovers)
+	}
+}
@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a finished or running span kept by Recorder.
+type RecordedSpan struct {
+	Name         string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	StatusCode   int
+	Err          error
+	Start        time.Time
+	End          time.Time
+	Ended        bool
+}
+
+// Recorder is an in-memory Tracer meant for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*recorderSpan
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (recorder *Recorder) Start(ctx context.Context, operation string) (context.Context, Span) {
+	spanContext := SpanContext{Flags: 0x01}
+	parentSpanID := ""
+
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		spanContext.TraceID = parent.TraceID
+		spanContext.Flags = parent.Flags
+		spanContext.TraceState = parent.TraceState
+		parentSpanID = hex.EncodeToString(parent.SpanID[:])
+	} else {
+		_, _ = rand.Read(spanContext.TraceID[:])
+	}
+	_, _ = rand.Read(spanContext.SpanID[:])
+
+	span := &recorderSpan{
+		recorder:    recorder,
+		spanContext: spanContext,
+		recorded: RecordedSpan{
+			Name:         operation,
+			TraceID:      hex.EncodeToString(spanContext.TraceID[:]),
+			SpanID:       hex.EncodeToString(spanContext.SpanID[:]),
+			ParentSpanID: parentSpanID,
+			Start:        time.Now(),
+		},
+	}
+
+	recorder.mu.Lock()
+	recorder.spans = append(recorder.spans, span)
+	recorder.mu.Unlock()
+
+	return ContextWithSpanContext(ctx, spanContext), span
+}
+
+func (recorder *Recorder) Spans() []RecordedSpan {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	spans := make([]RecordedSpan, len(recorder.spans))
+	for i, span := range recorder.spans {
+		spans[i] = span.recorded
+	}
+
+	return spans
+}
+
+func (recorder *Recorder) Reset() {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.spans = nil
+}
+
+type recorderSpan struct {
+	recorder    *Recorder
+	spanContext SpanContext
+	recorded    RecordedSpan
+}
+
+func (span *recorderSpan) SpanContext() SpanContext {
+	return span.spanContext
+}
+
+func (span *recorderSpan) SetStatus(statusCode int) {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+
+	span.recorded.StatusCode = statusCode
+}
+
+func (span *recorderSpan) RecordError(err error) {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+
+	span.recorded.Err = err
+}
+
+func (span *recorderSpan) End() {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+
+	if !span.recorded.Ended {
+		span.recorded.End = time.Now()
+		span.recorded.Ended = true
+	}
+}
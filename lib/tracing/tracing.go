@@ -0,0 +1,176 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adilsonchacon/goeli/lib/restapi"
+)
+
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Flags      byte
+	TraceState string
+}
+
+func (spanContext SpanContext) IsValid() bool {
+	return spanContext.TraceID != [16]byte{} && spanContext.SpanID != [8]byte{}
+}
+
+func (spanContext SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x",
+		hex.EncodeToString(spanContext.TraceID[:]), hex.EncodeToString(spanContext.SpanID[:]), spanContext.Flags)
+}
+
+// ParseTraceParent decodes a W3C traceparent header value. Only version 00
+// fields are read; later versions are accepted as long as they start with the
+// same layout.
+func ParseTraceParent(value string) (SpanContext, error) {
+	var spanContext SpanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return spanContext, fmt.Errorf("%w: %q", ErrInvalidTraceParent, value)
+	}
+
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != 16 {
+		return spanContext, fmt.Errorf("%w: bad trace-id in %q", ErrInvalidTraceParent, value)
+	}
+
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != 8 {
+		return spanContext, fmt.Errorf("%w: bad parent-id in %q", ErrInvalidTraceParent, value)
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return spanContext, fmt.Errorf("%w: bad trace-flags in %q", ErrInvalidTraceParent, value)
+	}
+
+	copy(spanContext.TraceID[:], traceID)
+	copy(spanContext.SpanID[:], spanID)
+	spanContext.Flags = flags[0]
+
+	if !spanContext.IsValid() {
+		return SpanContext{}, fmt.Errorf("%w: all-zero ids in %q", ErrInvalidTraceParent, value)
+	}
+
+	return spanContext, nil
+}
+
+type spanContextKey struct{}
+
+func ContextWithSpanContext(ctx context.Context, spanContext SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, spanContext)
+}
+
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	spanContext, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return spanContext, ok && spanContext.IsValid()
+}
+
+// Extract returns the request context enriched with the trace context found
+// in the incoming traceparent and tracestate headers, if any.
+func Extract(r *http.Request) context.Context {
+	spanContext, err := ParseTraceParent(r.Header.Get(TraceParentHeader))
+	if err != nil {
+		return r.Context()
+	}
+	spanContext.TraceState = r.Header.Get(TraceStateHeader)
+
+	return ContextWithSpanContext(r.Context(), spanContext)
+}
+
+func ExtractHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(Extract(r)))
+	})
+}
+
+type Tracer interface {
+	Start(ctx context.Context, operation string) (context.Context, Span)
+}
+
+type Span interface {
+	SpanContext() SpanContext
+	SetStatus(statusCode int)
+	RecordError(err error)
+	End()
+}
+
+// Middleware starts a span per Letmein call and injects its trace context
+// into the outgoing traceparent and tracestate headers. A nil tracer behaves
+// like NoopTracer. req.Context is restored once the call returns, so attempts
+// repeated by an outer middleware start sibling spans of the caller's
+// context instead of nesting under the previous attempt.
+func Middleware(tracer Tracer) restapi.Middleware {
+	if tracer == nil {
+		tracer = NoopTracer{}
+	}
+
+	return func(next restapi.RoundTrip) restapi.RoundTrip {
+		return func(req *restapi.Request) (*restapi.Response, error) {
+			parent := req.Context
+			ctx := parent
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			ctx, span := tracer.Start(ctx, req.Operation)
+			defer span.End()
+
+			req.Context = ctx
+			defer func() { req.Context = parent }()
+			if spanContext := span.SpanContext(); spanContext.IsValid() {
+				req.Header.Set(TraceParentHeader, spanContext.TraceParent())
+				if spanContext.TraceState != "" {
+					req.Header.Set(TraceStateHeader, spanContext.TraceState)
+				}
+			}
+
+			res, err := next(req)
+			if res != nil {
+				span.SetStatus(res.StatusCode)
+			}
+			if err != nil {
+				span.RecordError(err)
+			}
+
+			return res, err
+		}
+	}
+}
+
+// NoopTracer records nothing. Its spans carry the incoming trace context, so
+// the caller's traceparent is still forwarded to Letmein unchanged.
+type NoopTracer struct{}
+
+func (NoopTracer) Start(ctx context.Context, operation string) (context.Context, Span) {
+	spanContext, _ := SpanContextFromContext(ctx)
+	return ctx, noopSpan{spanContext: spanContext}
+}
+
+type noopSpan struct {
+	spanContext SpanContext
+}
+
+func (span noopSpan) SpanContext() SpanContext { return span.spanContext }
+
+func (noopSpan) SetStatus(int) {}
+
+func (noopSpan) RecordError(error) {}
+
+func (noopSpan) End() {}
@@ -0,0 +1,179 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adilsonchacon/goeli"
+	"github.com/adilsonchacon/goeli/app/admin/organizations"
+	"github.com/adilsonchacon/goeli/config/admin"
+	"github.com/adilsonchacon/goeli/lib/retry"
+	"github.com/adilsonchacon/goeli/lib/tracing"
+)
+
+const incomingTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func incomingContext(t *testing.T) context.Context {
+	incoming := httptest.NewRequest(http.MethodGet, "/", nil)
+	incoming.Header.Set("traceparent", incomingTraceParent)
+	incoming.Header.Set("tracestate", "vendor=value")
+
+	ctx := tracing.Extract(incoming)
+	if _, ok := tracing.SpanContextFromContext(ctx); !ok {
+		t.Fatal("Extract did not read the incoming traceparent")
+	}
+
+	return ctx
+}
+
+func TestParseTraceParentRejectsInvalidValues(t *testing.T) {
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-xyz-00f067aa0ba902b7-01",
+	}
+
+	for _, value := range invalid {
+		if _, err := tracing.ParseTraceParent(value); errors.Is(err, tracing.ErrInvalidTraceParent) {
+			t.Logf("traceparent %q is rejected", value)
+		} else {
+			t.Errorf("traceparent %q expected ErrInvalidTraceParent, got %v", value, err)
+		}
+	}
+
+	spanContext, err := tracing.ParseTraceParent(incomingTraceParent)
+	if err == nil && spanContext.TraceParent() == incomingTraceParent {
+		t.Log("valid traceparent round-trips")
+	} else {
+		t.Errorf("valid traceparent did not round-trip: %v %s", err, spanContext.TraceParent())
+	}
+}
+
+func TestMiddlewareStartsChildSpanAndInjectsHeaders(t *testing.T) {
+	var traceParent, traceState string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+		traceState = r.Header.Get("tracestate")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors": {"detail": "Not Found"}}`))
+	}))
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	adminConfig.Use(tracing.Middleware(recorder))
+
+	organizationRepo := organizations.NewRepo(adminConfig.WithContext(incomingContext(t)))
+	_, _ = organizationRepo.Find("123456789")
+
+	spans := recorder.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name == "organizations.Find" && span.StatusCode == http.StatusNotFound && span.Ended {
+		t.Log("span records operation and status")
+	} else {
+		t.Errorf("unexpected span %+v", span)
+	}
+
+	if span.TraceID == "4bf92f3577b34da6a3ce929d0e0e4736" && span.ParentSpanID == "00f067aa0ba902b7" {
+		t.Log("span continues the incoming trace")
+	} else {
+		t.Errorf("span is not a child of the incoming trace: %+v", span)
+	}
+
+	expected := "00-" + span.TraceID + "-" + span.SpanID + "-01"
+	if traceParent == expected && traceState == "vendor=value" {
+		t.Log("traceparent and tracestate are injected from the new span")
+	} else {
+		t.Errorf("expected traceparent %s and tracestate vendor=value, got %s and %s", expected, traceParent, traceState)
+	}
+}
+
+func TestRetriedAttemptsAreSiblingSpans(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": "123456789", "name": "Acme"}`))
+	}))
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	adminConfig := admin.NewConfig(server.URL, "a-valid-token")
+	policy := retry.DefaultPolicy()
+	policy.Backoff = retry.ExponentialBackoff(0, 0)
+	adminConfig.Use(retry.Middleware(policy), tracing.Middleware(recorder))
+
+	organizationRepo := organizations.NewRepo(adminConfig.WithContext(incomingContext(t)))
+	_, _ = organizationRepo.Find("123456789")
+
+	spans := recorder.Spans()
+	if len(spans) == 2 && spans[0].ParentSpanID == "00f067aa0ba902b7" && spans[1].ParentSpanID == "00f067aa0ba902b7" {
+		t.Log("each retried attempt is a child of the caller's span")
+	} else {
+		t.Errorf("expected two sibling spans under the incoming trace, got %+v", spans)
+	}
+}
+
+func TestMiddlewareRecordsTransportErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	baseURL := server.URL
+	server.Close()
+
+	recorder := tracing.NewRecorder()
+	eli := goeli.NewServiceConfig("", baseURL, "some-app-token")
+	eli.Use(tracing.Middleware(recorder))
+
+	_, _, _ = eli.SignIn("test@test.com", "Secret.123!")
+
+	spans := recorder.Spans()
+	if len(spans) == 1 && spans[0].Name == "SignIn" && spans[0].Err != nil && spans[0].ParentSpanID == "" {
+		t.Log("failed calls record the error on a root span")
+	} else {
+		t.Errorf("unexpected spans %+v", spans)
+	}
+}
+
+func TestNoopTracerForwardsIncomingTraceParent(t *testing.T) {
+	var traceParent string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	eli := goeli.NewServiceConfig("", server.URL, "some-app-token")
+	eli.Use(tracing.Middleware(nil))
+
+	_, _ = eli.WithContext(incomingContext(t)).SignedIn("a-valid-token")
+	if traceParent == incomingTraceParent {
+		t.Log("no-op tracer forwards the incoming traceparent")
+	} else {
+		t.Errorf("expected traceparent %s, got %q", incomingTraceParent, traceParent)
+	}
+
+	traceParent = ""
+	_, _ = eli.SignedIn("a-valid-token")
+	if traceParent == "" {
+		t.Log("no-op tracer without incoming context sends no traceparent")
+	} else {
+		t.Errorf("expected no traceparent, got %q", traceParent)
+	}
+}
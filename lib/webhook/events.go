@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/adilsonchacon/goeli/app/admin/apps"
+	"github.com/adilsonchacon/goeli/entities"
+)
+
+const (
+	UserCreated     = "user.created"
+	UserConfirmed   = "user.confirmed"
+	UserLocked      = "user.locked"
+	UserUnlocked    = "user.unlocked"
+	AppTokenRevoked = "app_token.revoked"
+)
+
+var userEvents = map[string]bool{UserCreated: true, UserConfirmed: true, UserLocked: true, UserUnlocked: true}
+
+var appTokenEvents = map[string]bool{AppTokenRevoked: true}
+
+// Event is the envelope every delivery shares. ID is unique per event and
+// stays the same when Letmein redelivers it.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserEvent struct {
+	Event
+	User entities.User
+}
+
+type AppTokenEvent struct {
+	Event
+	AppToken apps.AppToken
+}
+
+type UserHandler func(ctx context.Context, event UserEvent) error
+
+type AppTokenHandler func(ctx context.Context, event AppTokenEvent) error
+
+type envelope struct {
+	Event
+	Data json.RawMessage `json:"data"`
+}
@@ -0,0 +1,292 @@
+// Package webhook receives Letmein event deliveries. A Receiver is an
+// http.Handler that checks the HMAC-SHA256 signature and timestamp of each
+// delivery, drops replays, decodes the event and calls the handlers
+// registered for its type:
+//
+//	receiver, err := webhook.New(webhook.Options{Secret: secret})
+//	receiver.OnUser(webhook.UserLocked, func(ctx context.Context, event webhook.UserEvent) error {
+//		return sessions.Revoke(ctx, event.User.ID)
+//	})
+//	http.Handle("/webhooks/letmein", receiver)
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adilsonchacon/goeli/internal/httperr"
+	"github.com/adilsonchacon/goeli/lib/retry"
+)
+
+const (
+	DefaultTolerance   = 5 * time.Minute
+	DefaultMaxAttempts = 3
+	DefaultMaxBodySize = 1 << 20
+)
+
+var (
+	ErrInvalidSignature = errors.New("missing or invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside tolerance")
+	ErrMissingOption    = errors.New("missing option")
+)
+
+// Options configures a Receiver. Secret is required. A delivery is rejected
+// when its signed timestamp is more than Tolerance away from now; within
+// that window each event ID is handled once.
+//
+// A failing handler is called again up to MaxAttempts times in total, with
+// Backoff between calls. If it still fails, the delivery is answered with
+// 500 and its ID forgotten, so Letmein's own redelivery is handled.
+type Options struct {
+	Secret      []byte
+	Tolerance   time.Duration
+	MaxAttempts int
+	Backoff     func(attempt int) time.Duration
+	MaxBodySize int64
+}
+
+type Receiver struct {
+	options Options
+	now     func() time.Time
+
+	mutex            sync.Mutex
+	userHandlers     map[string][]UserHandler
+	appTokenHandlers map[string][]AppTokenHandler
+	seen             map[string]delivered
+}
+
+// delivered tracks an event ID within the tolerance window. An ID whose
+// handlers are still running is not done, and is never swept.
+type delivered struct {
+	expires time.Time
+	done    bool
+}
+
+func New(options Options) (*Receiver, error) {
+	if len(options.Secret) == 0 {
+		return nil, fmt.Errorf("%w: Secret", ErrMissingOption)
+	}
+
+	if options.Tolerance <= 0 {
+		options.Tolerance = DefaultTolerance
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.Backoff == nil {
+		options.Backoff = retry.ExponentialBackoff(100*time.Millisecond, time.Second)
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = DefaultMaxBodySize
+	}
+
+	return &Receiver{
+		options:          options,
+		now:              time.Now,
+		userHandlers:     make(map[string][]UserHandler),
+		appTokenHandlers: make(map[string][]AppTokenHandler),
+		seen:             make(map[string]delivered),
+	}, nil
+}
+
+// OnUser registers handler for one of the user.* event types. Like
+// http.ServeMux, it panics on a type that does not carry a user.
+func (receiver *Receiver) OnUser(eventType string, handler UserHandler) {
+	if !userEvents[eventType] {
+		panic(fmt.Sprintf("webhook: %q is not a user event", eventType))
+	}
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.userHandlers[eventType] = append(receiver.userHandlers[eventType], handler)
+}
+
+// OnAppToken registers handler for one of the app_token.* event types.
+func (receiver *Receiver) OnAppToken(eventType string, handler AppTokenHandler) {
+	if !appTokenEvents[eventType] {
+		panic(fmt.Sprintf("webhook: %q is not an app token event", eventType))
+	}
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.appTokenHandlers[eventType] = append(receiver.appTokenHandlers[eventType], handler)
+}
+
+// ServeHTTP answers 204 once the handlers succeed, and also for replays of
+// an event already handled and for event types nobody registered, so Letmein
+// stops redelivering them. A redelivery that arrives while the event is
+// still being handled is answered 409, so Letmein tries it again in case
+// the first delivery fails.
+func (receiver *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		httperr.Write(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, receiver.options.MaxBodySize))
+	if err != nil {
+		httperr.Write(w, http.StatusRequestEntityTooLarge, "webhook body too large")
+		return
+	}
+
+	timestamp, err := verify(receiver.options.Secret, r.Header.Get(SignatureHeader), body)
+	if err != nil {
+		httperr.Write(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	now := receiver.now()
+	if timestamp.Before(now.Add(-receiver.options.Tolerance)) || timestamp.After(now.Add(receiver.options.Tolerance)) {
+		httperr.Write(w, http.StatusUnauthorized, ErrStaleTimestamp.Error())
+		return
+	}
+
+	var delivery envelope
+	if err := json.Unmarshal(body, &delivery); err != nil || delivery.ID == "" || delivery.Type == "" {
+		httperr.Write(w, http.StatusBadRequest, "invalid webhook event")
+		return
+	}
+
+	dispatch, err := receiver.dispatcher(delivery)
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch receiver.claim(delivery.ID, timestamp.Add(receiver.options.Tolerance)) {
+	case claimDone:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case claimInFlight:
+		httperr.Write(w, http.StatusConflict, "webhook event is being handled")
+		return
+	}
+
+	if err := receiver.withRetries(r.Context(), dispatch); err != nil {
+		receiver.release(delivery.ID)
+		httperr.Write(w, http.StatusInternalServerError, "webhook handler failed")
+		return
+	}
+
+	receiver.finish(delivery.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dispatcher decodes the event data and returns a call to every handler
+// registered for its type. Handlers are copied so registrations made while
+// a delivery is retried do not affect it.
+func (receiver *Receiver) dispatcher(delivery envelope) (func(ctx context.Context) error, error) {
+	receiver.mutex.Lock()
+	userHandlers := append([]UserHandler(nil), receiver.userHandlers[delivery.Type]...)
+	appTokenHandlers := append([]AppTokenHandler(nil), receiver.appTokenHandlers[delivery.Type]...)
+	receiver.mutex.Unlock()
+
+	switch {
+	case userEvents[delivery.Type]:
+		event := UserEvent{Event: delivery.Event}
+		if err := json.Unmarshal(delivery.Data, &event.User); err != nil {
+			return nil, fmt.Errorf("invalid %s data", delivery.Type)
+		}
+		return func(ctx context.Context) error {
+			for _, handler := range userHandlers {
+				if err := handler(ctx, event); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case appTokenEvents[delivery.Type]:
+		event := AppTokenEvent{Event: delivery.Event}
+		if err := json.Unmarshal(delivery.Data, &event.AppToken); err != nil {
+			return nil, fmt.Errorf("invalid %s data", delivery.Type)
+		}
+		return func(ctx context.Context) error {
+			for _, handler := range appTokenHandlers {
+				if err := handler(ctx, event); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	default:
+		return func(ctx context.Context) error { return nil }, nil
+	}
+}
+
+// withRetries calls dispatch until it succeeds, the attempts run out or the
+// delivery request is canceled. Every handler of the event runs again on
+// each attempt, so handlers must be idempotent.
+func (receiver *Receiver) withRetries(ctx context.Context, dispatch func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = dispatch(ctx); err == nil || attempt >= receiver.options.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(receiver.options.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+type claimResult int
+
+const (
+	claimNew claimResult = iota
+	claimInFlight
+	claimDone
+)
+
+// claim records id until expires. It reports claimNew for an ID not seen
+// before, claimInFlight while another delivery of it is being handled and
+// claimDone once that delivery has succeeded.
+func (receiver *Receiver) claim(id string, expires time.Time) claimResult {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	now := receiver.now()
+	for seenID, seen := range receiver.seen {
+		if seen.done && !seen.expires.After(now) {
+			delete(receiver.seen, seenID)
+		}
+	}
+
+	if seen, ok := receiver.seen[id]; ok {
+		if seen.done {
+			return claimDone
+		}
+		return claimInFlight
+	}
+	receiver.seen[id] = delivered{expires: expires}
+
+	return claimNew
+}
+
+// finish marks id as handled, so later redeliveries are acknowledged.
+func (receiver *Receiver) finish(id string) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	if seen, ok := receiver.seen[id]; ok {
+		seen.done = true
+		receiver.seen[id] = seen
+	}
+}
+
+func (receiver *Receiver) release(id string) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	delete(receiver.seen, id)
+}
@@ -0,0 +1,302 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adilsonchacon/goeli/lib/webhook"
+)
+
+var secret = []byte("a-webhook-secret-shared-with-letmein")
+
+const userLocked = `{"id":"evt-1","type":"user.locked","created_at":"2026-10-19T10:00:00Z","data":{"id":"u1","name":"User","email":"user@example.com","active":false}}`
+
+func newReceiver(t *testing.T, options webhook.Options) *webhook.Receiver {
+	t.Helper()
+
+	if options.Secret == nil {
+		options.Secret = secret
+	}
+	if options.Backoff == nil {
+		options.Backoff = func(int) time.Duration { return time.Millisecond }
+	}
+
+	receiver, err := webhook.New(options)
+	if err != nil {
+		t.Fatalf("New expected no errors, got %s", err)
+	}
+
+	return receiver
+}
+
+func deliver(receiver http.Handler, signature, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/letmein", strings.NewReader(body))
+	if signature != "" {
+		req.Header.Set(webhook.SignatureHeader, signature)
+	}
+
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func TestNewRequiresSecret(t *testing.T) {
+	if _, err := webhook.New(webhook.Options{}); errors.Is(err, webhook.ErrMissingOption) {
+		t.Log("New without a secret returns ErrMissingOption")
+	} else {
+		t.Errorf("New without a secret expected ErrMissingOption, got %v", err)
+	}
+}
+
+func TestReceiverDispatchesTypedEvents(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{})
+
+	var locked []webhook.UserEvent
+	receiver.OnUser(webhook.UserLocked, func(ctx context.Context, event webhook.UserEvent) error {
+		locked = append(locked, event)
+		return nil
+	})
+	receiver.OnUser(webhook.UserCreated, func(ctx context.Context, event webhook.UserEvent) error {
+		t.Errorf("user.created handler expected not to run for %s", event.Type)
+		return nil
+	})
+
+	var revoked []webhook.AppTokenEvent
+	receiver.OnAppToken(webhook.AppTokenRevoked, func(ctx context.Context, event webhook.AppTokenEvent) error {
+		revoked = append(revoked, event)
+		return nil
+	})
+
+	response := deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked)
+	if response.Code == http.StatusNoContent && len(locked) == 1 && locked[0].User.Email == "user@example.com" && locked[0].ID == "evt-1" {
+		t.Log("Receiver decodes user.locked into a UserEvent carrying entities.User")
+	} else {
+		t.Errorf("Receiver expected 204 and one user.locked event, got %d %+v", response.Code, locked)
+	}
+
+	tokenRevoked := `{"id":"evt-2","type":"app_token.revoked","created_at":"2026-10-19T10:00:00Z","data":{"id":"t1","app_id":"p1","token":null,"revoked_at":"2026-10-19T10:00:00Z","revoked_by":"admin@example.com","created_at":"2026-01-01T00:00:00Z"}}`
+	response = deliver(receiver, webhook.Sign(secret, time.Now(), []byte(tokenRevoked)), tokenRevoked)
+	if response.Code == http.StatusNoContent && len(revoked) == 1 && revoked[0].AppToken.AppID == "p1" && *revoked[0].AppToken.RevokedBy == "admin@example.com" {
+		t.Log("Receiver decodes app_token.revoked into an AppTokenEvent carrying apps.AppToken")
+	} else {
+		t.Errorf("Receiver expected 204 and one app_token.revoked event, got %d %+v", response.Code, revoked)
+	}
+
+	unknown := `{"id":"evt-3","type":"organization.created","data":{}}`
+	if response := deliver(receiver, webhook.Sign(secret, time.Now(), []byte(unknown)), unknown); response.Code == http.StatusNoContent {
+		t.Log("Receiver acknowledges event types without handlers")
+	} else {
+		t.Errorf("Receiver expected 204 for an unhandled type, got %d", response.Code)
+	}
+}
+
+func TestReceiverRejectsBadSignatures(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{})
+
+	calls := 0
+	receiver.OnUser(webhook.UserLocked, func(ctx context.Context, event webhook.UserEvent) error {
+		calls++
+		return nil
+	})
+
+	tests := []struct {
+		name      string
+		signature string
+		body      string
+	}{
+		{"no signature", "", userLocked},
+		{"another secret", webhook.Sign([]byte("another-secret"), time.Now(), []byte(userLocked)), userLocked},
+		{"tampered body", webhook.Sign(secret, time.Now(), []byte(userLocked)), strings.Replace(userLocked, "u1", "u2", 1)},
+		{"stale timestamp", webhook.Sign(secret, time.Now().Add(-10*time.Minute), []byte(userLocked)), userLocked},
+		{"future timestamp", webhook.Sign(secret, time.Now().Add(10*time.Minute), []byte(userLocked)), userLocked},
+	}
+
+	for _, test := range tests {
+		if response := deliver(receiver, test.signature, test.body); response.Code == http.StatusUnauthorized {
+			t.Logf("Receiver rejects a delivery with %s", test.name)
+		} else {
+			t.Errorf("Receiver with %s expected 401, got %d", test.name, response.Code)
+		}
+	}
+
+	if calls != 0 {
+		t.Errorf("Handlers expected not to run for rejected deliveries, ran %d times", calls)
+	}
+
+	rotated := webhook.Sign(secret, time.Now(), []byte(userLocked)) + ",v1=00ff"
+	if response := deliver(receiver, rotated, userLocked); response.Code == http.StatusNoContent && calls == 1 {
+		t.Log("Receiver accepts a signature among several v1 values")
+	} else {
+		t.Errorf("Receiver expected 204 with several v1 values, got %d", response.Code)
+	}
+}
+
+func TestReceiverDropsReplays(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{})
+
+	calls := 0
+	receiver.OnUser(webhook.UserLocked, func(ctx context.Context, event webhook.UserEvent) error {
+		calls++
+		return nil
+	})
+
+	deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked)
+	response := deliver(receiver, webhook.Sign(secret, time.Now().Add(time.Second), []byte(userLocked)), userLocked)
+
+	if response.Code == http.StatusNoContent && calls == 1 {
+		t.Log("Receiver acknowledges a replayed event without handling it again")
+	} else {
+		t.Errorf("Replay expected 204 and a single handler call, got %d and %d calls", response.Code, calls)
+	}
+}
+
+func TestReceiverRetriesFailingHandlers(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{MaxAttempts: 3})
+
+	var mutex sync.Mutex
+	calls := 0
+	receiver.OnUser(webhook.UserLocked, func(ctx context.Context, event webhook.UserEvent) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls++
+		if calls < 3 {
+			return errors.New("session store unavailable")
+		}
+		return nil
+	})
+
+	response := deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked)
+	if response.Code == http.StatusNoContent && calls == 3 {
+		t.Log("Receiver retries a failing handler until it succeeds")
+	} else {
+		t.Errorf("Receiver expected 204 after 3 calls, got %d after %d", response.Code, calls)
+	}
+}
+
+func TestReceiverForgetsEventsThatKeepFailing(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{MaxAttempts: 2})
+
+	calls := 0
+	failing := true
+	receiver.OnUser(webhook.UserLocked, func(ctx context.Context, event webhook.UserEvent) error {
+		calls++
+		if failing {
+			return errors.New("session store unavailable")
+		}
+		return nil
+	})
+
+	response := deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked)
+	if response.Code == http.StatusInternalServerError && calls == 2 {
+		t.Log("Receiver answers 500 once the attempts run out")
+	} else {
+		t.Errorf("Receiver expected 500 after 2 calls, got %d after %d", response.Code, calls)
+	}
+
+	failing = false
+	response = deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked)
+	if response.Code == http.StatusNoContent && calls == 3 {
+		t.Log("Receiver handles Letmein's redelivery of a failed event")
+	} else {
+		t.Errorf("Redelivery expected 204 and a new handler call, got %d after %d", response.Code, calls)
+	}
+}
+
+func TestReceiverRejectsInvalidDeliveries(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{MaxBodySize: 512})
+
+	invalidUser := `{"id":"evt-4","type":"user.created","data":{"email":42}}`
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"malformed JSON", `{"id":`, http.StatusBadRequest},
+		{"missing event ID", `{"type":"user.locked","data":{}}`, http.StatusBadRequest},
+		{"data of the wrong shape", invalidUser, http.StatusBadRequest},
+		{"oversized body", `{"id":"evt-5","type":"user.locked","data":{"name":"` + strings.Repeat("x", 1024) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		if response := deliver(receiver, webhook.Sign(secret, time.Now(), []byte(test.body)), test.body); response.Code == test.status {
+			t.Logf("Receiver answers %d for %s", test.status, test.name)
+		} else {
+			t.Errorf("Receiver with %s expected %d, got %d", test.name, test.status, response.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/letmein", nil)
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, req)
+	if recorder.Code == http.StatusMethodNotAllowed && recorder.Header().Get("Allow") == http.MethodPost {
+		t.Log("Receiver only accepts POST")
+	} else {
+		t.Errorf("GET expected 405 with Allow: POST, got %d", recorder.Code)
+	}
+}
+
+func TestOnUserPanicsOnOtherEventTypes(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{})
+
+	defer func() {
+		if recover() != nil {
+			t.Log("OnUser panics when registered for app_token.revoked")
+		} else {
+			t.Error("OnUser expected to panic for app_token.revoked")
+		}
+	}()
+
+	receiver.OnUser(webhook.AppTokenRevoked, func(ctx context.Context, event webhook.UserEvent) error { return nil })
+}
+
+func TestReceiverAsksToRetryRedeliveriesInFlight(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{MaxAttempts: 1})
+
+	var mutex sync.Mutex
+	calls := 0
+	handling := make(chan struct{})
+	fail := make(chan struct{})
+	receiver.OnUser(webhook.UserLocked, func(ctx context.Context, event webhook.UserEvent) error {
+		mutex.Lock()
+		calls++
+		first := calls == 1
+		mutex.Unlock()
+
+		if first {
+			close(handling)
+			<-fail
+			return errors.New("session store unavailable")
+		}
+		return nil
+	})
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		first <- deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked)
+	}()
+	<-handling
+
+	if response := deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked); response.Code == http.StatusConflict {
+		t.Log("Receiver answers 409 to a redelivery while the event is being handled")
+	} else {
+		t.Errorf("Redelivery in flight expected 409, got %d", response.Code)
+	}
+
+	close(fail)
+	if response := <-first; response.Code != http.StatusInternalServerError {
+		t.Fatalf("Failing delivery expected 500, got %d", response.Code)
+	}
+
+	if response := deliver(receiver, webhook.Sign(secret, time.Now(), []byte(userLocked)), userLocked); response.Code == http.StatusNoContent && calls == 2 {
+		t.Log("Receiver handles the event on the redelivery after the first delivery failed")
+	} else {
+		t.Errorf("Redelivery after a failure expected 204 and a second call, got %d and %d calls", response.Code, calls)
+	}
+}
@@ -0,0 +1,50 @@
+package goeli
+
+import "fmt"
+
+func (config *Config) tokenKey(email string) string {
+	if config.TokenKey != "" {
+		return config.TokenKey
+	}
+
+	return email
+}
+
+// sessionToken returns sessionToken, or the token stored under TokenKey when
+// sessionToken is empty and the config has a store.
+func (config *Config) sessionToken(sessionToken string) (string, error) {
+	if sessionToken != "" || config.TokenStore == nil || config.TokenKey == "" {
+		return sessionToken, nil
+	}
+
+	stored, err := config.TokenStore.Get(config.TokenKey)
+	if err != nil {
+		return "", fmt.Errorf("could not load session token %q: %w", config.TokenKey, err)
+	}
+
+	return stored, nil
+}
+
+func (config *Config) storeSessionToken(key, sessionToken string) error {
+	if config.TokenStore == nil || key == "" || sessionToken == "" {
+		return nil
+	}
+
+	if err := config.TokenStore.Put(key, sessionToken); err != nil {
+		return fmt.Errorf("could not store session token %q: %w", key, err)
+	}
+
+	return nil
+}
+
+func (config *Config) deleteSessionToken() error {
+	if config.TokenStore == nil || config.TokenKey == "" {
+		return nil
+	}
+
+	if err := config.TokenStore.Delete(config.TokenKey); err != nil {
+		return fmt.Errorf("could not delete session token %q: %w", config.TokenKey, err)
+	}
+
+	return nil
+}